@@ -0,0 +1,167 @@
+/*
+Package assets implements the asset tags and asset surjection proofs of
+"Confidential Assets" by Poelstra et al., https://blockstream.com/bitcoin17-final41.pdf.
+
+Every asset has a generator H(asset) on secp256k1 whose discrete logarithm is
+unknown. Outputs carry a blinded tag H_A = H(asset) + rG instead of the asset
+itself. A surjection proof shows that an output tag is a re-blinding of one of
+the input tags, i.e. that output tag minus some input tag is a multiple of G,
+without revealing which one. It is a brs ring signature over the differences.
+*/
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/brs"
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// GeneratorDST is the domain-separation tag used to derive asset generators.
+const GeneratorDST = "blockchain-research/crypto/assets/generator/v1"
+
+const surjectionDST = "blockchain-research/crypto/assets/surjection/v1"
+
+/*
+Generator returns the unblinded asset tag H(asset) = brs.HashToCurve(asset, GeneratorDST).
+*/
+func Generator(asset []byte) []*big.Int {
+	x, y := brs.HashToCurve(asset, []byte(GeneratorDST))
+	return []*big.Int{x, y}
+}
+
+/*
+BlindTag returns the blinded asset tag H(asset) + rG.
+*/
+func BlindTag(asset []byte, r *big.Int) []*big.Int {
+	return Reblind(Generator(asset), r)
+}
+
+/*
+Reblind returns tag + rG.
+*/
+func Reblind(tag []*big.Int, r *big.Int) []*big.Int {
+	curve := btcec.S256()
+	rGx, rGy := curve.ScalarBaseMult(new(big.Int).Mod(r, curve.N).Bytes())
+	x, y := curve.Add(tag[0], tag[1], rGx, rGy)
+	return []*big.Int{x, y}
+}
+
+/*
+SurjectionProof shows that an output tag is a re-blinding of one of a list of
+input tags.
+*/
+type SurjectionProof struct {
+	sig *brs.Signature
+}
+
+/*
+ProveSurjection proves that outputTag is a re-blinding of inputTags[index].
+inputBlind and outputBlind are the blinding factors of inputTags[index] and
+outputTag with respect to the same asset generator.
+*/
+func ProveSurjection(inputTags [][]*big.Int, index int, inputBlind *big.Int, outputTag []*big.Int, outputBlind *big.Int) (*SurjectionProof, error) {
+	if index < 0 || index >= len(inputTags) {
+		return nil, errors.New("assets: input index out of range")
+	}
+	ring, err := surjectionRing(inputTags, outputTag)
+	if err != nil {
+		return nil, err
+	}
+
+	//outputTag - inputTags[index] = (outputBlind - inputBlind)G
+	curve := btcec.S256()
+	d := new(big.Int).Sub(outputBlind, inputBlind)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, errors.New("assets: output tag must be re-blinded")
+	}
+	priv, _ := btcec.PrivKeyFromBytes(curve, d.Bytes())
+
+	signer, err := brs.NewSigner([][]*btcec.PublicKey{ring}, []int{index}, []*btcec.PrivateKey{priv})
+	if err != nil {
+		return nil, fmt.Errorf("assets: output tag is not a re-blinding of input %d: %v", index, err)
+	}
+	return &SurjectionProof{sig: signer.Sign(surjectionMessage(outputTag))}, nil
+}
+
+/*
+VerifySurjection checks that outputTag is a re-blinding of one of inputTags.
+*/
+func VerifySurjection(inputTags [][]*big.Int, outputTag []*big.Int, proof *SurjectionProof) bool {
+	if proof == nil || proof.sig == nil {
+		return false
+	}
+	ring, err := surjectionRing(inputTags, outputTag)
+	if err != nil {
+		return false
+	}
+	verifier, err := brs.NewVerifier([][]*btcec.PublicKey{ring})
+	if err != nil {
+		return false
+	}
+	return verifier.Verify(surjectionMessage(outputTag), proof.sig)
+}
+
+/*
+MarshalBinary encodes the proof as its underlying brs signature.
+*/
+func (proof *SurjectionProof) MarshalBinary() ([]byte, error) {
+	if proof.sig == nil {
+		return nil, errors.New("assets: incomplete proof")
+	}
+	return proof.sig.MarshalBinary()
+}
+
+/*
+UnmarshalBinary decodes a proof produced by MarshalBinary. The ring size is
+checked against the input tags by VerifySurjection.
+*/
+func (proof *SurjectionProof) UnmarshalBinary(data []byte) error {
+	sig := &brs.Signature{}
+	if err := sig.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	proof.sig = sig
+	return nil
+}
+
+/*
+surjectionRing returns the keys outputTag - inputTags[k].
+*/
+func surjectionRing(inputTags [][]*big.Int, outputTag []*big.Int) ([]*btcec.PublicKey, error) {
+	curve := btcec.S256()
+	if len(inputTags) == 0 {
+		return nil, errors.New("assets: no input tags")
+	}
+	if !isPoint(outputTag) {
+		return nil, errors.New("assets: invalid output tag")
+	}
+	ring := make([]*btcec.PublicKey, len(inputTags))
+	for k, in := range inputTags {
+		if !isPoint(in) {
+			return nil, fmt.Errorf("assets: invalid input tag %d", k)
+		}
+		x, y := curve.Add(outputTag[0], outputTag[1], in[0], new(big.Int).Sub(curve.P, in[1]))
+		if x.Sign() == 0 && y.Sign() == 0 {
+			return nil, fmt.Errorf("assets: output tag equals input tag %d", k)
+		}
+		ring[k] = &btcec.PublicKey{Curve: curve, X: x, Y: y}
+	}
+	return ring, nil
+}
+
+/*
+surjectionMessage binds the signature to the output tag; the input tags are
+bound through the ring keys.
+*/
+func surjectionMessage(outputTag []*big.Int) []byte {
+	pub := &btcec.PublicKey{Curve: btcec.S256(), X: outputTag[0], Y: outputTag[1]}
+	return append([]byte(surjectionDST), pub.SerializeCompressed()...)
+}
+
+func isPoint(P []*big.Int) bool {
+	return len(P) == 2 && P[0] != nil && P[1] != nil && btcec.S256().IsOnCurve(P[0], P[1])
+}
@@ -0,0 +1,72 @@
+package assets
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+func randomBlind() *big.Int {
+	r, _ := rand.Int(rand.Reader, btcec.S256().N)
+	return r
+}
+
+func TestSurjectionProof(t *testing.T) {
+	assets := [][]byte{[]byte("USD"), []byte("EUR"), []byte("GBP")}
+	blinds := []*big.Int{randomBlind(), randomBlind(), randomBlind()}
+	inputs := make([][]*big.Int, len(assets))
+	for k := range assets {
+		inputs[k] = BlindTag(assets[k], blinds[k])
+	}
+
+	//the output is a re-blinded EUR tag
+	outBlind := randomBlind()
+	output := BlindTag(assets[1], outBlind)
+	proof, err := ProveSurjection(inputs, 1, blinds[1], output, outBlind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySurjection(inputs, output, proof) {
+		t.Errorf("Surjection proof verification failed")
+	}
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof2 := &SurjectionProof{}
+	if err := proof2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySurjection(inputs, output, proof2) {
+		t.Errorf("Surjection proof verification failed after unmarshal")
+	}
+
+	//the proof does not verify for a different input list
+	if VerifySurjection(inputs[:2], output, proof) {
+		t.Errorf("Surjection proof verified for another ring size")
+	}
+	other := [][]*big.Int{inputs[0], BlindTag([]byte("JPY"), randomBlind()), inputs[2]}
+	if VerifySurjection(other, output, proof) {
+		t.Errorf("Surjection proof verified for other input tags")
+	}
+
+	//an output of an asset that is not among the inputs cannot be proven
+	jpy := BlindTag([]byte("JPY"), outBlind)
+	if _, err := ProveSurjection(inputs, 1, blinds[1], jpy, outBlind); err == nil {
+		t.Errorf("expected error for output of another asset")
+	}
+}
+
+func TestReblind(t *testing.T) {
+	r1, r2 := randomBlind(), randomBlind()
+	tag := BlindTag([]byte("USD"), r1)
+	sum := new(big.Int).Add(r1, r2)
+	a := Reblind(tag, r2)
+	b := BlindTag([]byte("USD"), sum)
+	if a[0].Cmp(b[0]) != 0 || a[1].Cmp(b[1]) != 0 {
+		t.Errorf("Reblind is not additive")
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Package bls implements BLS signatures on the bn256 pairing.
+
+Signatures live in G1 and public keys in G2: a private key x has public key
+P = xg2, and the signature on a message m is S = xH(m), where H is
+bn256.HashToG1. A signature verifies if e(S, g2) = e(H(m), P).
+
+Signatures by different keys aggregate by addition. To rule out rogue-key
+attacks every public key must come with a proof of possession, a signature on
+the public key itself under a separate domain-separation tag, which is checked
+once before the key is accepted. This is the proof-of-possession scheme of the
+IETF BLS signature draft.
+*/
+package bls
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+const (
+	// SignatureDST is the domain-separation tag for hashing messages.
+	SignatureDST = "BLS_SIG_BN256G1_XMD:SHA-256_SVDW_RO_POP_"
+	// PossessionDST is the domain-separation tag for proofs of possession.
+	PossessionDST = "BLS_POP_BN256G1_XMD:SHA-256_SVDW_RO_POP_"
+
+	// PrivateKeySize is the length of PrivateKey.Marshal.
+	PrivateKeySize = 32
+	// PublicKeySize is the length of PublicKey.Marshal.
+	PublicKeySize = bn256.CompressedG2Size
+	// SignatureSize is the length of Signature.Marshal.
+	SignatureSize = bn256.CompressedG1Size
+)
+
+/*
+PublicKey is P = xg2.
+*/
+type PublicKey struct {
+	p *bn256.G2
+}
+
+/*
+PrivateKey is the scalar x in [1, Order) together with its public key.
+*/
+type PrivateKey struct {
+	PublicKey
+	x *big.Int
+}
+
+/*
+Signature is a point of G1, either a single signature or an aggregate.
+*/
+type Signature struct {
+	s *bn256.G1
+}
+
+/*
+GenerateKey returns a private key read from r, or from crypto/rand if r is nil.
+*/
+func GenerateKey(r io.Reader) (*PrivateKey, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	x, P, err := bn256.RandomG2(r)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{PublicKey{P}, x}, nil
+}
+
+/*
+Public returns the public key of priv.
+*/
+func (priv *PrivateKey) Public() *PublicKey {
+	return &priv.PublicKey
+}
+
+/*
+Sign returns S = xH(msg).
+*/
+func (priv *PrivateKey) Sign(msg []byte) *Signature {
+	return &Signature{new(bn256.G1).ScalarMult(hashMsg(msg, SignatureDST), priv.x)}
+}
+
+/*
+ProvePossession returns a proof of possession of priv: a signature on the
+encoded public key under PossessionDST.
+*/
+func (priv *PrivateKey) ProvePossession() *Signature {
+	return &Signature{new(bn256.G1).ScalarMult(hashMsg(priv.PublicKey.Marshal(), PossessionDST), priv.x)}
+}
+
+/*
+Verify checks that sig is a signature on msg by pub.
+*/
+func Verify(pub *PublicKey, msg []byte, sig *Signature) bool {
+	if !pub.valid() || !sig.valid() {
+		return false
+	}
+	return verify(pub, hashMsg(msg, SignatureDST), sig)
+}
+
+/*
+VerifyPossession checks a proof of possession made with ProvePossession. Only
+keys that pass it may be used with FastAggregateVerify or AggregatePublicKeys.
+*/
+func VerifyPossession(pub *PublicKey, proof *Signature) bool {
+	if !pub.valid() || !proof.valid() {
+		return false
+	}
+	return verify(pub, hashMsg(pub.Marshal(), PossessionDST), proof)
+}
+
+/*
+Aggregate returns the sum of sigs.
+*/
+func Aggregate(sigs []*Signature) (*Signature, error) {
+	if len(sigs) == 0 {
+		return nil, errors.New("bls: no signatures to aggregate")
+	}
+	sum := new(bn256.G1).SetInfinity()
+	for _, sig := range sigs {
+		if !sig.valid() {
+			return nil, errors.New("bls: invalid signature")
+		}
+		sum.Add(sum, sig.s)
+	}
+	return &Signature{sum}, nil
+}
+
+/*
+AggregatePublicKeys returns the sum of pubs, the key that verifies the
+aggregate of their signatures on a common message. Every key must have passed
+VerifyPossession.
+*/
+func AggregatePublicKeys(pubs []*PublicKey) (*PublicKey, error) {
+	if len(pubs) == 0 {
+		return nil, errors.New("bls: no public keys to aggregate")
+	}
+	sum := new(bn256.G2).SetInfinity()
+	for _, pub := range pubs {
+		if !pub.valid() {
+			return nil, errors.New("bls: invalid public key")
+		}
+		sum.Add(sum, pub.p)
+	}
+	return &PublicKey{sum}, nil
+}
+
+/*
+AggregateVerify checks that sig aggregates signatures by pubs[i] on msgs[i].
+The messages must be distinct. It checks
+e(-S, g2).e(H(m_1), P_1)...e(H(m_n), P_n) = 1
+with n+1 Miller loops and a single final exponentiation.
+*/
+func AggregateVerify(pubs []*PublicKey, msgs [][]byte, sig *Signature) bool {
+	if len(pubs) == 0 || len(pubs) != len(msgs) || !sig.valid() {
+		return false
+	}
+	seen := make(map[string]bool, len(msgs))
+	g1s := []*bn256.G1{new(bn256.G1).Neg(sig.s)}
+	g2s := []*bn256.G2{g2}
+	for i, pub := range pubs {
+		if !pub.valid() || seen[string(msgs[i])] {
+			return false
+		}
+		seen[string(msgs[i])] = true
+		g1s = append(g1s, hashMsg(msgs[i], SignatureDST))
+		g2s = append(g2s, pub.p)
+	}
+	return bn256.PairingCheck(g1s, g2s)
+}
+
+/*
+FastAggregateVerify checks that sig aggregates signatures by pubs on the same
+msg, by verifying sig against the sum of pubs. Every key must have passed
+VerifyPossession.
+*/
+func FastAggregateVerify(pubs []*PublicKey, msg []byte, sig *Signature) bool {
+	pub, err := AggregatePublicKeys(pubs)
+	if err != nil {
+		return false
+	}
+	return Verify(pub, msg, sig)
+}
+
+/*
+Marshal encodes x as 32 big-endian bytes.
+*/
+func (priv *PrivateKey) Marshal() []byte {
+	ret := make([]byte, PrivateKeySize)
+	priv.x.FillBytes(ret)
+	return ret
+}
+
+/*
+UnmarshalPrivateKey decodes a private key encoded by PrivateKey.Marshal.
+*/
+func UnmarshalPrivateKey(m []byte) (*PrivateKey, error) {
+	if len(m) != PrivateKeySize {
+		return nil, errors.New("bls: invalid private key length")
+	}
+	x := new(big.Int).SetBytes(m)
+	if x.Sign() == 0 || x.Cmp(bn256.Order) >= 0 {
+		return nil, errors.New("bls: private key out of range")
+	}
+	return &PrivateKey{PublicKey{new(bn256.G2).ScalarBaseMult(x)}, x}, nil
+}
+
+/*
+Marshal encodes pub as a compressed G2 point.
+*/
+func (pub *PublicKey) Marshal() []byte {
+	return pub.p.MarshalCompressed()
+}
+
+/*
+UnmarshalPublicKey decodes a public key encoded by PublicKey.Marshal. It
+rejects the identity and points outside G2.
+*/
+func UnmarshalPublicKey(m []byte) (*PublicKey, error) {
+	p, err := new(bn256.G2).UnmarshalCompressed(m)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsZero() {
+		return nil, errors.New("bls: public key is the identity")
+	}
+	return &PublicKey{p}, nil
+}
+
+/*
+Marshal encodes sig as a compressed G1 point.
+*/
+func (sig *Signature) Marshal() []byte {
+	return sig.s.MarshalCompressed()
+}
+
+/*
+UnmarshalSignature decodes a signature encoded by Signature.Marshal.
+*/
+func UnmarshalSignature(m []byte) (*Signature, error) {
+	s, err := new(bn256.G1).UnmarshalCompressed(m)
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{s}, nil
+}
+
+// g2 is the generator of G2.
+var g2 = new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+
+func hashMsg(msg []byte, dst string) *bn256.G1 {
+	return bn256.HashToG1(msg, []byte(dst))
+}
+
+/*
+verify checks e(-S, g2).e(h, P) = 1.
+*/
+func verify(pub *PublicKey, h *bn256.G1, sig *Signature) bool {
+	return bn256.PairingCheck(
+		[]*bn256.G1{new(bn256.G1).Neg(sig.s), h},
+		[]*bn256.G2{g2, pub.p},
+	)
+}
+
+/*
+valid rejects missing keys and the identity, under which the identity is a
+signature on every message.
+*/
+func (pub *PublicKey) valid() bool {
+	return pub != nil && pub.p != nil && !pub.p.IsZero()
+}
+
+func (sig *Signature) valid() bool {
+	return sig != nil && sig.s != nil
+}
@@ -0,0 +1,194 @@
+package bls
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+func keys(t *testing.T, n int) []*PrivateKey {
+	privs := make([]*PrivateKey, n)
+	for i := range privs {
+		priv, err := GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privs[i] = priv
+	}
+	return privs
+}
+
+func TestSignVerify(t *testing.T) {
+	priv := keys(t, 1)[0]
+	msg := []byte("message")
+	sig := priv.Sign(msg)
+	if !Verify(priv.Public(), msg, sig) {
+		t.Errorf("Signature verification failed")
+	}
+	if Verify(priv.Public(), []byte("other"), sig) {
+		t.Errorf("Signature verified for another message")
+	}
+	if Verify(keys(t, 1)[0].Public(), msg, sig) {
+		t.Errorf("Signature verified for another key")
+	}
+
+	//the identity key would accept the identity signature on anything
+	identity := &PublicKey{new(bn256.G2).SetInfinity()}
+	if Verify(identity, msg, &Signature{new(bn256.G1).SetInfinity()}) {
+		t.Errorf("Identity public key accepted")
+	}
+}
+
+func TestProofOfPossession(t *testing.T) {
+	privs := keys(t, 2)
+	proof := privs[0].ProvePossession()
+	if !VerifyPossession(privs[0].Public(), proof) {
+		t.Errorf("Proof of possession verification failed")
+	}
+	if VerifyPossession(privs[1].Public(), proof) {
+		t.Errorf("Proof of possession verified for another key")
+	}
+	//a proof of possession is not a signature on the encoded key
+	if VerifyPossession(privs[0].Public(), privs[0].Sign(privs[0].Public().Marshal())) {
+		t.Errorf("Signature accepted as proof of possession")
+	}
+}
+
+func TestAggregateVerify(t *testing.T) {
+	privs := keys(t, 4)
+	pubs := make([]*PublicKey, len(privs))
+	msgs := make([][]byte, len(privs))
+	sigs := make([]*Signature, len(privs))
+	for i, priv := range privs {
+		pubs[i] = priv.Public()
+		msgs[i] = []byte(fmt.Sprintf("message %d", i))
+		sigs[i] = priv.Sign(msgs[i])
+	}
+	agg, err := Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !AggregateVerify(pubs, msgs, agg) {
+		t.Errorf("Aggregate verification failed")
+	}
+
+	msgs[0], msgs[1] = msgs[1], msgs[0]
+	if AggregateVerify(pubs, msgs, agg) {
+		t.Errorf("Aggregate verified with swapped messages")
+	}
+	msgs[0], msgs[1] = msgs[1], msgs[0]
+
+	if AggregateVerify(pubs[1:], msgs[1:], agg) {
+		t.Errorf("Aggregate verified with a missing signer")
+	}
+
+	//repeated messages are rejected
+	msgs[1] = msgs[0]
+	sigs[1] = privs[1].Sign(msgs[1])
+	agg, _ = Aggregate(sigs)
+	if AggregateVerify(pubs, msgs, agg) {
+		t.Errorf("Aggregate verified with repeated messages")
+	}
+
+	if _, err := Aggregate(nil); err == nil {
+		t.Errorf("expected error for empty aggregate")
+	}
+}
+
+func TestFastAggregateVerify(t *testing.T) {
+	privs := keys(t, 4)
+	msg := []byte("common message")
+	pubs := make([]*PublicKey, len(privs))
+	sigs := make([]*Signature, len(privs))
+	for i, priv := range privs {
+		pubs[i] = priv.Public()
+		sigs[i] = priv.Sign(msg)
+	}
+	agg, err := Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !FastAggregateVerify(pubs, msg, agg) {
+		t.Errorf("Fast aggregate verification failed")
+	}
+	if FastAggregateVerify(pubs[1:], msg, agg) {
+		t.Errorf("Fast aggregate verified with a missing signer")
+	}
+	if FastAggregateVerify(pubs, []byte("other"), agg) {
+		t.Errorf("Fast aggregate verified for another message")
+	}
+}
+
+func TestRogueKey(t *testing.T) {
+	//the attacker publishes P' = xg2 - P for the victim's key P, so that
+	//P + P' = xg2 and xH(m) passes as a signature by both
+	victim := keys(t, 1)[0]
+	x := keys(t, 1)[0]
+	rogue := &PublicKey{new(bn256.G2).Add(x.Public().p, new(bn256.G2).Neg(victim.Public().p))}
+	msg := []byte("message")
+	if !FastAggregateVerify([]*PublicKey{victim.Public(), rogue}, msg, x.Sign(msg)) {
+		t.Fatalf("rogue key attack setup failed")
+	}
+
+	//which the attacker cannot back with a proof of possession
+	if VerifyPossession(rogue, x.ProvePossession()) {
+		t.Errorf("Rogue key passed the proof of possession")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	priv := keys(t, 1)[0]
+	sig := priv.Sign([]byte("message"))
+
+	priv2, err := UnmarshalPrivateKey(priv.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(priv2.Public().Marshal(), priv.Public().Marshal()) {
+		t.Errorf("Private key decoding changed the public key")
+	}
+	pub, err := UnmarshalPublicKey(priv.Public().Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := UnmarshalSignature(sig.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.Marshal()) != PublicKeySize || len(sig2.Marshal()) != SignatureSize {
+		t.Errorf("unexpected encoding sizes")
+	}
+	if !Verify(pub, []byte("message"), sig2) {
+		t.Errorf("Decoded signature verification failed")
+	}
+
+	if _, err := UnmarshalPublicKey(new(bn256.G2).SetInfinity().MarshalCompressed()); err == nil {
+		t.Errorf("expected error for identity public key")
+	}
+	if _, err := UnmarshalPrivateKey(make([]byte, PrivateKeySize)); err == nil {
+		t.Errorf("expected error for zero private key")
+	}
+	if _, err := UnmarshalSignature(sig.Marshal()[1:]); err == nil {
+		t.Errorf("expected error for truncated signature")
+	}
+}
+
+func BenchmarkAggregateVerify(b *testing.B) {
+	const n = 16
+	pubs := make([]*PublicKey, n)
+	msgs := make([][]byte, n)
+	sigs := make([]*Signature, n)
+	for i := range pubs {
+		priv, _ := GenerateKey(nil)
+		pubs[i] = priv.Public()
+		msgs[i] = []byte(fmt.Sprintf("message %d", i))
+		sigs[i] = priv.Sign(msgs[i])
+	}
+	agg, _ := Aggregate(sigs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		AggregateVerify(pubs, msgs, agg)
+	}
+}
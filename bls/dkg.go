@@ -0,0 +1,441 @@
+package bls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+/*
+The distributed key generation is Pedersen's joint-Feldman protocol with
+complaints, so that no dealer ever knows the private key. With participants
+1...n and threshold t it runs in three rounds followed by a local computation:
+
+	Deal      every participant j picks a random polynomial f_j of degree t-1,
+	          broadcasts its Commitment and sends f_j(i) privately to each i
+	Complain  every participant verifies the commitments and its shares, and
+	          broadcasts the dealers whose shares failed to verify
+	Justify   every dealer broadcasts the shares it was complained about
+	Finish    a dealer is disqualified if its commitment is invalid, if t or
+	          more participants complained about it, or if it did not answer a
+	          complaint with a valid share; the remaining dealers form QUAL.
+	          Participant i's key share is sum(f_j(i)) and the public key is
+	          sum(f_j(0)g2), over j in QUAL.
+
+Every decision about QUAL depends on broadcast messages only, so all honest
+participants agree on it provided the broadcast of the Transport is reliable:
+every participant receives the same broadcast messages from a dealer. Over a
+transport without that guarantee, a dealer sending different commitments to
+different participants is disqualified only by those that receive both, and
+QUAL may differ between them. As shown by Gennaro et al., a rushing adversary can
+bias the distribution of the public key; this does not affect the security of
+threshold BLS signatures.
+*/
+
+/*
+Message is a DKG message between participants.
+*/
+type Message struct {
+	From int
+	// To is the recipient, or 0 for a broadcast to every other participant.
+	To int
+	// Commitment is a dealer's broadcast in the Deal round.
+	Commitment *Commitment
+	// Share is a dealer's private share for To in the Deal round, or a share
+	// broadcast in answer to a complaint in the Justify round.
+	Share *Share
+	// Complaints are the dealers whose shares failed to verify.
+	Complaints []int
+}
+
+/*
+Transport carries DKG messages between the participants 1...n.
+*/
+type Transport interface {
+	// Send delivers msg to msg.To, or to every participant but msg.From if
+	// msg.To is 0.
+	Send(msg *Message) error
+	// Receive returns and removes the messages delivered to index.
+	Receive(index int) ([]*Message, error)
+}
+
+/*
+MemoryTransport is a Transport within a single process, for running the DKG
+locally. It is safe for concurrent use.
+*/
+type MemoryTransport struct {
+	mu    sync.Mutex
+	inbox [][]*Message
+}
+
+/*
+Participant runs the DKG for one index.
+*/
+type Participant struct {
+	index, t, n int
+	transport   Transport
+	f           []*big.Int
+
+	commitments  map[int]*Commitment
+	shares       map[int]*Share
+	complaints   map[int]map[int]bool
+	disqualified map[int]bool
+	// pending holds messages received ahead of their round.
+	pending []*Message
+}
+
+/*
+NewMemoryTransport returns a transport for n participants.
+*/
+func NewMemoryTransport(n int) *MemoryTransport {
+	return &MemoryTransport{inbox: make([][]*Message, n+1)}
+}
+
+/*
+Send implements Transport.
+*/
+func (tr *MemoryTransport) Send(msg *Message) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	n := len(tr.inbox) - 1
+	if msg.From < 1 || msg.From > n || msg.To < 0 || msg.To > n || msg.To == msg.From {
+		return errors.New("bls: invalid message sender or recipient")
+	}
+	if msg.To != 0 {
+		tr.inbox[msg.To] = append(tr.inbox[msg.To], msg)
+		return nil
+	}
+	for i := 1; i <= n; i++ {
+		if i != msg.From {
+			tr.inbox[i] = append(tr.inbox[i], msg)
+		}
+	}
+	return nil
+}
+
+/*
+Receive implements Transport.
+*/
+func (tr *MemoryTransport) Receive(index int) ([]*Message, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if index < 1 || index >= len(tr.inbox) {
+		return nil, errors.New("bls: invalid participant index")
+	}
+	msgs := tr.inbox[index]
+	tr.inbox[index] = nil
+	return msgs, nil
+}
+
+/*
+NewParticipant returns the participant with the given index in 1...n of a
+t-of-n DKG, with its secret polynomial read from r, or from crypto/rand if r
+is nil.
+*/
+func NewParticipant(index, t, n int, transport Transport, r io.Reader) (*Participant, error) {
+	if index < 1 || index > n {
+		return nil, errors.New("bls: invalid participant index")
+	}
+	f, err := randomPolynomial(nil, t, n, r)
+	if err != nil {
+		return nil, err
+	}
+	return &Participant{
+		index:        index,
+		t:            t,
+		n:            n,
+		transport:    transport,
+		f:            f,
+		commitments:  map[int]*Commitment{},
+		shares:       map[int]*Share{},
+		complaints:   map[int]map[int]bool{},
+		disqualified: map[int]bool{},
+	}, nil
+}
+
+/*
+Deal broadcasts the commitment to the participant's polynomial and sends every
+other participant its share.
+*/
+func (p *Participant) Deal() error {
+	c := commit(p.f)
+	p.commitments[p.index] = c
+	p.shares[p.index] = &Share{p.index, evalPolynomial(p.f, p.index)}
+	if err := p.transport.Send(&Message{From: p.index, Commitment: c}); err != nil {
+		return err
+	}
+	for i := 1; i <= p.n; i++ {
+		if i == p.index {
+			continue
+		}
+		share := &Share{i, evalPolynomial(p.f, i)}
+		if err := p.transport.Send(&Message{From: p.index, To: i, Share: share}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Complain receives the dealt commitments and shares, disqualifies the dealers
+whose commitment is missing or invalid, and broadcasts a complaint against
+those whose share does not match their commitment.
+*/
+func (p *Participant) Complain() error {
+	msgs, err := p.receive(func(msg *Message) bool {
+		return msg.Commitment != nil || (msg.To != 0 && msg.Share != nil)
+	})
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		switch {
+		case msg.To == 0 && msg.Commitment != nil:
+			if _, ok := p.commitments[msg.From]; ok {
+				//equivocating dealer
+				p.disqualified[msg.From] = true
+			}
+			p.commitments[msg.From] = msg.Commitment
+		case msg.To == p.index && msg.Share != nil:
+			p.shares[msg.From] = msg.Share
+		}
+	}
+
+	var complaints []int
+	for j := 1; j <= p.n; j++ {
+		c, ok := p.commitments[j]
+		if !ok || !c.Valid() || c.Threshold() != p.t {
+			p.disqualified[j] = true
+			continue
+		}
+		share, ok := p.shares[j]
+		if !ok || share.Index != p.index || !c.VerifyShare(share) {
+			complaints = append(complaints, j)
+			p.complain(j, p.index)
+		}
+	}
+	if len(complaints) == 0 {
+		return nil
+	}
+	return p.transport.Send(&Message{From: p.index, Complaints: complaints})
+}
+
+/*
+Justify receives the complaints and answers those against the participant by
+broadcasting the disputed shares.
+*/
+func (p *Participant) Justify() error {
+	msgs, err := p.receive(func(msg *Message) bool {
+		return msg.Complaints != nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if msg.To != 0 {
+			continue
+		}
+		for _, j := range msg.Complaints {
+			p.complain(j, msg.From)
+		}
+	}
+
+	for _, i := range sortedKeys(p.complaints[p.index]) {
+		share := &Share{i, evalPolynomial(p.f, i)}
+		if err := p.transport.Send(&Message{From: p.index, Share: share}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Finish receives the answers to the complaints, determines QUAL and returns the
+participant's key share together with the sum of the commitments of QUAL, whose
+Key is the public key of the group.
+*/
+func (p *Participant) Finish() (*KeyShare, *Commitment, error) {
+	msgs, err := p.receive(func(msg *Message) bool {
+		return msg.To == 0 && msg.Share != nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	answers := map[int]map[int]*Share{}
+	for _, msg := range msgs {
+		if msg.To != 0 || msg.Share == nil {
+			continue
+		}
+		if answers[msg.From] == nil {
+			answers[msg.From] = map[int]*Share{}
+		}
+		answers[msg.From][msg.Share.Index] = msg.Share
+	}
+
+	for j, complainers := range p.complaints {
+		if p.disqualified[j] {
+			continue
+		}
+		if len(complainers) >= p.t {
+			p.disqualified[j] = true
+			continue
+		}
+		if j == p.index {
+			//our own answers are not delivered back to us, and are valid
+			continue
+		}
+		for i := range complainers {
+			share := answers[j][i]
+			if share == nil || share.Index != i || !p.commitments[j].VerifyShare(share) {
+				p.disqualified[j] = true
+				break
+			}
+			if i == p.index {
+				p.shares[j] = share
+			}
+		}
+	}
+
+	x := new(big.Int)
+	var group *Commitment
+	for j := 1; j <= p.n; j++ {
+		if p.disqualified[j] {
+			continue
+		}
+		x.Add(x, p.shares[j].Value)
+		group = addCommitments(group, p.commitments[j])
+	}
+	if group == nil {
+		return nil, nil, errors.New("bls: every dealer was disqualified")
+	}
+	if p.disqualified[p.index] {
+		return nil, nil, errors.New("bls: participant was disqualified")
+	}
+	return newKeyShare(&Share{p.index, x.Mod(x, bn256.Order)}), group, nil
+}
+
+/*
+Qualified returns the indices of the dealers that were not disqualified.
+*/
+func (p *Participant) Qualified() []int {
+	var qual []int
+	for j := 1; j <= p.n; j++ {
+		if !p.disqualified[j] {
+			qual = append(qual, j)
+		}
+	}
+	return qual
+}
+
+/*
+RunLocalDKG runs a t-of-n DKG over a MemoryTransport and returns the key shares
+of all participants and the group commitment.
+*/
+func RunLocalDKG(t, n int, r io.Reader) ([]*KeyShare, *Commitment, error) {
+	transport := NewMemoryTransport(n)
+	participants := make([]*Participant, n)
+	for i := range participants {
+		p, err := NewParticipant(i+1, t, n, transport, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		participants[i] = p
+	}
+	return runDKG(participants)
+}
+
+/*
+runDKG runs the rounds of the participants in lockstep and checks that they
+agree on the public key.
+*/
+func runDKG(participants []*Participant) ([]*KeyShare, *Commitment, error) {
+	rounds := []func(*Participant) error{
+		(*Participant).Deal,
+		(*Participant).Complain,
+		(*Participant).Justify,
+	}
+	for _, round := range rounds {
+		for _, p := range participants {
+			if err := round(p); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	keys := make([]*KeyShare, len(participants))
+	var group *Commitment
+	for i, p := range participants {
+		key, c, err := p.Finish()
+		if err != nil {
+			return nil, nil, err
+		}
+		if group != nil && !bytes.Equal(c.Key.Marshal(), group.Key.Marshal()) {
+			return nil, nil, errors.New("bls: participants disagree on the public key")
+		}
+		keys[i], group = key, c
+	}
+	return keys, group, nil
+}
+
+/*
+receive returns the pending and newly received messages for which round is
+true and keeps the others pending. A participant that has finished a round
+may already find messages of the next round from faster participants.
+*/
+func (p *Participant) receive(round func(*Message) bool) ([]*Message, error) {
+	msgs, err := p.transport.Receive(p.index)
+	if err != nil {
+		return nil, err
+	}
+	var ret, pending []*Message
+	for _, msg := range append(p.pending, msgs...) {
+		if msg == nil {
+			continue
+		}
+		if round(msg) {
+			ret = append(ret, msg)
+		} else {
+			pending = append(pending, msg)
+		}
+	}
+	p.pending = pending
+	return ret, nil
+}
+
+func (p *Participant) complain(dealer, complainer int) {
+	if dealer < 1 || dealer > p.n {
+		return
+	}
+	if p.complaints[dealer] == nil {
+		p.complaints[dealer] = map[int]bool{}
+	}
+	p.complaints[dealer][complainer] = true
+}
+
+/*
+addCommitments returns the coefficient-wise sum of a and b, where a may be nil.
+*/
+func addCommitments(a, b *Commitment) *Commitment {
+	if a == nil {
+		return b
+	}
+	C := make([]*bn256.G1, len(b.Coefficients))
+	for j := range C {
+		C[j] = new(bn256.G1).Add(a.Coefficients[j], b.Coefficients[j])
+	}
+	return &Commitment{C, &PublicKey{new(bn256.G2).Add(a.Key.p, b.Key.p)}}
+}
+
+func sortedKeys(m map[int]bool) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,137 @@
+package bls
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func newParticipants(t *testing.T, threshold, n int) ([]*Participant, *MemoryTransport) {
+	transport := NewMemoryTransport(n)
+	participants := make([]*Participant, n)
+	for i := range participants {
+		p, err := NewParticipant(i+1, threshold, n, transport, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		participants[i] = p
+	}
+	return participants, transport
+}
+
+func checkThresholdKey(t *testing.T, keys []*KeyShare, c *Commitment, threshold int) {
+	msg := []byte("message")
+	partials := make([]*PartialSignature, len(keys))
+	for i, key := range keys {
+		if !c.VerifyPublicKey(key.Index, key.Public()) {
+			t.Errorf("key share %d does not match the group commitment", key.Index)
+		}
+		partials[i] = key.SignShare(msg)
+	}
+	sig, err := Combine(partials[len(partials)-threshold:], threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(c.Key, msg, sig) {
+		t.Errorf("threshold signature does not verify under the group key")
+	}
+}
+
+func TestDKG(t *testing.T) {
+	keys, c, err := RunLocalDKG(3, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Valid() || c.Threshold() != 3 {
+		t.Errorf("invalid group commitment")
+	}
+	checkThresholdKey(t, keys, c, 3)
+}
+
+func TestDKGComplaint(t *testing.T) {
+	participants, transport := newParticipants(t, 3, 5)
+	for _, p := range participants {
+		if err := p.Deal(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//dealer 1 sends participant 2 a wrong share, but answers the complaint
+	for _, msg := range transport.inbox[2] {
+		if msg.From == 1 && msg.Share != nil {
+			msg.Share = &Share{2, new(big.Int).Add(msg.Share.Value, big.NewInt(1))}
+		}
+	}
+	keys, c := finishDKG(t, participants)
+	for _, p := range participants {
+		if len(p.Qualified()) != 5 {
+			t.Errorf("participant %d disqualified an honest dealer", p.index)
+		}
+	}
+	checkThresholdKey(t, keys, c, 3)
+}
+
+func TestDKGDisqualify(t *testing.T) {
+	participants, transport := newParticipants(t, 3, 5)
+	for _, p := range participants {
+		if err := p.Deal(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//dealer 1 sends participant 2 a wrong share and then stays silent
+	for _, msg := range transport.inbox[2] {
+		if msg.From == 1 && msg.Share != nil {
+			msg.Share = &Share{2, new(big.Int).Add(msg.Share.Value, big.NewInt(1))}
+		}
+	}
+	for _, p := range participants {
+		if err := p.Complain(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range participants[1:] {
+		if err := p.Justify(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var group *Commitment
+	for _, p := range participants[1:] {
+		key, c, err := p.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if qual := p.Qualified(); len(qual) != 4 || qual[0] != 2 {
+			t.Errorf("participant %d did not disqualify dealer 1: %v", p.index, qual)
+		}
+		if group != nil && !bytes.Equal(c.Key.Marshal(), group.Key.Marshal()) {
+			t.Errorf("participants disagree on the group key")
+		}
+		group = c
+		if !c.VerifyPublicKey(key.Index, key.Public()) {
+			t.Errorf("key share %d does not match the group commitment", key.Index)
+		}
+	}
+}
+
+func finishDKG(t *testing.T, participants []*Participant) ([]*KeyShare, *Commitment) {
+	for _, round := range []func(*Participant) error{(*Participant).Complain, (*Participant).Justify} {
+		for _, p := range participants {
+			if err := round(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	keys := make([]*KeyShare, len(participants))
+	var group *Commitment
+	for i, p := range participants {
+		key, c, err := p.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if group != nil && !bytes.Equal(c.Key.Marshal(), group.Key.Marshal()) {
+			t.Errorf("participants disagree on the group key")
+		}
+		keys[i], group = key, c
+	}
+	return keys, group
+}
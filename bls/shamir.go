@@ -0,0 +1,311 @@
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+// PedersenDST is the domain-separation tag from which the second generator h
+// of Pedersen commitments is derived.
+const PedersenDST = "blockchain-research/crypto/bls/pedersen-h/v1"
+
+var (
+	// g1 is the generator of G1.
+	g1 = new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	// pedersenH is a generator of G1 whose discrete logarithm is unknown.
+	pedersenH = bn256.HashToG1(nil, []byte(PedersenDST))
+)
+
+/*
+Share is the value f(Index) of a secret polynomial f over Z_Order, held by the
+participant with the given index. Indices start at 1, since f(0) is the secret.
+*/
+type Share struct {
+	Index int
+	Value *big.Int
+}
+
+/*
+Commitment is a Feldman commitment C_j = a_jg1 to the coefficients of
+f(x) = a_0 + a_1x + ... + a_t-1x^t-1, together with the public key a_0g2 of
+the secret. Anybody can derive the G1 image f(i)g1 of every share from it.
+*/
+type Commitment struct {
+	Coefficients []*bn256.G1
+	Key          *PublicKey
+}
+
+/*
+PedersenShare is a share of the secret polynomial f together with the share of
+the blinding polynomial f'.
+*/
+type PedersenShare struct {
+	Share
+	Blind *big.Int
+}
+
+/*
+PedersenCommitment is a Pedersen commitment C_j = a_jg1 + b_jh to the
+coefficients of f and f'. Unlike a Feldman commitment it reveals nothing about
+the secret.
+*/
+type PedersenCommitment struct {
+	Coefficients []*bn256.G1
+}
+
+/*
+Split shares secret among n participants so that any t of them can recover it
+and fewer learn nothing, and returns the shares with their Feldman commitment.
+*/
+func Split(secret *big.Int, t, n int, r io.Reader) ([]*Share, *Commitment, error) {
+	f, err := randomPolynomial(secret, t, n, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return evalShares(f, n), commit(f), nil
+}
+
+/*
+SplitPedersen is Split with a Pedersen commitment.
+*/
+func SplitPedersen(secret *big.Int, t, n int, r io.Reader) ([]*PedersenShare, *PedersenCommitment, error) {
+	f, err := randomPolynomial(secret, t, n, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := randomPolynomial(nil, t, n, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	C := make([]*bn256.G1, t)
+	for j := range C {
+		C[j] = new(bn256.G1).ScalarBaseMult(f[j])
+		C[j].Add(C[j], new(bn256.G1).ScalarMult(pedersenH, b[j]))
+	}
+	shares := make([]*PedersenShare, n)
+	for i := range shares {
+		shares[i] = &PedersenShare{
+			Share: Share{i + 1, evalPolynomial(f, i+1)},
+			Blind: evalPolynomial(b, i+1),
+		}
+	}
+	return shares, &PedersenCommitment{C}, nil
+}
+
+/*
+Recover interpolates the secret f(0) from t shares with distinct indices.
+*/
+func Recover(shares []*Share, t int) (*big.Int, error) {
+	indices, err := thresholdIndices(shareIndices(shares), t)
+	if err != nil {
+		return nil, err
+	}
+	lambda := lagrangeCoefficients(indices)
+	secret := new(big.Int)
+	for k := range indices {
+		secret.Add(secret, new(big.Int).Mul(lambda[k], shares[k].Value))
+	}
+	return secret.Mod(secret, bn256.Order), nil
+}
+
+/*
+Threshold returns the number of shares needed to recover the secret.
+*/
+func (c *Commitment) Threshold() int {
+	return len(c.Coefficients)
+}
+
+/*
+Eval returns f(index)g1 = sum(index^j.C_j).
+*/
+func (c *Commitment) Eval(index int) *bn256.G1 {
+	return evalCommitment(c.Coefficients, index)
+}
+
+/*
+Valid checks that the commitment is well formed and that Key is the G2 image
+of the committed secret, e(C_0, g2) = e(g1, Key).
+*/
+func (c *Commitment) Valid() bool {
+	if c == nil || len(c.Coefficients) == 0 || !c.Key.valid() {
+		return false
+	}
+	for _, C := range c.Coefficients {
+		if C == nil {
+			return false
+		}
+	}
+	return c.VerifyPublicKey(0, c.Key)
+}
+
+/*
+VerifyShare checks the share against the commitment, share.Value.g1 = Eval(share.Index).
+*/
+func (c *Commitment) VerifyShare(share *Share) bool {
+	if share == nil || share.Index <= 0 || share.Value == nil {
+		return false
+	}
+	lhs := new(bn256.G1).ScalarBaseMult(new(big.Int).Mod(share.Value, bn256.Order))
+	return g1Equal(lhs, c.Eval(share.Index))
+}
+
+/*
+VerifyPublicKey checks that pub is the public key f(index)g2 of the share with
+the given index, e(Eval(index), g2) = e(g1, pub). Index 0 checks the public key
+of the secret.
+*/
+func (c *Commitment) VerifyPublicKey(index int, pub *PublicKey) bool {
+	if !pub.valid() {
+		return false
+	}
+	return bn256.PairingCheck(
+		[]*bn256.G1{c.Eval(index), new(bn256.G1).Neg(g1)},
+		[]*bn256.G2{g2, pub.p},
+	)
+}
+
+/*
+VerifyShare checks the share against the commitment,
+share.Value.g1 + share.Blind.h = sum(share.Index^j.C_j).
+*/
+func (c *PedersenCommitment) VerifyShare(share *PedersenShare) bool {
+	if share == nil || share.Index <= 0 || share.Value == nil || share.Blind == nil {
+		return false
+	}
+	lhs := new(bn256.G1).ScalarBaseMult(new(big.Int).Mod(share.Value, bn256.Order))
+	lhs.Add(lhs, new(bn256.G1).ScalarMult(pedersenH, new(big.Int).Mod(share.Blind, bn256.Order)))
+	return g1Equal(lhs, evalCommitment(c.Coefficients, share.Index))
+}
+
+/*
+randomPolynomial returns the coefficients of a random polynomial of degree t-1
+with constant term secret, or a random one if secret is nil.
+*/
+func randomPolynomial(secret *big.Int, t, n int, r io.Reader) ([]*big.Int, error) {
+	if t < 1 || t > n {
+		return nil, errors.New("bls: threshold must be between 1 and the number of participants")
+	}
+	if r == nil {
+		r = rand.Reader
+	}
+	f := make([]*big.Int, t)
+	for j := range f {
+		a, err := rand.Int(r, bn256.Order)
+		if err != nil {
+			return nil, err
+		}
+		f[j] = a
+	}
+	if secret != nil {
+		f[0] = new(big.Int).Mod(secret, bn256.Order)
+	}
+	return f, nil
+}
+
+/*
+evalPolynomial returns f(x) by Horner's rule.
+*/
+func evalPolynomial(f []*big.Int, x int) *big.Int {
+	X := big.NewInt(int64(x))
+	ret := new(big.Int)
+	for j := len(f) - 1; j >= 0; j-- {
+		ret.Mul(ret, X)
+		ret.Add(ret, f[j])
+		ret.Mod(ret, bn256.Order)
+	}
+	return ret
+}
+
+func evalShares(f []*big.Int, n int) []*Share {
+	shares := make([]*Share, n)
+	for i := range shares {
+		shares[i] = &Share{i + 1, evalPolynomial(f, i+1)}
+	}
+	return shares
+}
+
+func commit(f []*big.Int) *Commitment {
+	C := make([]*bn256.G1, len(f))
+	for j := range C {
+		C[j] = new(bn256.G1).ScalarBaseMult(f[j])
+	}
+	return &Commitment{C, &PublicKey{new(bn256.G2).ScalarBaseMult(f[0])}}
+}
+
+/*
+evalCommitment returns sum(x^j.C_j) by Horner's rule.
+*/
+func evalCommitment(C []*bn256.G1, x int) *bn256.G1 {
+	X := big.NewInt(int64(x))
+	ret := new(bn256.G1).SetInfinity()
+	for j := len(C) - 1; j >= 0; j-- {
+		ret.ScalarMult(ret, X)
+		ret.Add(ret, C[j])
+	}
+	return ret
+}
+
+/*
+lagrangeCoefficients returns lambda_i = prod(j/(j-i)) over all other indices j,
+so that f(0) = sum(lambda_i.f(i)).
+*/
+func lagrangeCoefficients(indices []int) []*big.Int {
+	lambda := make([]*big.Int, len(indices))
+	for k, i := range indices {
+		num := big.NewInt(1)
+		den := big.NewInt(1)
+		for _, j := range indices {
+			if j == i {
+				continue
+			}
+			num.Mul(num, big.NewInt(int64(j)))
+			den.Mul(den, big.NewInt(int64(j-i)))
+		}
+		den.Mod(den, bn256.Order)
+		lambda[k] = num.Mul(num, den.ModInverse(den, bn256.Order))
+		lambda[k].Mod(lambda[k], bn256.Order)
+	}
+	return lambda
+}
+
+func shareIndices(shares []*Share) []int {
+	indices := make([]int, len(shares))
+	for k, share := range shares {
+		if share == nil || share.Value == nil {
+			indices[k] = 0
+			continue
+		}
+		indices[k] = share.Index
+	}
+	return indices
+}
+
+/*
+thresholdIndices checks that the first t indices are positive and distinct and
+returns them.
+*/
+func thresholdIndices(indices []int, t int) ([]int, error) {
+	if t < 1 || len(indices) < t {
+		return nil, errors.New("bls: not enough shares")
+	}
+	seen := make(map[int]bool, t)
+	for _, i := range indices[:t] {
+		if i <= 0 {
+			return nil, errors.New("bls: invalid share index")
+		}
+		if seen[i] {
+			return nil, errors.New("bls: duplicate share index")
+		}
+		seen[i] = true
+	}
+	return indices[:t], nil
+}
+
+func g1Equal(a, b *bn256.G1) bool {
+	return bytes.Equal(a.Marshal(), b.Marshal())
+}
@@ -0,0 +1,90 @@
+package bls
+
+import (
+	"errors"
+	"io"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+/*
+KeyShare is the share x_i = f(i) of a t-of-n private key x = f(0) held by the
+participant with index i. Its embedded PrivateKey signs partial signatures and
+its public key x_ig2 verifies them.
+*/
+type KeyShare struct {
+	Index int
+	PrivateKey
+}
+
+/*
+PartialSignature is the signature x_iH(m) of the participant with index i.
+*/
+type PartialSignature struct {
+	Index int
+	Signature
+}
+
+/*
+SplitKey shares priv among n participants so that any t of them can sign for
+its public key. The commitment lets every participant verify its share and the
+public keys of the others.
+*/
+func SplitKey(priv *PrivateKey, t, n int, r io.Reader) ([]*KeyShare, *Commitment, error) {
+	shares, c, err := Split(priv.x, t, n, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := make([]*KeyShare, n)
+	for i, share := range shares {
+		keys[i] = newKeyShare(share)
+	}
+	return keys, c, nil
+}
+
+/*
+SignShare returns the partial signature x_iH(msg).
+*/
+func (ks *KeyShare) SignShare(msg []byte) *PartialSignature {
+	return &PartialSignature{ks.Index, *ks.Sign(msg)}
+}
+
+/*
+VerifyPartial checks that pub is the public key of share ps.Index under the
+commitment and that ps is a signature on msg by it.
+*/
+func (c *Commitment) VerifyPartial(pub *PublicKey, msg []byte, ps *PartialSignature) bool {
+	if ps == nil || ps.Index <= 0 {
+		return false
+	}
+	return c.VerifyPublicKey(ps.Index, pub) && Verify(pub, msg, &ps.Signature)
+}
+
+/*
+Combine recovers the signature xH(m) = sum(lambda_i.x_iH(m)) from the first t
+partial signatures, which must have distinct indices. It does not verify them;
+an invalid partial signature yields an invalid signature.
+*/
+func Combine(partials []*PartialSignature, t int) (*Signature, error) {
+	indices := make([]int, len(partials))
+	for k, ps := range partials {
+		if ps == nil || !ps.Signature.valid() {
+			return nil, errors.New("bls: invalid partial signature")
+		}
+		indices[k] = ps.Index
+	}
+	indices, err := thresholdIndices(indices, t)
+	if err != nil {
+		return nil, err
+	}
+	lambda := lagrangeCoefficients(indices)
+	sum := new(bn256.G1).SetInfinity()
+	for k := range indices {
+		sum.Add(sum, new(bn256.G1).ScalarMult(partials[k].s, lambda[k]))
+	}
+	return &Signature{sum}, nil
+}
+
+func newKeyShare(share *Share) *KeyShare {
+	return &KeyShare{share.Index, PrivateKey{PublicKey{new(bn256.G2).ScalarBaseMult(share.Value)}, share.Value}}
+}
@@ -0,0 +1,117 @@
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+func TestSplitRecover(t *testing.T) {
+	secret, _ := rand.Int(rand.Reader, bn256.Order)
+	shares, c, err := Split(secret, 3, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Valid() || c.Threshold() != 3 {
+		t.Errorf("invalid commitment")
+	}
+	for _, share := range shares {
+		if !c.VerifyShare(share) {
+			t.Errorf("share %d does not match the commitment", share.Index)
+		}
+	}
+
+	//any 3 shares recover the secret
+	for _, subset := range [][]*Share{shares[:3], shares[2:], {shares[4], shares[0], shares[2]}} {
+		got, err := Recover(subset, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(secret) != 0 {
+			t.Errorf("Recover returned the wrong secret")
+		}
+	}
+	if got, _ := Recover(shares[:2], 2); got.Cmp(secret) == 0 {
+		t.Errorf("2 shares recovered the secret")
+	}
+	if _, err := Recover([]*Share{shares[0], shares[0], shares[1]}, 3); err == nil {
+		t.Errorf("expected error for duplicate shares")
+	}
+
+	bad := &Share{shares[0].Index, new(big.Int).Add(shares[0].Value, big.NewInt(1))}
+	if c.VerifyShare(bad) {
+		t.Errorf("modified share matches the commitment")
+	}
+	if _, _, err := Split(secret, 6, 5, nil); err == nil {
+		t.Errorf("expected error for threshold above n")
+	}
+}
+
+func TestSplitPedersen(t *testing.T) {
+	secret, _ := rand.Int(rand.Reader, bn256.Order)
+	shares, c, err := SplitPedersen(secret, 2, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := make([]*Share, len(shares))
+	for i, share := range shares {
+		if !c.VerifyShare(share) {
+			t.Errorf("share %d does not match the commitment", share.Index)
+		}
+		plain[i] = &share.Share
+	}
+	if got, _ := Recover(plain[1:3], 2); got.Cmp(secret) != 0 {
+		t.Errorf("Recover returned the wrong secret")
+	}
+
+	shares[0].Blind.Add(shares[0].Blind, big.NewInt(1))
+	if c.VerifyShare(shares[0]) {
+		t.Errorf("modified share matches the commitment")
+	}
+}
+
+func TestThresholdSignature(t *testing.T) {
+	priv := keys(t, 1)[0]
+	shares, c, err := SplitKey(priv, 3, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.VerifyPublicKey(0, priv.Public()) {
+		t.Errorf("commitment does not match the public key")
+	}
+
+	msg := []byte("message")
+	partials := make([]*PartialSignature, len(shares))
+	for i, share := range shares {
+		partials[i] = share.SignShare(msg)
+		if !c.VerifyPartial(share.Public(), msg, partials[i]) {
+			t.Errorf("partial signature %d verification failed", share.Index)
+		}
+	}
+	//a partial signature does not verify under another share's key
+	if c.VerifyPartial(shares[1].Public(), msg, partials[0]) {
+		t.Errorf("partial signature verified under another key")
+	}
+
+	sig, err := Combine([]*PartialSignature{partials[4], partials[1], partials[2]}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(priv.Public(), msg, sig) {
+		t.Errorf("combined signature verification failed")
+	}
+	if !bytes.Equal(sig.Marshal(), priv.Sign(msg).Marshal()) {
+		t.Errorf("combined signature differs from the signature of the private key")
+	}
+
+	sig, _ = Combine(partials[:2], 2)
+	if Verify(priv.Public(), msg, sig) {
+		t.Errorf("2 partial signatures formed a signature")
+	}
+	if _, err := Combine(partials[:2], 3); err == nil {
+		t.Errorf("expected error for too few partial signatures")
+	}
+}
@@ -24,12 +24,18 @@ package bn256
 
 import (
 	"crypto/rand"
+	"errors"
 	"io"
 	"math/big"
 )
 
-// BUG(agl): this implementation is not constant time.
-// TODO(agl): keep GF(p²) elements in Mongomery form.
+// BUG(agl): this implementation is not constant time. Only the point
+// arithmetic of ScalarMult and ScalarBaseMult in G₁ and G₂, on the
+// Montgomery-form gfP of ladder.go, glv.go and fixedbase.go, takes the same
+// steps and memory accesses for every scalar and point. Around it, the scalar
+// is reduced modulo Order and split by the GLV and GLS lattices with math/big,
+// and points are converted to and from math/big, which is not constant time.
+// The pairing, GT and the other group operations use math/big throughout.
 
 // G1 is an abstract cyclic group. The zero value is suitable for use as the
 // output of an operation, but cannot be used as an input.
@@ -60,22 +66,10 @@ func (e *G1) String() string {
 }
 
 // ScalarBaseMult sets e to g*k where g is the generator of the group and
-// then returns e.
+// then returns e. It uses a precomputed table of multiples of g.
 //Update: deal with negative int
 func (e *G1) ScalarBaseMult(k *big.Int) *G1 {
-	if e.p == nil {
-		e.p = newCurvePoint(nil)
-	}
-	cmp := k.Cmp(big.NewInt(0))
-	if cmp == 0 {
-		e.p.SetInfinity()
-	}
-	if cmp > 0 {
-		e.p.Mul(curveGen, k, new(bnPool))
-	}
-	if cmp < 0 {
-		e.p.Negative(e.p.Mul(curveGen, new(big.Int).Abs(k), new(bnPool)))
-	}
+	e.p = curveGenBase().ScalarMult(k).p
 	return e
 }
 
@@ -201,19 +195,10 @@ func (e *G2) String() string {
 }
 
 // ScalarBaseMult sets e to g*k where g is the generator of the group and
-// then returns out.
+// then returns out. It uses a precomputed table of multiples of g.
 //Update: deal with negative int
 func (e *G2) ScalarBaseMult(k *big.Int) *G2 {
-	if e.p == nil {
-		e.p = newTwistPoint(nil)
-	}
-
-	if k.Cmp(big.NewInt(0)) < 0 {
-		k = new(big.Int).Add(Order, k)
-		k = new(big.Int).Mod(k, Order)
-	}
-	e.p.Mul(twistGen, k, new(bnPool))
-
+	e.p = twistGenBase().ScalarMult(k).p
 	return e
 }
 
@@ -269,13 +254,14 @@ func (n *G2) Marshal() []byte {
 }
 
 // Unmarshal sets e to the result of converting the output of Marshal back into
-// a group element and then returns e.
-func (e *G2) Unmarshal(m []byte) (*G2, bool) {
+// a group element and then returns e. It returns an error unless m encodes a
+// point of G₂ with coordinates reduced modulo p.
+func (e *G2) Unmarshal(m []byte) (*G2, error) {
 	// Each value is a 256-bit number.
 	const numBytes = 256 / 8
 
 	if len(m) != 4*numBytes {
-		return nil, false
+		return nil, errors.New("bn256: invalid G2 length")
 	}
 
 	if e.p == nil {
@@ -287,6 +273,12 @@ func (e *G2) Unmarshal(m []byte) (*G2, bool) {
 	e.p.y.x.SetBytes(m[2*numBytes : 3*numBytes])
 	e.p.y.y.SetBytes(m[3*numBytes : 4*numBytes])
 
+	for _, v := range []*big.Int{e.p.x.x, e.p.x.y, e.p.y.x, e.p.y.y} {
+		if v.Cmp(p) >= 0 {
+			return nil, errors.New("bn256: coordinate not reduced modulo p")
+		}
+	}
+
 	if e.p.x.x.Sign() == 0 &&
 		e.p.x.y.Sign() == 0 &&
 		e.p.y.x.Sign() == 0 &&
@@ -300,11 +292,14 @@ func (e *G2) Unmarshal(m []byte) (*G2, bool) {
 		e.p.t.SetOne()
 
 		if !e.p.IsOnCurve() {
-			return nil, false
+			return nil, errors.New("bn256: point not on curve")
+		}
+		if !e.p.inSubgroup(new(bnPool)) {
+			return nil, errors.New("bn256: point not in G2")
 		}
 	}
 
-	return e, true
+	return e, nil
 }
 
 // GT is an abstract cyclic group. The zero value is suitable for use as the
@@ -382,33 +377,36 @@ func (n *GT) Marshal() []byte {
 }
 
 // Unmarshal sets e to the result of converting the output of Marshal back into
-// a group element and then returns e.
-func (e *GT) Unmarshal(m []byte) (*GT, bool) {
+// a group element and then returns e. It returns an error unless m encodes an
+// element of GT with coordinates reduced modulo p.
+func (e *GT) Unmarshal(m []byte) (*GT, error) {
 	// Each value is a 256-bit number.
 	const numBytes = 256 / 8
 
 	if len(m) != 12*numBytes {
-		return nil, false
+		return nil, errors.New("bn256: invalid GT length")
 	}
 
 	if e.p == nil {
 		e.p = newGFp12(nil)
 	}
 
-	e.p.x.x.x.SetBytes(m[0*numBytes : 1*numBytes])
-	e.p.x.x.y.SetBytes(m[1*numBytes : 2*numBytes])
-	e.p.x.y.x.SetBytes(m[2*numBytes : 3*numBytes])
-	e.p.x.y.y.SetBytes(m[3*numBytes : 4*numBytes])
-	e.p.x.z.x.SetBytes(m[4*numBytes : 5*numBytes])
-	e.p.x.z.y.SetBytes(m[5*numBytes : 6*numBytes])
-	e.p.y.x.x.SetBytes(m[6*numBytes : 7*numBytes])
-	e.p.y.x.y.SetBytes(m[7*numBytes : 8*numBytes])
-	e.p.y.y.x.SetBytes(m[8*numBytes : 9*numBytes])
-	e.p.y.y.y.SetBytes(m[9*numBytes : 10*numBytes])
-	e.p.y.z.x.SetBytes(m[10*numBytes : 11*numBytes])
-	e.p.y.z.y.SetBytes(m[11*numBytes : 12*numBytes])
+	coords := []*big.Int{
+		e.p.x.x.x, e.p.x.x.y, e.p.x.y.x, e.p.x.y.y, e.p.x.z.x, e.p.x.z.y,
+		e.p.y.x.x, e.p.y.x.y, e.p.y.y.x, e.p.y.y.y, e.p.y.z.x, e.p.y.z.y,
+	}
+	for i, v := range coords {
+		v.SetBytes(m[i*numBytes : (i+1)*numBytes])
+		if v.Cmp(p) >= 0 {
+			return nil, errors.New("bn256: coordinate not reduced modulo p")
+		}
+	}
+
+	if !e.p.inSubgroup(new(bnPool)) {
+		return nil, errors.New("bn256: element not in GT")
+	}
 
-	return e, true
+	return e, nil
 }
 
 // Pair calculates an Optimal Ate pairing.
@@ -416,6 +414,41 @@ func Pair(g1 *G1, g2 *G2) *GT {
 	return &GT{optimalAte(g2.p, g1.p, new(bnPool))}
 }
 
+// MultiPair calculates the product of the Optimal Ate pairings e(g1s[i], g2s[i]).
+// It runs a Miller loop per pair but only a single final exponentiation, so it
+// is much faster than multiplying the results of Pair. It returns nil if the
+// slices have different lengths.
+func MultiPair(g1s []*G1, g2s []*G2) *GT {
+	if len(g1s) != len(g2s) {
+		return nil
+	}
+	pool := new(bnPool)
+	acc := newGFp12(pool)
+	acc.SetOne()
+	for i := range g1s {
+		// e(O, Q) = e(P, O) = 1, and the Miller loop does not handle infinity.
+		if g1s[i].p.IsInfinity() || g2s[i].p.IsInfinity() {
+			continue
+		}
+		m := miller(g2s[i].p, g1s[i].p, pool)
+		acc.Mul(acc, m, pool)
+		m.Put(pool)
+	}
+	ret := finalExponentiation(acc, pool)
+	acc.Put(pool)
+	return &GT{ret}
+}
+
+// PairingCheck returns true iff the product of the pairings e(g1s[i], g2s[i])
+// is the identity of GT. It returns false if the slices have different lengths.
+func PairingCheck(g1s []*G1, g2s []*G2) bool {
+	e := MultiPair(g1s, g2s)
+	if e == nil {
+		return false
+	}
+	return e.p.IsOne()
+}
+
 // bnPool implements a tiny cache of *big.Int objects that's used to reduce the
 // number of allocations made during processing.
 type bnPool struct {
@@ -7,6 +7,7 @@ package bn256
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/hex"
 	"math/big"
 	"testing"
 )
@@ -238,22 +239,230 @@ func TestG1Marshal(t *testing.T) {
 func TestG2Marshal(t *testing.T) {
 	g := new(G2).ScalarBaseMult(new(big.Int).SetInt64(1))
 	form := g.Marshal()
-	_, ok := new(G2).Unmarshal(form)
-	if !ok {
-		t.Fatalf("failed to unmarshal")
+	_, err := new(G2).Unmarshal(form)
+	if err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
 	}
 
 	g.ScalarBaseMult(Order)
 	form = g.Marshal()
-	g2, ok := new(G2).Unmarshal(form)
-	if !ok {
-		t.Fatalf("failed to unmarshal ∞")
+	g2, err := new(G2).Unmarshal(form)
+	if err != nil {
+		t.Fatalf("failed to unmarshal ∞: %v", err)
 	}
 	if !g2.p.IsInfinity() {
 		t.Fatalf("∞ unmarshaled incorrectly")
 	}
 }
 
+func TestG1Compressed(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		_, g, _ := RandomG1(rand.Reader)
+		m := g.MarshalCompressed()
+		if len(m) != CompressedG1Size {
+			t.Fatalf("bad compressed length %d", len(m))
+		}
+		g2, err := new(G1).UnmarshalCompressed(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(g.Marshal(), g2.Marshal()) {
+			t.Fatalf("compressed round trip failed")
+		}
+		// flipping the sign gives -g
+		m[0] ^= 1
+		neg, err := new(G1).UnmarshalCompressed(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(neg.Marshal(), new(G1).Neg(g).Marshal()) {
+			t.Errorf("sign flag does not select -g")
+		}
+	}
+
+	inf := new(G1).SetInfinity()
+	m := inf.MarshalCompressed()
+	if g, err := new(G1).UnmarshalCompressed(m); err != nil || !g.IsZero() {
+		t.Errorf("compressed infinity round trip failed")
+	}
+	m[CompressedG1Size-1] = 1
+	if _, err := new(G1).UnmarshalCompressed(m); err == nil {
+		t.Errorf("expected error for non-canonical infinity")
+	}
+
+	// x >= p, a bad flag and a bad length
+	bad := make([]byte, CompressedG1Size)
+	bad[0] = 0x02
+	p.FillBytes(bad[1:])
+	if _, err := new(G1).UnmarshalCompressed(bad); err == nil {
+		t.Errorf("expected error for x >= p")
+	}
+	one := new(G1).ScalarBaseMult(big.NewInt(1)).MarshalCompressed()
+	for _, flag := range []byte{0x01, 0x04, 0x82} {
+		one[0] = flag
+		if _, err := new(G1).UnmarshalCompressed(one); err == nil {
+			t.Errorf("expected error for flag %#x", flag)
+		}
+	}
+	if _, err := new(G1).UnmarshalCompressed(one[1:]); err == nil {
+		t.Errorf("expected error for short input")
+	}
+
+	// an x with no point on the curve
+	for x := int64(2); ; x++ {
+		rhs := big.NewInt(x*x*x + 3)
+		if _, ok := sqrtGFp(rhs); ok {
+			continue
+		}
+		bad = make([]byte, CompressedG1Size)
+		bad[0] = 0x02
+		big.NewInt(x).FillBytes(bad[1:])
+		if _, err := new(G1).UnmarshalCompressed(bad); err == nil {
+			t.Errorf("expected error for x off the curve")
+		}
+		break
+	}
+}
+
+func TestG2Compressed(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		_, g, _ := RandomG2(rand.Reader)
+		m := g.MarshalCompressed()
+		if len(m) != CompressedG2Size {
+			t.Fatalf("bad compressed length %d", len(m))
+		}
+		g2, err := new(G2).UnmarshalCompressed(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(g.Marshal(), g2.Marshal()) {
+			t.Fatalf("compressed round trip failed")
+		}
+		m[0] ^= 1
+		neg, err := new(G2).UnmarshalCompressed(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(neg.Marshal(), new(G2).Neg(g).Marshal()) {
+			t.Errorf("sign flag does not select -g")
+		}
+	}
+
+	m := new(G2).SetInfinity().MarshalCompressed()
+	if g, err := new(G2).UnmarshalCompressed(m); err != nil || !g.IsZero() {
+		t.Errorf("compressed infinity round trip failed")
+	}
+	m[1] = 1
+	if _, err := new(G2).UnmarshalCompressed(m); err == nil {
+		t.Errorf("expected error for non-canonical infinity")
+	}
+
+	one := new(G2).ScalarBaseMult(big.NewInt(1)).MarshalCompressed()
+	bad := append([]byte{}, one...)
+	p.FillBytes(bad[33:])
+	if _, err := new(G2).UnmarshalCompressed(bad); err == nil {
+		t.Errorf("expected error for x.y >= p")
+	}
+	one[0] = 0x04
+	if _, err := new(G2).UnmarshalCompressed(one); err == nil {
+		t.Errorf("expected error for a bad flag")
+	}
+}
+
+// twistPointOutsideG2 returns a point of the twist that is not in G₂.
+func twistPointOutsideG2() *twistPoint {
+	pool := new(bnPool)
+	for x := int64(1); ; x++ {
+		pt := newTwistPoint(nil)
+		pt.x.x.SetInt64(1)
+		pt.x.y.SetInt64(x)
+		rhs := newGFp2(pool).Square(pt.x, pool)
+		rhs.Mul(rhs, pt.x, pool)
+		rhs.Add(rhs, twistB)
+		rhs.Minimal()
+		if _, ok := pt.y.Sqrt(rhs, pool); !ok {
+			continue
+		}
+		pt.z.SetOne()
+		pt.t.SetOne()
+		r := newCTTwistPoint(pt)
+		ctTwistMul(r, r, Order)
+		if gfpIsZero(&r.z.x)&gfpIsZero(&r.z.y) == 0 {
+			return pt
+		}
+	}
+}
+
+func TestG2Subgroup(t *testing.T) {
+	pool := new(bnPool)
+	for i := 0; i < 5; i++ {
+		_, g, _ := RandomG2(rand.Reader)
+		if !g.p.inSubgroup(pool) {
+			t.Errorf("point of G2 failed the subgroup check")
+		}
+	}
+
+	pt := twistPointOutsideG2()
+	if pt.inSubgroup(pool) {
+		t.Errorf("point outside G2 passed the subgroup check")
+	}
+	// the cofactor multiple is in G2
+	cleared := newTwistPoint(pool).clearCofactor(pt, pool)
+	if !cleared.inSubgroup(pool) {
+		t.Errorf("cofactor multiple failed the subgroup check")
+	}
+	r := newCTTwistPoint(pt)
+	ctTwistMul(r, r, twistCofactor)
+	want := newTwistPoint(pool)
+	r.affine(want)
+	if !bytes.Equal((&G2{cleared}).Marshal(), (&G2{want}).Marshal()) {
+		t.Errorf("clearCofactor differs from multiplication by the cofactor")
+	}
+
+	g := &G2{pt}
+	if _, err := new(G2).Unmarshal(g.Marshal()); err == nil {
+		t.Errorf("Unmarshal accepted a point outside G2")
+	}
+	if _, err := new(G2).UnmarshalCompressed(g.MarshalCompressed()); err == nil {
+		t.Errorf("UnmarshalCompressed accepted a point outside G2")
+	}
+
+	// coordinates must be reduced
+	form := new(G2).ScalarBaseMult(big.NewInt(1)).Marshal()
+	x := new(big.Int).SetBytes(form[:32])
+	x.Add(x, p).FillBytes(form[:32])
+	if _, err := new(G2).Unmarshal(form); err == nil {
+		t.Errorf("Unmarshal accepted an unreduced coordinate")
+	}
+}
+
+func TestGTSubgroup(t *testing.T) {
+	_, g1, _ := RandomG1(rand.Reader)
+	_, g2, _ := RandomG2(rand.Reader)
+	e := Pair(g1, g2)
+	if _, err := new(GT).Unmarshal(e.Marshal()); err != nil {
+		t.Errorf("failed to unmarshal GT: %v", err)
+	}
+
+	// the Miller loop output lies outside GT
+	m := &GT{miller(g2.p, g1.p, new(bnPool))}
+	if _, err := new(GT).Unmarshal(m.Marshal()); err == nil {
+		t.Errorf("Unmarshal accepted an element outside GT")
+	}
+	if _, err := new(GT).Unmarshal(make([]byte, 12*32)); err == nil {
+		t.Errorf("Unmarshal accepted zero")
+	}
+
+	form := e.Marshal()
+	p.FillBytes(form[:32])
+	if _, err := new(GT).Unmarshal(form); err == nil {
+		t.Errorf("Unmarshal accepted an unreduced coordinate")
+	}
+	if _, err := new(GT).Unmarshal(form[1:]); err == nil {
+		t.Errorf("Unmarshal accepted a short input")
+	}
+}
+
 func TestG1Identity(t *testing.T) {
 	g := new(G1).ScalarBaseMult(new(big.Int).SetInt64(0))
 	if !g.p.IsInfinity() {
@@ -297,8 +506,628 @@ func TestTripartiteDiffieHellman(t *testing.T) {
 	}
 }
 
+func TestGFp2Sqrt(t *testing.T) {
+	pool := new(bnPool)
+
+	for i := 0; i < 10; i++ {
+		a := newGFp2(pool)
+		a.x, _ = rand.Int(rand.Reader, p)
+		a.y, _ = rand.Int(rand.Reader, p)
+
+		sq := newGFp2(pool).Square(a, pool)
+		sq.Minimal()
+		root, ok := newGFp2(pool).Sqrt(sq, pool)
+		if !ok {
+			t.Fatalf("failed to find square root")
+		}
+		check := newGFp2(pool).Square(root, pool)
+		check.Minimal()
+		if check.x.Cmp(sq.x) != 0 || check.y.Cmp(sq.y) != 0 {
+			t.Fatalf("bad square root")
+		}
+	}
+
+	// ξ = i+3 is not a square in GF(p²).
+	xi := &gfP2{big.NewInt(1), big.NewInt(3)}
+	if _, ok := newGFp2(pool).Sqrt(xi, pool); ok {
+		t.Errorf("found square root of non-square")
+	}
+}
+
+func TestHashG2(t *testing.T) {
+	h1 := HashG2([]byte("msg"), []byte("dst"))
+	h2 := HashG2([]byte("msg"), []byte("dst"))
+	if !bytes.Equal(h1.Marshal(), h2.Marshal()) {
+		t.Errorf("HashG2 is not deterministic")
+	}
+	if bytes.Equal(h1.Marshal(), HashG2([]byte("msg"), []byte("other")).Marshal()) {
+		t.Errorf("HashG2 ignores the domain-separation tag")
+	}
+	if _, err := new(G2).Unmarshal(h1.Marshal()); err != nil {
+		t.Errorf("HashG2 output is not on the curve")
+	}
+	pool := new(bnPool)
+	if !h1.p.inSubgroup(pool) {
+		t.Errorf("HashG2 output is not in G2")
+	}
+	// the point found before clearing the cofactor is on the twist but
+	// outside G₂, so the output is in G₂ only because the cofactor was cleared
+	dst := shortDST([]byte("dst"))
+	pt := newTwistPoint(pool)
+	rhs := newGFp2(pool)
+	for ctr := uint32(0); ; ctr++ {
+		pt.x.x.Set(hashToBase([]byte("msg"), dst, ctr, 1))
+		pt.x.y.Set(hashToBase([]byte("msg"), dst, ctr, 0))
+		rhs.Square(pt.x, pool)
+		rhs.Mul(rhs, pt.x, pool)
+		rhs.Add(rhs, twistB)
+		rhs.Minimal()
+		if _, ok := pt.y.Sqrt(rhs, pool); ok {
+			break
+		}
+	}
+	pt.z.SetOne()
+	pt.t.SetOne()
+	if pt.inSubgroup(pool) {
+		t.Errorf("uncleared HashG2 candidate is in G2")
+	}
+	cleared := newTwistPoint(pool).clearCofactor(pt, pool)
+	cleared.MakeAffine(pool)
+	if !bytes.Equal((&G2{cleared}).Marshal(), h1.Marshal()) {
+		t.Errorf("HashG2 output is not the cofactor multiple of its candidate")
+	}
+	// long tags are hashed down: otherwise the length byte of a 256-byte tag
+	// is 0 and that of the tag extended by 0 is 1, and both hash tag‖0‖1
+	long := bytes.Repeat([]byte{'d'}, 256)
+	if bytes.Equal(HashG2([]byte{1}, long).Marshal(), HashG2(nil, append(long, 0)).Marshal()) {
+		t.Errorf("HashG2 tags of 256 bytes or more collide")
+	}
+}
+
+func TestExpandMessageXMD(t *testing.T) {
+	// RFC 9380, appendix K.1
+	dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+	vectors := []struct {
+		msg  string
+		n    int
+		want string
+	}{
+		{"", 0x20, "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235"},
+		{"abc", 0x20, "d8ccab23b5985ccea865c6c97b6e5b8350e794e603b4b97902f53a8a0d605615"},
+		{"abcdef0123456789", 0x20, "eff31487c770a893cfb36f912fbfcbff40d5661771ca4b2cb4eafe524333f5c1"},
+		{"", 0x80, "af84c27ccfd45d41914fdff5df25293e221afc53d8ad2ac06d5e3e29485dadbee0d121587713a3e0dd4d5e69e93eb7cd4f5df4cd103e188cf60cb02edc3edf18eda8576c412b18ffb658e3dd6ec849469b979d444cf7b26911a08e63cf31f9dcc541708d3491184472c2c29bb749d4286b004ceb5ee6b9a7fa5b646c993f0ced"},
+	}
+	for _, v := range vectors {
+		if got := hex.EncodeToString(expandMessageXMD([]byte(v.msg), dst, v.n)); got != v.want {
+			t.Errorf("expand_message_xmd(%q, %d) = %s, want %s", v.msg, v.n, got, v.want)
+		}
+	}
+}
+
+func TestHashToG1(t *testing.T) {
+	dst := []byte("BN256G1_XMD:SHA-256_SVDW_RO_TESTGEN")
+	vectors := []struct {
+		msg  string
+		want string
+	}{
+		{"", "2e00d4daf9e069343e63dcf6ca71c58338074b827ad8227af27031e4a5f9327703ee5b42bb8761dcd39aa9c9f3979b7355addee4d8758642e50db359df5e94ac"},
+		{"abc", "8dad296bec2f339008dff337b3d6f15411a84149f9db295dc16d65ce9ae0a36c2c204661412bd63a157ff9de71978eeb5cef5372a1a1b28690bca03885070853"},
+	}
+	for _, v := range vectors {
+		h := HashToG1([]byte(v.msg), dst)
+		if got := hex.EncodeToString(h.Marshal()); got != v.want {
+			t.Errorf("HashToG1(%q) = %s, want %s", v.msg, got, v.want)
+		}
+		if !h.p.IsOnCurve() {
+			t.Errorf("HashToG1(%q) is not on the curve", v.msg)
+		}
+	}
+	if bytes.Equal(HashToG1([]byte("abc"), dst).Marshal(), HashToG1([]byte("abc"), []byte("other")).Marshal()) {
+		t.Errorf("HashToG1 ignores the domain-separation tag")
+	}
+
+	// u = 0 makes the SVDW map fall through to its third candidate
+	if !svdw1.mapToCurve(new(big.Int)).IsOnCurve() {
+		t.Errorf("SVDW map of zero is not on the curve")
+	}
+}
+
+func TestHashToG2(t *testing.T) {
+	dst := []byte("BN256G2_XMD:SHA-256_SVDW_RO_TESTGEN")
+	vectors := []struct {
+		msg  string
+		want string
+	}{
+		{"", "165466e2209495ccce77e1b13c8776a62a455d6a6acbdb29a6c7c11c9055a3b639abf2a7cef0c6fc35110a9d3e2fcd89059999813135d42a12fed1c332aba6c1648d50c386a8c89c5e0a0e33a4447336757328d31ff395de5781895a1f0ee2972be168f83150cd9a6e8251812c780c38ec08ec4809175120aab88822a709c141"},
+		{"abc", "1287bed6816718a94dd780d3c7879e44ba3265f4c7b6b1ac187365fc0718984b856a720412375b5b522c03bc5a8d33571cbc8d8e65e5a0578c61d24b1be3b83d5a751fe38c6ac3bdb53068b5d0dedf56d5231a41edf16d03af901a14239d1396164bebba97d455594139b9a1069f12f7a41906846d03fe943fa669803d97a3ba"},
+	}
+	for _, v := range vectors {
+		h := HashToG2([]byte(v.msg), dst)
+		if got := hex.EncodeToString(h.Marshal()); got != v.want {
+			t.Errorf("HashToG2(%q) = %s, want %s", v.msg, got, v.want)
+		}
+		if _, err := new(G2).Unmarshal(h.Marshal()); err != nil {
+			t.Errorf("HashToG2(%q) is not in G2: %v", v.msg, err)
+		}
+	}
+	if bytes.Equal(HashToG2([]byte("abc"), dst).Marshal(), HashToG2([]byte("abc"), []byte("other")).Marshal()) {
+		t.Errorf("HashToG2 ignores the domain-separation tag")
+	}
+
+	if !svdw2.mapToCurve(newGFp2(nil), nil).IsOnCurve() {
+		t.Errorf("SVDW map of zero is not on the twist")
+	}
+}
+
+func TestMultiPair(t *testing.T) {
+	g1s := make([]*G1, 3)
+	g2s := make([]*G2, 3)
+	want := new(GT)
+	for i := range g1s {
+		_, g1s[i], _ = RandomG1(rand.Reader)
+		_, g2s[i], _ = RandomG2(rand.Reader)
+		if i == 0 {
+			want = Pair(g1s[i], g2s[i])
+		} else {
+			want.Add(want, Pair(g1s[i], g2s[i]))
+		}
+	}
+	if got := MultiPair(g1s, g2s); !bytes.Equal(got.Marshal(), want.Marshal()) {
+		t.Errorf("MultiPair differs from the product of pairings")
+	}
+
+	//pairs with the identity contribute nothing
+	g1s = append(g1s, new(G1).SetInfinity())
+	g2s = append(g2s, g2s[0])
+	if got := MultiPair(g1s, g2s); !bytes.Equal(got.Marshal(), want.Marshal()) {
+		t.Errorf("MultiPair with identity differs from the product of pairings")
+	}
+
+	if MultiPair(g1s, g2s[:1]) != nil || PairingCheck(g1s, g2s[:1]) {
+		t.Errorf("expected failure for mismatched lengths")
+	}
+}
+
+func TestPairingCheck(t *testing.T) {
+	a, p1, _ := RandomG1(rand.Reader)
+	b, p2, _ := RandomG2(rand.Reader)
+
+	// e(aP, bQ).e(-abP, Q) == 1
+	ab := new(big.Int).Mul(a, b)
+	p3 := new(G1).ScalarBaseMult(ab)
+	p3.Neg(p3)
+	g2 := new(G2).ScalarBaseMult(big.NewInt(1))
+	if !PairingCheck([]*G1{p1, p3}, []*G2{p2, g2}) {
+		t.Errorf("PairingCheck rejected a valid product")
+	}
+	if PairingCheck([]*G1{p1, p1}, []*G2{p2, g2}) {
+		t.Errorf("PairingCheck accepted an invalid product")
+	}
+	if !PairingCheck(nil, nil) {
+		t.Errorf("PairingCheck rejected the empty product")
+	}
+}
+
+func TestGFp(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		a, _ := rand.Int(rand.Reader, p)
+		b, _ := rand.Int(rand.Reader, p)
+		if i == 0 {
+			a.Sub(p, big.NewInt(1))
+		}
+		x, y := newGFpFromBig(a), newGFpFromBig(b)
+		if x.toBig().Cmp(a) != 0 {
+			t.Fatalf("bad conversion of %s", a)
+		}
+
+		var c gfP
+		gfpAdd(&c, x, y)
+		if want := new(big.Int).Add(a, b); c.toBig().Cmp(want.Mod(want, p)) != 0 {
+			t.Errorf("bad sum of %s and %s", a, b)
+		}
+		gfpSub(&c, x, y)
+		if want := new(big.Int).Sub(a, b); c.toBig().Cmp(want.Mod(want, p)) != 0 {
+			t.Errorf("bad difference of %s and %s", a, b)
+		}
+		gfpMul(&c, x, y)
+		if want := new(big.Int).Mul(a, b); c.toBig().Cmp(want.Mod(want, p)) != 0 {
+			t.Errorf("bad product of %s and %s", a, b)
+		}
+		gfpInvert(&c, x)
+		if want := new(big.Int).ModInverse(a, p); c.toBig().Cmp(want) != 0 {
+			t.Errorf("bad inverse of %s", a)
+		}
+	}
+	var c gfP
+	gfpNeg(&c, &gfP{})
+	if gfpIsZero(&c) != 1 || gfpIsZero(newGFpFromInt64(-1)) != 0 {
+		t.Errorf("-0 != 0")
+	}
+}
+
+func TestScalarMultLadder(t *testing.T) {
+	pool := new(bnPool)
+	_, g1, _ := RandomG1(rand.Reader)
+	_, g2, _ := RandomG2(rand.Reader)
+
+	// k·P by repeated addition
+	sum1 := newCurvePoint(pool)
+	sum1.SetInfinity()
+	sum2 := newTwistPoint(pool)
+	sum2.SetInfinity()
+	for k := int64(0); k < 8; k++ {
+		c1 := newCurvePoint(pool).Mul(g1.p, big.NewInt(k), pool)
+		c1.MakeAffine(pool)
+		s1 := newCurvePoint(pool)
+		s1.Set(sum1)
+		s1.MakeAffine(pool)
+		if c1.IsInfinity() != s1.IsInfinity() || !s1.IsInfinity() && (c1.x.Cmp(s1.x) != 0 || c1.y.Cmp(s1.y) != 0) {
+			t.Errorf("%d·P differs from repeated addition in G1", k)
+		}
+		c2 := newTwistPoint(pool).Mul(g2.p, big.NewInt(k), pool)
+		c2.MakeAffine(pool)
+		s2 := newTwistPoint(pool)
+		s2.Set(sum2)
+		s2.MakeAffine(pool)
+		if c2.IsInfinity() != s2.IsInfinity() || !s2.IsInfinity() && (c2.x.String() != s2.x.String() || c2.y.String() != s2.y.String()) {
+			t.Errorf("%d·P differs from repeated addition in G2", k)
+		}
+		if k == 1 {
+			// the Jacobian addition does not handle P+P
+			sum1.Double(g1.p, pool)
+			sum2.Double(g2.p, pool)
+		} else {
+			sum1.Add(sum1, g1.p, pool)
+			sum2.Add(sum2, g2.p, pool)
+		}
+	}
+
+	// a·P + b·P == (a+b)·P, also for scalars longer than 256 bits
+	a, _ := rand.Int(rand.Reader, Order)
+	b := new(big.Int).Lsh(Order, 10)
+	b.Add(b, big.NewInt(5))
+	ab := new(big.Int).Add(a, b)
+	lhs := new(G2).ScalarMult(g2, a)
+	lhs.Add(lhs, new(G2).ScalarMult(g2, b))
+	if !bytes.Equal(lhs.Marshal(), new(G2).ScalarMult(g2, ab).Marshal()) {
+		t.Errorf("a·P + b·P != (a+b)·P in G2")
+	}
+	lhs1 := new(G1).ScalarMult(g1, a)
+	lhs1.Add(lhs1, new(G1).ScalarMult(g1, b))
+	if !bytes.Equal(lhs1.Marshal(), new(G1).ScalarMult(g1, ab).Marshal()) {
+		t.Errorf("a·P + b·P != (a+b)·P in G1")
+	}
+
+	// -a·P == -(a·P)
+	neg := new(G1).ScalarMult(g1, new(big.Int).Neg(a))
+	if !bytes.Equal(neg.Marshal(), new(G1).Neg(new(G1).ScalarMult(g1, a)).Marshal()) {
+		t.Errorf("(-a)·P != -(a·P) in G1")
+	}
+}
+
+func TestFixedBase(t *testing.T) {
+	_, g1, _ := RandomG1(rand.Reader)
+	_, g2, _ := RandomG2(rand.Reader)
+	t1, t2 := NewFixedBaseG1(g1), NewFixedBaseG2(g2)
+	r, _ := rand.Int(rand.Reader, Order)
+	for _, k := range []*big.Int{
+		r,
+		new(big.Int).Neg(r),
+		new(big.Int).Add(r, Order),
+		big.NewInt(0),
+		big.NewInt(1),
+		new(big.Int).Sub(Order, big.NewInt(1)),
+		new(big.Int).Lsh(big.NewInt(1), 300),
+	} {
+		if !bytes.Equal(t1.ScalarMult(k).Marshal(), new(G1).ScalarMult(g1, k).Marshal()) {
+			t.Errorf("FixedBaseG1 differs from ScalarMult for k = %v", k)
+		}
+		if !bytes.Equal(t2.ScalarMult(k).Marshal(), new(G2).ScalarMult(g2, k).Marshal()) {
+			t.Errorf("FixedBaseG2 differs from ScalarMult for k = %v", k)
+		}
+		if !bytes.Equal(new(G1).ScalarBaseMult(k).Marshal(), new(G1).ScalarMult(&G1{curveGen}, k).Marshal()) {
+			t.Errorf("G1.ScalarBaseMult differs from ScalarMult for k = %v", k)
+		}
+		if !bytes.Equal(new(G2).ScalarBaseMult(k).Marshal(), new(G2).ScalarMult(&G2{twistGen}, k).Marshal()) {
+			t.Errorf("G2.ScalarBaseMult differs from ScalarMult for k = %v", k)
+		}
+	}
+	if !NewFixedBaseG1(new(G1).SetInfinity()).ScalarMult(r).IsZero() {
+		t.Errorf("k·∞ != ∞ in G1")
+	}
+	if !NewFixedBaseG2(new(G2).SetInfinity()).ScalarMult(r).IsZero() {
+		t.Errorf("k·∞ != ∞ in G2")
+	}
+}
+
+func TestGLV(t *testing.T) {
+	_, g1, _ := RandomG1(rand.Reader)
+	_, g2, _ := RandomG2(rand.Reader)
+	lambda := uPoly(1, 6, 18, 36)
+	r, _ := rand.Int(rand.Reader, Order)
+	for _, k := range []*big.Int{
+		r,
+		new(big.Int).Neg(r),
+		big.NewInt(0),
+		big.NewInt(1),
+		new(big.Int).Sub(Order, big.NewInt(1)),
+		Order,
+		lambda,
+		sixuSquared,
+		new(big.Int).Lsh(big.NewInt(1), 300),
+	} {
+		k1 := new(big.Int).Mod(k, Order)
+		want1 := newCTCurvePoint(g1.p)
+		ctCurveMul(want1, want1, k1)
+		got1 := newCTCurvePoint(g1.p)
+		ctCurveMulGLV(got1, got1, k)
+		w1, c1 := newCurvePoint(nil), newCurvePoint(nil)
+		want1.affine(w1)
+		got1.affine(c1)
+		if !bytes.Equal((&G1{c1}).Marshal(), (&G1{w1}).Marshal()) {
+			t.Errorf("GLV differs from the ladder for k = %v", k)
+		}
+
+		want2 := newCTTwistPoint(g2.p)
+		ctTwistMul(want2, want2, k1)
+		got2 := newCTTwistPoint(g2.p)
+		ctTwistMulGLS(got2, got2, k)
+		w2, c2 := newTwistPoint(nil), newTwistPoint(nil)
+		want2.affine(w2)
+		got2.affine(c2)
+		if !bytes.Equal((&G2{c2}).Marshal(), (&G2{w2}).Marshal()) {
+			t.Errorf("GLS differs from the ladder for k = %v", k)
+		}
+	}
+
+	// the decompositions are congruent to k and within the bounds
+	for i := 0; i < 1000; i++ {
+		k, _ := rand.Int(rand.Reader, Order)
+		for _, l := range []struct {
+			lattice *lattice
+			lambda  *big.Int
+			bits    int
+		}{
+			{glvLattice, lambda, glvBits},
+			{glsLattice, sixuSquared, glsBits},
+		} {
+			ks := l.lattice.decompose(k)
+			sum, pow := new(big.Int), big.NewInt(1)
+			for _, ki := range ks {
+				if ki.BitLen() > l.bits {
+					t.Fatalf("decomposition of %v has a %d-bit part", k, ki.BitLen())
+				}
+				sum.Add(sum, new(big.Int).Mul(ki, pow))
+				pow.Mul(pow, l.lambda)
+			}
+			if sum.Sub(sum, k).Mod(sum, Order).Sign() != 0 {
+				t.Fatalf("decomposition of %v is not congruent to it", k)
+			}
+		}
+	}
+}
+
+func BenchmarkScalarMultLadderG1(b *testing.B) {
+	k, _ := rand.Int(rand.Reader, Order)
+	a := newCTCurvePoint(curveGen)
+	for i := 0; i < b.N; i++ {
+		c := newCTCurvePoint(curveGen)
+		ctCurveMul(c, a, k)
+		c.affine(newCurvePoint(nil))
+	}
+}
+
+func BenchmarkScalarMultLadderG2(b *testing.B) {
+	k, _ := rand.Int(rand.Reader, Order)
+	a := newCTTwistPoint(twistGen)
+	for i := 0; i < b.N; i++ {
+		c := newCTTwistPoint(twistGen)
+		ctTwistMul(c, a, k)
+		c.affine(newTwistPoint(nil))
+	}
+}
+
+func BenchmarkHashToG2(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		HashToG2([]byte("message"), []byte("BN256G2_XMD:SHA-256_SVDW_RO_BENCH"))
+	}
+}
+
+func BenchmarkUnmarshalG2(b *testing.B) {
+	_, g, _ := RandomG2(rand.Reader)
+	m := g.Marshal()
+	for i := 0; i < b.N; i++ {
+		new(G2).Unmarshal(m)
+	}
+}
+
+func BenchmarkScalarMultG1(b *testing.B) {
+	k, _ := rand.Int(rand.Reader, Order)
+	g := &G1{curveGen}
+	for i := 0; i < b.N; i++ {
+		new(G1).ScalarMult(g, k)
+	}
+}
+
+func BenchmarkScalarMultG2(b *testing.B) {
+	k, _ := rand.Int(rand.Reader, Order)
+	g := &G2{twistGen}
+	for i := 0; i < b.N; i++ {
+		new(G2).ScalarMult(g, k)
+	}
+}
+
+func BenchmarkScalarBaseMultG1(b *testing.B) {
+	k, _ := rand.Int(rand.Reader, Order)
+	new(G1).ScalarBaseMult(k)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		new(G1).ScalarBaseMult(k)
+	}
+}
+
+func BenchmarkScalarBaseMultG2(b *testing.B) {
+	k, _ := rand.Int(rand.Reader, Order)
+	new(G2).ScalarBaseMult(k)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		new(G2).ScalarBaseMult(k)
+	}
+}
+
+/*
+BenchmarkFixedBaseG2 compares the comb table of a point of G2 against ScalarMult
+with the GLS decomposition and against the plain ladder that ScalarMult used
+before either existed, which ccs08 benchmarks cannot reach.
+*/
+func BenchmarkFixedBaseG2(b *testing.B) {
+	k0, _ := rand.Int(rand.Reader, Order)
+	P := new(G2).ScalarBaseMult(k0)
+	k, _ := rand.Int(rand.Reader, Order)
+	table := NewFixedBaseG2(P)
+	b.Run("Comb", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			table.ScalarMult(k)
+		}
+	})
+	b.Run("ScalarMult", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			new(G2).ScalarMult(P, k)
+		}
+	})
+	b.Run("Ladder", func(b *testing.B) {
+		a := newCTTwistPoint(P.p)
+		for i := 0; i < b.N; i++ {
+			var c ctTwistPoint
+			ctTwistMul(&c, a, k)
+			c.affine(newTwistPoint(nil))
+		}
+	})
+}
+
+func BenchmarkNewFixedBaseG2(b *testing.B) {
+	g := &G2{twistGen}
+	for i := 0; i < b.N; i++ {
+		NewFixedBaseG2(g)
+	}
+}
+
+func BenchmarkMultiPair(b *testing.B) {
+	g1s := []*G1{&G1{curveGen}, &G1{curveGen}, &G1{curveGen}, &G1{curveGen}}
+	g2s := []*G2{&G2{twistGen}, &G2{twistGen}, &G2{twistGen}, &G2{twistGen}}
+	for i := 0; i < b.N; i++ {
+		MultiPair(g1s, g2s)
+	}
+}
+
 func BenchmarkPairing(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		Pair(&G1{curveGen}, &G2{twistGen})
 	}
 }
+
+func TestMultiScalarMult(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 5, 40} {
+		g1s := make([]*G1, n)
+		g2s := make([]*G2, n)
+		scalars := make([]*big.Int, n)
+		want1 := new(G1).SetInfinity()
+		want2 := new(G2).SetInfinity()
+		for i := range scalars {
+			_, g1s[i], _ = RandomG1(rand.Reader)
+			_, g2s[i], _ = RandomG2(rand.Reader)
+			scalars[i], _ = rand.Int(rand.Reader, Order)
+			switch i {
+			case 1:
+				scalars[i].Neg(scalars[i])
+			case 2:
+				g1s[i].SetInfinity()
+				g2s[i].SetInfinity()
+			case 3:
+				scalars[i].SetInt64(0)
+			}
+			want1.Add(want1, new(G1).ScalarMult(g1s[i], scalars[i]))
+			want2.Add(want2, new(G2).ScalarMult(g2s[i], scalars[i]))
+		}
+		if got := MultiScalarMultG1(g1s, scalars); !bytes.Equal(got.Marshal(), want1.Marshal()) {
+			t.Errorf("MultiScalarMultG1 differs from the sum of ScalarMult for %d points", n)
+		}
+		if got := MultiScalarMultG2(g2s, scalars); !bytes.Equal(got.Marshal(), want2.Marshal()) {
+			t.Errorf("MultiScalarMultG2 differs from the sum of ScalarMult for %d points", n)
+		}
+	}
+	if MultiScalarMultG1([]*G1{new(G1).SetInfinity()}, nil) != nil {
+		t.Errorf("MultiScalarMultG1 accepted mismatched lengths")
+	}
+}
+
+func TestMultiScalarMultGT(t *testing.T) {
+	_, g1, _ := RandomG1(rand.Reader)
+	_, g2, _ := RandomG2(rand.Reader)
+	base := Pair(g1, g2)
+	for _, n := range []int{0, 1, 3, 40} {
+		elems := make([]*GT, n)
+		scalars := make([]*big.Int, n)
+		want := new(GT).ScalarMult(base, big.NewInt(0))
+		for i := range scalars {
+			k, _ := rand.Int(rand.Reader, Order)
+			elems[i] = new(GT).ScalarMult(base, k)
+			scalars[i], _ = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+			if i == 1 {
+				scalars[i].Neg(scalars[i])
+			}
+			want.Add(want, new(GT).ScalarMult(elems[i], new(big.Int).Mod(scalars[i], Order)))
+		}
+		if got := MultiScalarMultGT(elems, scalars); !bytes.Equal(got.Marshal(), want.Marshal()) {
+			t.Errorf("MultiScalarMultGT differs from the product of ScalarMult for %d elements", n)
+		}
+	}
+	if MultiScalarMultGT([]*GT{base}, nil) != nil {
+		t.Errorf("MultiScalarMultGT accepted mismatched lengths")
+	}
+}
+
+func benchmarkMultiScalarMultG1(b *testing.B, n int) {
+	points := make([]*G1, n)
+	scalars := make([]*big.Int, n)
+	for i := range points {
+		_, points[i], _ = RandomG1(rand.Reader)
+		scalars[i], _ = rand.Int(rand.Reader, Order)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MultiScalarMultG1(points, scalars)
+	}
+}
+
+func BenchmarkMultiScalarMultG1_8(b *testing.B)   { benchmarkMultiScalarMultG1(b, 8) }
+func BenchmarkMultiScalarMultG1_64(b *testing.B)  { benchmarkMultiScalarMultG1(b, 64) }
+func BenchmarkMultiScalarMultG1_512(b *testing.B) { benchmarkMultiScalarMultG1(b, 512) }
+
+func BenchmarkMultiScalarMultGT_64(b *testing.B) {
+	_, g1, _ := RandomG1(rand.Reader)
+	_, g2, _ := RandomG2(rand.Reader)
+	elems := make([]*GT, 64)
+	scalars := make([]*big.Int, len(elems))
+	for i := range elems {
+		elems[i] = Pair(g1, g2)
+		scalars[i], _ = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MultiScalarMultGT(elems, scalars)
+	}
+}
+
+func BenchmarkMultiScalarMultG2_64(b *testing.B) {
+	points := make([]*G2, 64)
+	scalars := make([]*big.Int, len(points))
+	for i := range points {
+		_, points[i], _ = RandomG2(rand.Reader)
+		scalars[i], _ = rand.Int(rand.Reader, Order)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MultiScalarMultG2(points, scalars)
+	}
+}
@@ -0,0 +1,194 @@
+package bn256
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Compressed points consist of a flag byte followed by the x coordinate: 32
+// bytes for G₁ and x.x ‖ x.y, 64 bytes, for G₂. Since p > 2²⁵⁵ there is no
+// spare bit in x to carry the sign of y, hence the extra byte. The flag is
+//
+//	0x00  the point at infinity; all following bytes must be zero
+//	0x02  y has sign 0
+//	0x03  y has sign 1
+//
+// where the sign of y ∈ GF(p) is its least significant bit and the sign of
+// y = y.x·i+y.y ∈ GF(p²) is that of y.y, or of y.x if y.y is zero.
+const (
+	compressedInfinity byte = 0x00
+	compressedEven     byte = 0x02
+	compressedOdd      byte = 0x03
+
+	// CompressedG1Size is the length of G1.MarshalCompressed.
+	CompressedG1Size = 1 + 32
+	// CompressedG2Size is the length of G2.MarshalCompressed.
+	CompressedG2Size = 1 + 64
+)
+
+// pPlus1Over4 is (p+1)/4; since p ≡ 3 mod 4, a^((p+1)/4) is a square root of
+// a whenever a is a square.
+var pPlus1Over4 = new(big.Int).Rsh(new(big.Int).Add(p, big.NewInt(1)), 2)
+
+// sqrtGFp returns a square root of a in GF(p), if there is one.
+func sqrtGFp(a *big.Int) (*big.Int, bool) {
+	y := new(big.Int).Exp(a, pPlus1Over4, p)
+	yy := new(big.Int).Mul(y, y)
+	if yy.Mod(yy, p).Cmp(new(big.Int).Mod(a, p)) != 0 {
+		return nil, false
+	}
+	return y, true
+}
+
+// sgn0 returns the sign of e, which must be minimal.
+func (e *gfP2) sgn0() uint {
+	if e.y.Sign() != 0 {
+		return e.y.Bit(0)
+	}
+	return e.x.Bit(0)
+}
+
+// MarshalCompressed converts e to its 33-byte compressed form.
+func (e *G1) MarshalCompressed() []byte {
+	ret := make([]byte, CompressedG1Size)
+	if e.p.IsInfinity() {
+		return ret
+	}
+	e.p.MakeAffine(nil)
+	x := new(big.Int).Mod(e.p.x, p)
+	y := new(big.Int).Mod(e.p.y, p)
+	ret[0] = compressedEven | byte(y.Bit(0))
+	x.FillBytes(ret[1:])
+	return ret
+}
+
+// UnmarshalCompressed sets e to the point encoded by MarshalCompressed and
+// returns e. It rejects every encoding that MarshalCompressed cannot produce.
+func (e *G1) UnmarshalCompressed(m []byte) (*G1, error) {
+	if len(m) != CompressedG1Size {
+		return nil, errors.New("bn256: invalid compressed G1 length")
+	}
+	if e.p == nil {
+		e.p = newCurvePoint(nil)
+	}
+	if m[0] == compressedInfinity {
+		if !allZero(m[1:]) {
+			return nil, errors.New("bn256: non-canonical encoding of infinity")
+		}
+		e.p.SetInfinity()
+		return e, nil
+	}
+	if m[0] != compressedEven && m[0] != compressedOdd {
+		return nil, errors.New("bn256: invalid compressed point flag")
+	}
+	x := new(big.Int).SetBytes(m[1:])
+	if x.Cmp(p) >= 0 {
+		return nil, errors.New("bn256: coordinate not reduced modulo p")
+	}
+
+	// y² = x³+3
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, curveB)
+	y, ok := sqrtGFp(rhs)
+	if !ok {
+		return nil, errors.New("bn256: point not on curve")
+	}
+	if y.Bit(0) != uint(m[0]&1) {
+		if y.Sign() == 0 {
+			return nil, errors.New("bn256: non-canonical sign of y")
+		}
+		y.Sub(p, y)
+	}
+
+	e.p.x.Set(x)
+	e.p.y.Set(y)
+	e.p.z.SetInt64(1)
+	e.p.t.SetInt64(1)
+	return e, nil
+}
+
+// MarshalCompressed converts e to its 65-byte compressed form.
+func (e *G2) MarshalCompressed() []byte {
+	const numBytes = 256 / 8
+
+	ret := make([]byte, CompressedG2Size)
+	if e.p.IsInfinity() {
+		return ret
+	}
+	e.p.MakeAffine(nil)
+	e.p.x.Minimal()
+	e.p.y.Minimal()
+	ret[0] = compressedEven | byte(e.p.y.sgn0())
+	e.p.x.x.FillBytes(ret[1 : 1+numBytes])
+	e.p.x.y.FillBytes(ret[1+numBytes:])
+	return ret
+}
+
+// UnmarshalCompressed sets e to the point encoded by MarshalCompressed and
+// returns e. It rejects every encoding that MarshalCompressed cannot produce,
+// including points of the twist outside G₂.
+func (e *G2) UnmarshalCompressed(m []byte) (*G2, error) {
+	const numBytes = 256 / 8
+
+	if len(m) != CompressedG2Size {
+		return nil, errors.New("bn256: invalid compressed G2 length")
+	}
+	if e.p == nil {
+		e.p = newTwistPoint(nil)
+	}
+	if m[0] == compressedInfinity {
+		if !allZero(m[1:]) {
+			return nil, errors.New("bn256: non-canonical encoding of infinity")
+		}
+		e.p.SetInfinity()
+		return e, nil
+	}
+	if m[0] != compressedEven && m[0] != compressedOdd {
+		return nil, errors.New("bn256: invalid compressed point flag")
+	}
+	pool := new(bnPool)
+	x := &gfP2{
+		new(big.Int).SetBytes(m[1 : 1+numBytes]),
+		new(big.Int).SetBytes(m[1+numBytes:]),
+	}
+	if x.x.Cmp(p) >= 0 || x.y.Cmp(p) >= 0 {
+		return nil, errors.New("bn256: coordinate not reduced modulo p")
+	}
+
+	// y² = x³+3/ξ
+	rhs := newGFp2(pool).Square(x, pool)
+	rhs.Mul(rhs, x, pool)
+	rhs.Add(rhs, twistB)
+	rhs.Minimal()
+	y := newGFp2(nil)
+	if _, ok := y.Sqrt(rhs, pool); !ok {
+		return nil, errors.New("bn256: point not on curve")
+	}
+	y.Minimal()
+	if y.sgn0() != uint(m[0]&1) {
+		if y.IsZero() {
+			return nil, errors.New("bn256: non-canonical sign of y")
+		}
+		y.Negative(y)
+		y.Minimal()
+	}
+
+	e.p.x.Set(x)
+	e.p.y.Set(y)
+	e.p.z.SetOne()
+	e.p.t.SetOne()
+	if !e.p.inSubgroup(pool) {
+		return nil, errors.New("bn256: point not in G2")
+	}
+	return e, nil
+}
+
+func allZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
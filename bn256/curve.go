@@ -225,23 +225,12 @@ func (c *curvePoint) Double(a *curvePoint, pool *bnPool) {
 	pool.Put(f)
 }
 
+// Mul sets c to scalar·a using the GLV endomorphism. The point arithmetic
+// takes time independent of a and scalar.
 func (c *curvePoint) Mul(a *curvePoint, scalar *big.Int, pool *bnPool) *curvePoint {
-	sum := newCurvePoint(pool)
-	sum.SetInfinity()
-	t := newCurvePoint(pool)
-
-	for i := scalar.BitLen(); i >= 0; i-- {
-		t.Double(sum, pool)
-		if scalar.Bit(i) != 0 {
-			sum.Add(t, a, pool)
-		} else {
-			sum.Set(t)
-		}
-	}
-
-	c.Set(sum)
-	sum.Put(pool)
-	t.Put(pool)
+	r := newCTCurvePoint(a)
+	ctCurveMulGLV(r, r, scalar)
+	r.affine(c)
 	return c
 }
 
@@ -0,0 +1,137 @@
+package bn256
+
+import (
+	"math/big"
+	"sync"
+)
+
+// Multiplication of a fixed point uses the comb method of Lim and Lee with
+// combTeeth teeth spaced combSpacing bits apart. For a base P the table holds
+// T[b] = sum(2^(j·combSpacing)·P) over the bits j set in b, for all
+// b < 2^combTeeth. The scalar k is then read as combSpacing columns of
+// combTeeth bits,
+//
+//	k·P = sum over i of 2^i·T[bit(k, i) | bit(k, i+combSpacing)<<1 | ...],
+//
+// which takes combSpacing doublings and additions instead of the 256 of each
+// of a ladder. Each entry is read by scanning the whole table with conditional
+// moves, so like ScalarMult the time taken does not depend on the scalar.
+
+const (
+	combTeeth   = 6
+	combSpacing = (ladderBits + combTeeth - 1) / combTeeth
+	combSize    = 1 << combTeeth
+)
+
+// FixedBaseG1 is a precomputed table for multiplying a fixed point of G₁, such
+// as a commitment generator or a public key, by many scalars.
+type FixedBaseG1 struct {
+	table [combSize]ctCurvePoint
+}
+
+// FixedBaseG2 is a precomputed table for multiplying a fixed point of G₂, such
+// as a commitment generator or a public key, by many scalars.
+type FixedBaseG2 struct {
+	table [combSize]ctTwistPoint
+}
+
+var (
+	curveGenOnce, twistGenOnce sync.Once
+	curveGenTable              *FixedBaseG1
+	twistGenTable              *FixedBaseG2
+)
+
+// curveGenBase returns the table of the generator of G₁, building it on first
+// use.
+func curveGenBase() *FixedBaseG1 {
+	curveGenOnce.Do(func() {
+		curveGenTable = NewFixedBaseG1(&G1{curveGen})
+	})
+	return curveGenTable
+}
+
+// twistGenBase returns the table of the generator of G₂, building it on first
+// use.
+func twistGenBase() *FixedBaseG2 {
+	twistGenOnce.Do(func() {
+		twistGenTable = NewFixedBaseG2(&G2{twistGen})
+	})
+	return twistGenTable
+}
+
+// combIndex returns the table index of column i of k.
+func combIndex(k *big.Int, i int) uint64 {
+	var b uint64
+	for j := 0; j < combTeeth; j++ {
+		b |= uint64(k.Bit(j*combSpacing+i)) << uint(j)
+	}
+	return b
+}
+
+// NewFixedBaseG1 returns the table for multiplying P.
+func NewFixedBaseG1(P *G1) *FixedBaseG1 {
+	t := new(FixedBaseG1)
+	t.table[0] = ctCurvePoint{y: *newGFpFromInt64(1)}
+	base := *newCTCurvePoint(P.p)
+	for j := 0; j < combTeeth; j++ {
+		if j > 0 {
+			for i := 0; i < combSpacing; i++ {
+				ctCurveAdd(&base, &base, &base)
+			}
+		}
+		for b := 1 << uint(j); b < 1<<uint(j+1); b++ {
+			ctCurveAdd(&t.table[b], &t.table[b-1<<uint(j)], &base)
+		}
+	}
+	return t
+}
+
+// ScalarMult returns k·P for the point P of the table. k is reduced modulo
+// Order and may be negative.
+func (t *FixedBaseG1) ScalarMult(k *big.Int) *G1 {
+	k = new(big.Int).Mod(k, Order)
+	r := ctCurvePoint{y: *newGFpFromInt64(1)}
+	var e ctCurvePoint
+	for i := combSpacing - 1; i >= 0; i-- {
+		ctCurveAdd(&r, &r, &r)
+		ctCurveSelect(&e, t.table[:], combIndex(k, i))
+		ctCurveAdd(&r, &r, &e)
+	}
+	out := newCurvePoint(nil)
+	r.affine(out)
+	return &G1{out}
+}
+
+// NewFixedBaseG2 returns the table for multiplying P.
+func NewFixedBaseG2(P *G2) *FixedBaseG2 {
+	t := new(FixedBaseG2)
+	t.table[0] = ctTwistPoint{y: ctGFp2{y: *newGFpFromInt64(1)}}
+	base := *newCTTwistPoint(P.p)
+	for j := 0; j < combTeeth; j++ {
+		if j > 0 {
+			for i := 0; i < combSpacing; i++ {
+				ctTwistAdd(&base, &base, &base)
+			}
+		}
+		for b := 1 << uint(j); b < 1<<uint(j+1); b++ {
+			ctTwistAdd(&t.table[b], &t.table[b-1<<uint(j)], &base)
+		}
+	}
+	return t
+}
+
+// ScalarMult returns k·P for the point P of the table. k is reduced modulo
+// Order and may be negative.
+func (t *FixedBaseG2) ScalarMult(k *big.Int) *G2 {
+	k = new(big.Int).Mod(k, Order)
+	r := ctTwistPoint{y: ctGFp2{y: *newGFpFromInt64(1)}}
+	var e ctTwistPoint
+	for i := combSpacing - 1; i >= 0; i-- {
+		ctTwistAdd(&r, &r, &r)
+		ctTwistSelect(&e, t.table[:], combIndex(k, i))
+		ctTwistAdd(&r, &r, &e)
+	}
+	out := newTwistPoint(nil)
+	r.affine(out)
+	return &G2{out}
+}
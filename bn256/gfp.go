@@ -0,0 +1,202 @@
+package bn256
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// gfP is an element of GF(p) in Montgomery form, a·R mod p with R = 2²⁵⁶,
+// stored as four little-endian 64-bit limbs. Unlike the math/big arithmetic
+// used elsewhere in this package, its operations take time independent of
+// their inputs.
+type gfP [4]uint64
+
+var (
+	// pLimbs is p as four little-endian limbs.
+	pLimbs = limbsFromBig(p)
+	// np is -p⁻¹ mod 2⁶⁴.
+	np = montgomeryNP()
+	// r2 is R² mod p, used to convert into Montgomery form.
+	r2 = gfP(limbsFromBig(new(big.Int).Mod(new(big.Int).Lsh(big.NewInt(1), 512), p)))
+	// pMinus2 is the exponent used for inversion by Fermat's little theorem.
+	pMinus2 = new(big.Int).Sub(p, big.NewInt(2))
+)
+
+func limbsFromBig(a *big.Int) (ret [4]uint64) {
+	var buf [32]byte
+	a.FillBytes(buf[:])
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			ret[i] |= uint64(buf[31-8*i-j]) << (8 * uint(j))
+		}
+	}
+	return ret
+}
+
+func montgomeryNP() uint64 {
+	mod := new(big.Int).Lsh(big.NewInt(1), 64)
+	inv := new(big.Int).ModInverse(new(big.Int).Mod(p, mod), mod)
+	return -inv.Uint64()
+}
+
+// newGFpFromBig returns a mod p in Montgomery form. a may be negative or
+// larger than p.
+func newGFpFromBig(a *big.Int) *gfP {
+	e := gfP(limbsFromBig(new(big.Int).Mod(a, p)))
+	gfpMul(&e, &e, &r2)
+	return &e
+}
+
+// newGFpFromInt64 returns a mod p in Montgomery form.
+func newGFpFromInt64(a int64) *gfP {
+	return newGFpFromBig(big.NewInt(a))
+}
+
+// toBig returns e as an integer in [0, p).
+func (e *gfP) toBig() *big.Int {
+	one := gfP{1}
+	var t gfP
+	gfpMul(&t, e, &one)
+	var buf [32]byte
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			buf[31-8*i-j] = byte(t[i] >> (8 * uint(j)))
+		}
+	}
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// gfpCmov sets c to a if cond is 1 and leaves it unchanged if cond is 0.
+func gfpCmov(c, a *gfP, cond uint64) {
+	mask := -cond
+	for i := range c {
+		c[i] ^= (c[i] ^ a[i]) & mask
+	}
+}
+
+// gfpCswap swaps a and b if cond is 1 and leaves them unchanged if cond is 0.
+func gfpCswap(a, b *gfP, cond uint64) {
+	mask := -cond
+	for i := range a {
+		t := (a[i] ^ b[i]) & mask
+		a[i] ^= t
+		b[i] ^= t
+	}
+}
+
+// gfpIsZero returns 1 if e is zero and 0 otherwise.
+func gfpIsZero(e *gfP) uint64 {
+	t := e[0] | e[1] | e[2] | e[3]
+	return 1 ^ ((t | -t) >> 63)
+}
+
+// gfpReduce sets c to t - p if the five-limb value t is at least p, and to t
+// otherwise.
+func gfpReduce(c *gfP, t *[5]uint64) {
+	var r gfP
+	var b uint64
+	r[0], b = bits.Sub64(t[0], pLimbs[0], 0)
+	r[1], b = bits.Sub64(t[1], pLimbs[1], b)
+	r[2], b = bits.Sub64(t[2], pLimbs[2], b)
+	r[3], b = bits.Sub64(t[3], pLimbs[3], b)
+	_, b = bits.Sub64(t[4], 0, b)
+	// b is 1 iff t < p
+	mask := -b
+	for i := range c {
+		c[i] = (t[i] & mask) | (r[i] &^ mask)
+	}
+}
+
+func gfpAdd(c, a, b *gfP) {
+	var t [5]uint64
+	var carry uint64
+	t[0], carry = bits.Add64(a[0], b[0], 0)
+	t[1], carry = bits.Add64(a[1], b[1], carry)
+	t[2], carry = bits.Add64(a[2], b[2], carry)
+	t[3], carry = bits.Add64(a[3], b[3], carry)
+	t[4] = carry
+	gfpReduce(c, &t)
+}
+
+func gfpSub(c, a, b *gfP) {
+	var t gfP
+	var borrow uint64
+	t[0], borrow = bits.Sub64(a[0], b[0], 0)
+	t[1], borrow = bits.Sub64(a[1], b[1], borrow)
+	t[2], borrow = bits.Sub64(a[2], b[2], borrow)
+	t[3], borrow = bits.Sub64(a[3], b[3], borrow)
+	// add p back if the subtraction wrapped around
+	mask := -borrow
+	var carry uint64
+	c[0], carry = bits.Add64(t[0], pLimbs[0]&mask, 0)
+	c[1], carry = bits.Add64(t[1], pLimbs[1]&mask, carry)
+	c[2], carry = bits.Add64(t[2], pLimbs[2]&mask, carry)
+	c[3], _ = bits.Add64(t[3], pLimbs[3]&mask, carry)
+}
+
+func gfpNeg(c, a *gfP) {
+	gfpSub(c, &gfP{}, a)
+}
+
+// gfpMul sets c to a·b·R⁻¹ mod p using coarsely integrated operand scanning
+// Montgomery multiplication.
+func gfpMul(c, a, b *gfP) {
+	var t [6]uint64
+	for i := 0; i < 4; i++ {
+		// t += a·b[i]
+		var C uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[j], b[i])
+			var c1, c2 uint64
+			lo, c1 = bits.Add64(lo, t[j], 0)
+			lo, c2 = bits.Add64(lo, C, 0)
+			t[j] = lo
+			C = hi + c1 + c2
+		}
+		var c3 uint64
+		t[4], c3 = bits.Add64(t[4], C, 0)
+		t[5] = c3
+
+		// t = (t + m·p) / 2⁶⁴ where m makes the low limb vanish
+		m := t[0] * np
+		hi, lo := bits.Mul64(m, pLimbs[0])
+		_, c1 := bits.Add64(lo, t[0], 0)
+		C = hi + c1
+		for j := 1; j < 4; j++ {
+			hi, lo := bits.Mul64(m, pLimbs[j])
+			var c1, c2 uint64
+			lo, c1 = bits.Add64(lo, t[j], 0)
+			lo, c2 = bits.Add64(lo, C, 0)
+			t[j-1] = lo
+			C = hi + c1 + c2
+		}
+		t[3], c1 = bits.Add64(t[4], C, 0)
+		t[4] = t[5] + c1
+	}
+	var r [5]uint64
+	copy(r[:], t[:5])
+	gfpReduce(c, &r)
+}
+
+func gfpSquare(c, a *gfP) {
+	gfpMul(c, a, a)
+}
+
+// gfpExp sets c to a^k. The running time depends on k, which must be public,
+// but not on a.
+func gfpExp(c, a *gfP, k *big.Int) {
+	ret := *newGFpFromInt64(1)
+	base := *a
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		gfpSquare(&ret, &ret)
+		if k.Bit(i) != 0 {
+			gfpMul(&ret, &ret, &base)
+		}
+	}
+	*c = ret
+}
+
+// gfpInvert sets c to a⁻¹, or to zero if a is zero.
+func gfpInvert(c, a *gfP) {
+	gfpExp(c, a, pMinus2)
+}
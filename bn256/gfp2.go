@@ -217,3 +217,71 @@ func (e *gfP2) Invert(a *gfP2, pool *bnPool) *gfP2 {
 
 	return e
 }
+
+// Sqrt sets e to a square root of a and returns e and true. If a is not a
+// square, it returns nil and false. It uses algorithm 9 of "Square root
+// computation over even extension fields", Adj and Rodríguez-Henríquez,
+// https://eprint.iacr.org/2012/685.pdf, which requires p = 3 mod 4.
+func (e *gfP2) Sqrt(a *gfP2, pool *bnPool) (*gfP2, bool) {
+	if a.IsZero() {
+		e.SetZero()
+		return e, true
+	}
+
+	// a1 = a^((p-3)/4)
+	exp := pool.Get().Sub(p, big.NewInt(3))
+	exp.Rsh(exp, 2)
+	a1 := newGFp2(pool).Exp(a, exp, pool)
+
+	// alpha = a1²a, a0 = alpha^p·alpha
+	alpha := newGFp2(pool).Square(a1, pool)
+	alpha.Mul(alpha, a, pool)
+	a0 := newGFp2(pool).Conjugate(alpha)
+	a0.Mul(a0, alpha, pool)
+	a0.Minimal()
+
+	minusOne := pool.Get().Sub(p, big.NewInt(1))
+	defer func() {
+		pool.Put(exp)
+		pool.Put(minusOne)
+		a1.Put(pool)
+		alpha.Put(pool)
+		a0.Put(pool)
+	}()
+	if a0.x.Sign() == 0 && a0.y.Cmp(minusOne) == 0 {
+		return nil, false
+	}
+
+	x0 := newGFp2(pool).Mul(a1, a, pool)
+	x := newGFp2(pool)
+	alpha.Minimal()
+	if alpha.x.Sign() == 0 && alpha.y.Cmp(minusOne) == 0 {
+		// x = i·x0
+		x.x.Set(x0.y)
+		x.y.Neg(x0.x)
+	} else {
+		// x = (1+alpha)^((p-1)/2)·x0
+		b := newGFp2(pool).Set(alpha)
+		b.y.Add(b.y, big.NewInt(1))
+		exp.Rsh(minusOne, 1)
+		b.Exp(b, exp, pool)
+		x.Mul(b, x0, pool)
+		b.Put(pool)
+	}
+	x0.Put(pool)
+	x.Minimal()
+
+	// Guard against non-squares that slip past the norm test.
+	check := newGFp2(pool).Square(x, pool)
+	check.Sub(check, a)
+	check.Minimal()
+	ok := check.IsZero()
+	check.Put(pool)
+	if !ok {
+		x.Put(pool)
+		return nil, false
+	}
+	e.Set(x)
+	x.Put(pool)
+	return e, true
+}
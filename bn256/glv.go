@@ -0,0 +1,218 @@
+package bn256
+
+import (
+	"math/big"
+)
+
+// Scalar multiplication in G₁ and G₂ uses the endomorphisms of BN curves to
+// shorten the scalar, following Gallant, Lambert and Vanstone (GLV) and
+// Galbraith, Lin and Scott (GLS).
+//
+// On G₁, φ(x, y) = (βx, y) with β a cube root of unity in GF(p) acts as
+// multiplication by λ = 36u³+18u²+6u+1, a cube root of unity mod Order. A
+// scalar k is written as k ≡ k₀ + k₁λ with |kᵢ| < 2¹²⁷, so k·P = k₀·P +
+// k₁·φ(P) takes 127 steps of a joint double-and-add instead of 256.
+//
+// On G₂, ψ acts as multiplication by 6u², a primitive twelfth root of unity
+// mod Order, and k ≡ k₀ + k₁·6u² + k₂·(6u²)² + k₃·(6u²)³ with |kᵢ| < 2⁶⁵, so
+// k·Q takes 65 steps.
+//
+// The kᵢ are found by Babai rounding: with B a basis of the lattice of the
+// vectors v with sum(vᵢλⁱ) ≡ 0 mod Order, (k, 0, …) is written in the basis
+// B, the coordinates are rounded to integers cⱼ, and (k₀, k₁, …) = (k, 0, …) -
+// sum(cⱼBⱼ). Each |kᵢ| is at most half the sum of column i of B. The G₁ basis
+// is that of the GLV paper and the G₂ basis is from "Endomorphisms for faster
+// elliptic curve cryptography on a large class of curves" by Galbraith, Lin
+// and Scott, https://eprint.iacr.org/2008/194.pdf.
+//
+// The point arithmetic is that of the ladder: negative kᵢ negate their point
+// with a conditional move, and the sum of the points selected by each column
+// of bits is read from a table with ctCurveSelect or ctTwistSelect.
+
+const (
+	glvBits = 127
+	glsBits = 65
+)
+
+// uPoly returns sum(c[i]·uⁱ).
+func uPoly(c ...int64) *big.Int {
+	ret := new(big.Int)
+	for i := len(c) - 1; i >= 0; i-- {
+		ret.Mul(ret, u)
+		ret.Add(ret, big.NewInt(c[i]))
+	}
+	return ret
+}
+
+// lattice is a basis of the vectors v with sum(vᵢλⁱ) ≡ 0 mod Order, with
+// round[j]/det the j-th coordinate of (1, 0, …) in the basis.
+type lattice struct {
+	basis [][]*big.Int
+	round []*big.Int
+	det   *big.Int
+}
+
+var (
+	// glvBeta is β = ξ^((2p²-2)/3), for which φ(P) = λ·P on G₁.
+	glvBeta = newGFpFromBig(xiTo2PSquaredMinus2Over3)
+
+	// glvLattice has det = Order.
+	glvLattice = &lattice{
+		basis: [][]*big.Int{
+			{uPoly(1, 2), uPoly(0, -2, -6)},
+			{uPoly(1, 4, 6), uPoly(1, 2)},
+		},
+		round: []*big.Int{uPoly(1, 2), uPoly(0, 2, 6)},
+		det:   Order,
+	}
+
+	// glsLattice has det = -3·Order, so round is negated.
+	glsLattice = &lattice{
+		basis: [][]*big.Int{
+			{uPoly(1, 1), uPoly(0, 1), uPoly(0, 1), uPoly(0, -2)},
+			{uPoly(1, 2), uPoly(0, -1), uPoly(-1, -1), uPoly(0, -1)},
+			{uPoly(0, 2), uPoly(1, 2), uPoly(1, 2), uPoly(1, 2)},
+			{uPoly(-1, 1), uPoly(2, 4), uPoly(1, -2), uPoly(-1, 1)},
+		},
+		round: []*big.Int{uPoly(3, 9, 6), uPoly(0, 3, 24, 36), uPoly(0, 3, 12, 18), uPoly(0, -3, -6)},
+		det:   new(big.Int).Mul(big.NewInt(3), Order),
+	}
+
+	// psiX and psiY are ξ^((p-1)/3) and ξ^((p-1)/2), by which ψ multiplies
+	// the conjugated coordinates.
+	psiX = newCTGFp2(xiToPMinus1Over3)
+	psiY = newCTGFp2(xiToPMinus1Over2)
+)
+
+// decompose returns the kᵢ with k ≡ sum(kᵢλⁱ) mod Order.
+func (l *lattice) decompose(k *big.Int) []*big.Int {
+	ks := make([]*big.Int, len(l.basis[0]))
+	ks[0] = new(big.Int).Set(k)
+	for i := 1; i < len(ks); i++ {
+		ks[i] = new(big.Int)
+	}
+	twoDet := new(big.Int).Lsh(l.det, 1)
+	c, t := new(big.Int), new(big.Int)
+	for j, b := range l.basis {
+		// c = ⌊(2k·round[j] + det) / 2det⌋, the nearest integer to k·round[j]/det
+		c.Mul(k, l.round[j])
+		c.Lsh(c, 1)
+		c.Add(c, l.det)
+		c.Div(c, twoDet)
+		for i := range ks {
+			ks[i].Sub(ks[i], t.Mul(c, b[i]))
+		}
+	}
+	return ks
+}
+
+// jointLen returns the number of steps for the scalars ks, which is bits
+// unless one of them is longer.
+func jointLen(ks []*big.Int, bits int) int {
+	for _, k := range ks {
+		if n := k.BitLen(); n > bits {
+			bits = n
+		}
+	}
+	return bits
+}
+
+// jointIndex returns the table index of bit i of the scalars ks.
+func jointIndex(ks []*big.Int, i int) uint64 {
+	var b uint64
+	for j, k := range ks {
+		b |= uint64(k.Bit(i)) << uint(j)
+	}
+	return b
+}
+
+// ctCurveEndo sets c to φ(a) = βX : Y : Z.
+func ctCurveEndo(c, a *ctCurvePoint) {
+	gfpMul(&c.x, &a.x, glvBeta)
+	c.y, c.z = a.y, a.z
+}
+
+// ctCurveMulGLV sets c to scalar·a.
+func ctCurveMulGLV(c, a *ctCurvePoint, scalar *big.Int) {
+	ks := glvLattice.decompose(new(big.Int).Mod(scalar, Order))
+	var pts [2]ctCurvePoint
+	pts[0] = *a
+	ctCurveEndo(&pts[1], a)
+	for i, k := range ks {
+		ctCurveCneg(&pts[i], uint64(k.Sign()>>1&1))
+		k.Abs(k)
+	}
+
+	var table [4]ctCurvePoint
+	table[0] = ctCurvePoint{y: *newGFpFromInt64(1)}
+	table[1], table[2] = pts[0], pts[1]
+	ctCurveAdd(&table[3], &pts[0], &pts[1])
+
+	r := table[0]
+	var e ctCurvePoint
+	for i := jointLen(ks, glvBits) - 1; i >= 0; i-- {
+		ctCurveAdd(&r, &r, &r)
+		ctCurveSelect(&e, table[:], jointIndex(ks, i))
+		ctCurveAdd(&r, &r, &e)
+	}
+	*c = r
+}
+
+// ctTwistPsi sets c to ψ(a) = conj(X)·ξ^((p-1)/3) : conj(Y)·ξ^((p-1)/2) :
+// conj(Z), see twistPoint.psi. It is defined on the whole twist.
+func ctTwistPsi(c, a *ctTwistPoint) {
+	var x, y ctGFp2
+	gfpNeg(&x.x, &a.x.x)
+	x.y = a.x.y
+	gfpNeg(&y.x, &a.y.x)
+	y.y = a.y.y
+	gfpNeg(&c.z.x, &a.z.x)
+	c.z.y = a.z.y
+	ctGFp2Mul(&c.x, &x, psiX)
+	ctGFp2Mul(&c.y, &y, psiY)
+}
+
+// ctTwistMulGLS sets c to scalar·a, for a in G₂.
+func ctTwistMulGLS(c, a *ctTwistPoint, scalar *big.Int) {
+	ks := glsLattice.decompose(new(big.Int).Mod(scalar, Order))
+	var pts [4]ctTwistPoint
+	pts[0] = *a
+	for i := 1; i < len(pts); i++ {
+		ctTwistPsi(&pts[i], &pts[i-1])
+	}
+	for i, k := range ks {
+		ctTwistCneg(&pts[i], uint64(k.Sign()>>1&1))
+		k.Abs(k)
+	}
+
+	var table [16]ctTwistPoint
+	table[0] = ctTwistPoint{y: ctGFp2{y: *newGFpFromInt64(1)}}
+	for j := range pts {
+		for b := 1 << uint(j); b < 1<<uint(j+1); b++ {
+			ctTwistAdd(&table[b], &table[b-1<<uint(j)], &pts[j])
+		}
+	}
+
+	r := table[0]
+	var e ctTwistPoint
+	for i := jointLen(ks, glsBits) - 1; i >= 0; i-- {
+		ctTwistAdd(&r, &r, &r)
+		ctTwistSelect(&e, table[:], jointIndex(ks, i))
+		ctTwistAdd(&r, &r, &e)
+	}
+	*c = r
+}
+
+// ctTwistMulPublic sets c to k·a, for a public k >= 0 and any point a of the
+// twist, by double-and-add over the k.BitLen() bits of k. It is meant for
+// constants such as 6u², which are half as long as a reduced scalar.
+func ctTwistMulPublic(c, a *ctTwistPoint, k *big.Int) {
+	r := ctTwistPoint{y: ctGFp2{y: *newGFpFromInt64(1)}}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		ctTwistAdd(&r, &r, &r)
+		if k.Bit(i) == 1 {
+			ctTwistAdd(&r, &r, a)
+		}
+	}
+	*c = r
+}
@@ -0,0 +1,98 @@
+package bn256
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+)
+
+// twistCofactor is the cofactor of G₂ in the twist group E'(GF(p²)), whose
+// order is Order·(2p-Order).
+var twistCofactor = new(big.Int).Sub(new(big.Int).Lsh(p, 1), Order)
+
+// twistTrace is the trace of Frobenius t = p+1-Order = 6u²+1.
+var twistTrace = new(big.Int).Add(sixuSquared, big.NewInt(1))
+
+// clearCofactor sets c to twistCofactor·a for any point a of the twist and
+// returns c. On the whole twist ψ satisfies ψ²-tψ+p = 0, so 2p-Order = p-1+t
+// acts as t(1+ψ)-ψ²-1 and
+//
+//	twistCofactor·a = t·(a + ψ(a)) - ψ²(a) - a,
+//
+// which takes one multiplication by the 128-bit t instead of the 256-bit
+// twistCofactor. The result is the same point.
+func (c *twistPoint) clearCofactor(a *twistPoint, pool *bnPool) *twistPoint {
+	q := newCTTwistPoint(a)
+	var psi1, psi2, r ctTwistPoint
+	ctTwistPsi(&psi1, q)
+	ctTwistPsi(&psi2, &psi1)
+	ctTwistAdd(&r, q, &psi1)
+	ctTwistMulPublic(&r, &r, twistTrace)
+	ctTwistAdd(&psi2, &psi2, q)
+	ctTwistCneg(&psi2, 1)
+	ctTwistAdd(&r, &r, &psi2)
+	r.affine(c)
+	return c
+}
+
+// HashG2 deterministically maps msg to an element of G₂ whose discrete
+// logarithm with respect to the generator is unknown. dst is a
+// domain-separation tag that must be distinct for every use.
+//
+// Deprecated: HashG2 takes a variable number of steps and follows no
+// standard; use HashToG2. It is kept so that points derived with it, such as
+// the GeneratorNUMS generator of ccs08, can be reproduced.
+//
+// The map is try-and-increment: for ctr = 0, 1, … it sets
+//
+//	xᵢ = SHA-256(dst ‖ len(dst) ‖ msg ‖ ctr ‖ i) mod p  for i = 0, 1
+//
+// (len(dst) is one byte, ctr is a 4-byte big-endian integer and i is one
+// byte; a dst longer than 255 bytes is first hashed down as in RFC 9380,
+// section 5.3.3), takes x = x₁i+x₀ and stops at the first x for which x³+3/ξ is a
+// square in GF(p²). The point (x, y), where y is the square root returned
+// by gfP2.Sqrt, is then multiplied by the cofactor 2p-Order.
+func HashG2(msg, dst []byte) *G2 {
+	dst = shortDST(dst)
+	pool := new(bnPool)
+	pt := newTwistPoint(pool)
+	rhs := newGFp2(pool)
+
+	for ctr := uint32(0); ; ctr++ {
+		pt.x.x.Set(hashToBase(msg, dst, ctr, 1))
+		pt.x.y.Set(hashToBase(msg, dst, ctr, 0))
+
+		rhs.Square(pt.x, pool)
+		rhs.Mul(rhs, pt.x, pool)
+		rhs.Add(rhs, twistB)
+		rhs.Minimal()
+		if _, ok := pt.y.Sqrt(rhs, pool); !ok {
+			continue
+		}
+		pt.z.SetOne()
+		pt.t.SetOne()
+
+		out := newTwistPoint(nil)
+		out.clearCofactor(pt, pool)
+		if out.IsInfinity() {
+			continue
+		}
+		out.MakeAffine(pool)
+		return &G2{out}
+	}
+}
+
+// hashToBase returns SHA-256(dst ‖ len(dst) ‖ msg ‖ ctr ‖ i) mod p.
+func hashToBase(msg, dst []byte, ctr uint32, i byte) *big.Int {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], ctr)
+
+	digest := sha256.New()
+	digest.Write(dst)
+	digest.Write([]byte{byte(len(dst))})
+	digest.Write(msg)
+	digest.Write(buf[:])
+	digest.Write([]byte{i})
+	x := new(big.Int).SetBytes(digest.Sum(nil))
+	return x.Mod(x, p)
+}
@@ -0,0 +1,345 @@
+package bn256
+
+import (
+	"crypto/sha256"
+	"math/big"
+)
+
+// HashToG1 and HashToG2 implement the hash_to_curve construction of RFC 9380,
+// "Hashing to Elliptic Curves", with expand_message_xmd over SHA-256 and the
+// Shallue–van de Woestijne map of section 6.6.1, which works for curves
+// y² = x³+B such as G₁ and the twist carrying G₂. Both encode the message
+// into two field elements, map each of them to the curve and add the results,
+// so that the output is indistinguishable from a random point.
+
+// hashToFieldLen is L = ⌈(⌈log₂ p⌉+k)/8⌉ for the security parameter k = 128.
+const hashToFieldLen = (256 + 128) / 8
+
+// svdwG1 holds the constants of the SVDW map to y² = x³+3 over GF(p).
+type svdwG1 struct {
+	z, c1, c2, c3, c4 *big.Int
+}
+
+// svdwG2 holds the constants of the SVDW map to y² = x³+3/ξ over GF(p²).
+type svdwG2 struct {
+	z, c1, c2, c3, c4 *gfP2
+}
+
+var (
+	svdw1 = newSVDWG1()
+	svdw2 = newSVDWG2()
+)
+
+// HashToG1 deterministically maps msg to an element of G₁ whose discrete
+// logarithm with respect to the generator is unknown. dst is a
+// domain-separation tag that must be distinct for every use.
+func HashToG1(msg, dst []byte) *G1 {
+	u := hashToField(msg, dst, 2, 1)
+	q0 := svdw1.mapToCurve(u[0])
+	q1 := svdw1.mapToCurve(u[1])
+
+	// The cofactor of G₁ is one.
+	out := newCurvePoint(nil)
+	out.Add(q0, q1, nil)
+	out.MakeAffine(nil)
+	return &G1{out}
+}
+
+// HashToG2 deterministically maps msg to an element of G₂ whose discrete
+// logarithm with respect to the generator is unknown. dst is a
+// domain-separation tag that must be distinct for every use.
+//
+// HashToG2 supersedes HashG2 and, unlike it, runs in a fixed number of
+// steps. Cofactor clearing is multiplication by 2p-Order, computed with ψ.
+func HashToG2(msg, dst []byte) *G2 {
+	pool := new(bnPool)
+	u := hashToField(msg, dst, 2, 2)
+	q0 := svdw2.mapToCurve(&gfP2{u[1], u[0]}, pool)
+	q1 := svdw2.mapToCurve(&gfP2{u[3], u[2]}, pool)
+
+	sum := newTwistPoint(pool)
+	sum.Add(q0, q1, pool)
+	out := newTwistPoint(nil)
+	out.clearCofactor(sum, pool)
+	out.MakeAffine(pool)
+	return &G2{out}
+}
+
+// shortDST returns dst, or SHA-256("H2C-OVERSIZE-DST-" ‖ dst) if dst is
+// longer than 255 bytes and its length would not fit in one byte (RFC 9380,
+// section 5.3.3).
+func shortDST(dst []byte) []byte {
+	if len(dst) <= 255 {
+		return dst
+	}
+	h := sha256.New()
+	h.Write([]byte("H2C-OVERSIZE-DST-"))
+	h.Write(dst)
+	return h.Sum(nil)
+}
+
+// expandMessageXMD is expand_message_xmd of RFC 9380, section 5.3.1, with
+// SHA-256. It panics if n exceeds 255·32 bytes.
+func expandMessageXMD(msg, dst []byte, n int) []byte {
+	const (
+		bInBytes = sha256.Size
+		sInBytes = sha256.BlockSize
+	)
+
+	dst = shortDST(dst)
+	ell := (n + bInBytes - 1) / bInBytes
+	if ell > 255 {
+		panic("bn256: expand_message_xmd output too long")
+	}
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, sInBytes))
+	h.Write(msg)
+	h.Write([]byte{byte(n >> 8), byte(n), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	out := make([]byte, 0, ell*bInBytes)
+	bi := make([]byte, bInBytes)
+	for i := 1; i <= ell; i++ {
+		for j := range bi {
+			bi[j] ^= b0[j]
+		}
+		h.Reset()
+		h.Write(bi)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(bi[:0])
+		out = append(out, bi...)
+	}
+	return out[:n]
+}
+
+// hashToField is hash_to_field of RFC 9380, section 5.2, returning count
+// elements of GF(p^m) as count·m coefficients in GF(p). The coefficients of
+// an element of GF(p²) come in the order of the RFC, real part first.
+func hashToField(msg, dst []byte, count, m int) []*big.Int {
+	uniform := expandMessageXMD(msg, dst, count*m*hashToFieldLen)
+	ret := make([]*big.Int, count*m)
+	for i := range ret {
+		e := new(big.Int).SetBytes(uniform[i*hashToFieldLen : (i+1)*hashToFieldLen])
+		ret[i] = e.Mod(e, p)
+	}
+	return ret
+}
+
+func isSquareGFp(a *big.Int) bool {
+	return big.Jacobi(a, p) >= 0
+}
+
+// isSquareGFp2 reports whether a is a square in GF(p²), which is the case if
+// and only if its norm a.x²+a.y² is a square in GF(p).
+func isSquareGFp2(a *gfP2) bool {
+	norm := new(big.Int).Mul(a.x, a.x)
+	norm.Add(norm, new(big.Int).Mul(a.y, a.y))
+	return isSquareGFp(norm.Mod(norm, p))
+}
+
+// curveRHS returns x³+3 mod p.
+func curveRHS(x *big.Int) *big.Int {
+	ret := new(big.Int).Mul(x, x)
+	ret.Mul(ret, x)
+	ret.Add(ret, curveB)
+	return ret.Mod(ret, p)
+}
+
+// twistRHS returns x³+3/ξ in minimal form.
+func twistRHS(x *gfP2, pool *bnPool) *gfP2 {
+	ret := newGFp2(nil).Square(x, pool)
+	ret.Mul(ret, x, pool)
+	ret.Add(ret, twistB)
+	ret.Minimal()
+	return ret
+}
+
+// inv0GFp2 returns a⁻¹, or zero if a is zero.
+func inv0GFp2(a *gfP2, pool *bnPool) *gfP2 {
+	if a.IsZero() {
+		return newGFp2(nil)
+	}
+	ret := newGFp2(nil).Invert(a, pool)
+	ret.Minimal()
+	return ret
+}
+
+// newSVDWG1 computes Z with find_z_svdw of RFC 9380, appendix H.1, and the
+// constants c1 = g(Z), c2 = -Z/2, c3 = √(-3g(Z)Z²) with sgn0(c3) = 0 and
+// c4 = -4g(Z)/3Z², where g(x) = x³+3.
+func newSVDWG1() *svdwG1 {
+	inv := func(a *big.Int) *big.Int { return new(big.Int).ModInverse(a, p) }
+	mod := func(a *big.Int) *big.Int { return a.Mod(a, p) }
+	half := inv(big.NewInt(2))
+
+	for ctr := int64(1); ; ctr++ {
+		for _, z := range []int64{ctr, -ctr} {
+			Z := mod(big.NewInt(z))
+			gz := curveRHS(Z)
+			if gz.Sign() == 0 {
+				continue
+			}
+			// h = -3Z²/4g(Z)
+			threeZ2 := mod(new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(Z, Z)))
+			h := mod(new(big.Int).Mul(new(big.Int).Neg(threeZ2), inv(mod(new(big.Int).Lsh(gz, 2)))))
+			if h.Sign() == 0 || !isSquareGFp(h) {
+				continue
+			}
+			c2 := mod(new(big.Int).Mul(new(big.Int).Neg(Z), half))
+			if !isSquareGFp(gz) && !isSquareGFp(curveRHS(c2)) {
+				continue
+			}
+
+			c3, _ := sqrtGFp(mod(new(big.Int).Neg(new(big.Int).Mul(gz, threeZ2))))
+			if c3.Bit(0) != 0 {
+				c3.Sub(p, c3)
+			}
+			c4 := mod(new(big.Int).Mul(new(big.Int).Neg(new(big.Int).Lsh(gz, 2)), inv(threeZ2)))
+			return &svdwG1{Z, gz, c2, c3, c4}
+		}
+	}
+}
+
+// newSVDWG2 is newSVDWG1 for the twist, with g(x) = x³+3/ξ.
+func newSVDWG2() *svdwG2 {
+	pool := new(bnPool)
+	half := &gfP2{new(big.Int), new(big.Int).ModInverse(big.NewInt(2), p)}
+
+	for ctr := int64(1); ; ctr++ {
+		for _, z := range []int64{ctr, -ctr} {
+			Z := &gfP2{new(big.Int), new(big.Int).Mod(big.NewInt(z), p)}
+			gz := twistRHS(Z, pool)
+			if gz.IsZero() {
+				continue
+			}
+			// h = -3Z²/4g(Z)
+			threeZ2 := newGFp2(nil).Square(Z, pool)
+			threeZ2.MulScalar(threeZ2, big.NewInt(3))
+			threeZ2.Minimal()
+			fourGZ := newGFp2(nil).MulScalar(gz, big.NewInt(4))
+			fourGZ.Minimal()
+			h := newGFp2(nil).Negative(threeZ2)
+			h.Mul(h, inv0GFp2(fourGZ, pool), pool)
+			h.Minimal()
+			if h.IsZero() || !isSquareGFp2(h) {
+				continue
+			}
+			c2 := newGFp2(nil).Negative(Z)
+			c2.Mul(c2, half, pool)
+			c2.Minimal()
+			if !isSquareGFp2(gz) && !isSquareGFp2(twistRHS(c2, pool)) {
+				continue
+			}
+
+			t := newGFp2(nil).Mul(gz, threeZ2, pool)
+			t.Negative(t)
+			t.Minimal()
+			c3 := newGFp2(nil)
+			c3.Sqrt(t, pool)
+			c3.Minimal()
+			if c3.sgn0() != 0 {
+				c3.Negative(c3)
+				c3.Minimal()
+			}
+			c4 := newGFp2(nil).Negative(fourGZ)
+			c4.Mul(c4, inv0GFp2(threeZ2, pool), pool)
+			c4.Minimal()
+			return &svdwG2{Z, gz, c2, c3, c4}
+		}
+	}
+}
+
+// mapToCurve is map_to_curve_svdw of RFC 9380, section 6.6.1. u must be
+// reduced modulo p.
+func (m *svdwG1) mapToCurve(u *big.Int) *curvePoint {
+	mod := func(a *big.Int) *big.Int { return a.Mod(a, p) }
+
+	tv1 := mod(new(big.Int).Mul(u, u))
+	tv1 = mod(tv1.Mul(tv1, m.c1))
+	tv2 := mod(new(big.Int).Add(big.NewInt(1), tv1))
+	tv1 = mod(tv1.Sub(big.NewInt(1), tv1))
+	tv3 := mod(new(big.Int).Mul(tv1, tv2))
+	if tv3.Sign() != 0 {
+		tv3.ModInverse(tv3, p)
+	}
+	tv4 := mod(new(big.Int).Mul(u, tv1))
+	tv4 = mod(tv4.Mul(tv4, tv3))
+	tv4 = mod(tv4.Mul(tv4, m.c3))
+
+	x := mod(new(big.Int).Sub(m.c2, tv4))
+	if !isSquareGFp(curveRHS(x)) {
+		x = mod(new(big.Int).Add(m.c2, tv4))
+		if !isSquareGFp(curveRHS(x)) {
+			x = mod(new(big.Int).Mul(tv2, tv2))
+			x = mod(x.Mul(x, tv3))
+			x = mod(x.Mul(x, x))
+			x = mod(x.Mul(x, m.c4))
+			x = mod(x.Add(x, m.z))
+		}
+	}
+
+	y, _ := sqrtGFp(curveRHS(x))
+	if y.Bit(0) != u.Bit(0) {
+		y = mod(y.Neg(y))
+	}
+
+	ret := newCurvePoint(nil)
+	ret.x.Set(x)
+	ret.y.Set(y)
+	ret.z.SetInt64(1)
+	ret.t.SetInt64(1)
+	return ret
+}
+
+// mapToCurve is map_to_curve_svdw of RFC 9380, section 6.6.1, over GF(p²). u
+// must be minimal.
+func (m *svdwG2) mapToCurve(u *gfP2, pool *bnPool) *twistPoint {
+	one := newGFp2(nil).SetOne()
+
+	tv1 := newGFp2(nil).Square(u, pool)
+	tv1.Mul(tv1, m.c1, pool)
+	tv2 := newGFp2(nil).Add(one, tv1)
+	tv2.Minimal()
+	tv1.Sub(one, tv1)
+	tv1.Minimal()
+	tv3 := newGFp2(nil).Mul(tv1, tv2, pool)
+	tv3.Minimal()
+	tv3 = inv0GFp2(tv3, pool)
+	tv4 := newGFp2(nil).Mul(u, tv1, pool)
+	tv4.Mul(tv4, tv3, pool)
+	tv4.Mul(tv4, m.c3, pool)
+
+	x := newGFp2(nil).Sub(m.c2, tv4)
+	x.Minimal()
+	if !isSquareGFp2(twistRHS(x, pool)) {
+		x.Add(m.c2, tv4)
+		x.Minimal()
+		if !isSquareGFp2(twistRHS(x, pool)) {
+			x.Square(tv2, pool)
+			x.Mul(x, tv3, pool)
+			x.Square(x, pool)
+			x.Mul(x, m.c4, pool)
+			x.Add(x, m.z)
+			x.Minimal()
+		}
+	}
+
+	y := newGFp2(nil)
+	y.Sqrt(twistRHS(x, pool), pool)
+	y.Minimal()
+	if y.sgn0() != u.sgn0() {
+		y.Negative(y)
+		y.Minimal()
+	}
+
+	ret := newTwistPoint(nil)
+	ret.x.Set(x)
+	ret.y.Set(y)
+	ret.z.SetOne()
+	ret.t.SetOne()
+	return ret
+}
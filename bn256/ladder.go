@@ -0,0 +1,342 @@
+package bn256
+
+import (
+	"math/big"
+)
+
+// This file implements the point arithmetic of scalar multiplication in G₁
+// and G₂ in time independent of the scalar and the point; see the BUG note in
+// bn256.go for what is left in math/big. Points are converted to homogeneous
+// projective coordinates over the Montgomery-form gfP, combined with the
+// complete addition formulas of algorithm 7 of "Complete addition formulas for
+// prime order elliptic curves" by Renes, Costello and Batina,
+// https://eprint.iacr.org/2015/1060.pdf. The formulas are complete because
+// neither curve has points of order two. curvePoint.Mul and twistPoint.Mul
+// shorten the scalar with the endomorphisms in glv.go; the Montgomery ladder
+// over a fixed number of bits below works for any point and any scalar.
+
+// ladderBits is the number of ladder steps for scalars below 2²⁵⁶.
+const ladderBits = 256
+
+// ctGFp2 is an element of GF(p²) over gfP, x·i+y like gfP2.
+type ctGFp2 struct {
+	x, y gfP
+}
+
+func newCTGFp2(a *gfP2) *ctGFp2 {
+	return &ctGFp2{*newGFpFromBig(a.x), *newGFpFromBig(a.y)}
+}
+
+func (e *ctGFp2) gfP2() *gfP2 {
+	return &gfP2{e.x.toBig(), e.y.toBig()}
+}
+
+func ctGFp2Add(c, a, b *ctGFp2) {
+	gfpAdd(&c.x, &a.x, &b.x)
+	gfpAdd(&c.y, &a.y, &b.y)
+}
+
+func ctGFp2Sub(c, a, b *ctGFp2) {
+	gfpSub(&c.x, &a.x, &b.x)
+	gfpSub(&c.y, &a.y, &b.y)
+}
+
+// ctGFp2Mul sets c to a·b using (a.x·i+a.y)(b.x·i+b.y) =
+// ((a.x+a.y)(b.x+b.y) - a.x·b.x - a.y·b.y)·i + a.y·b.y - a.x·b.x.
+func ctGFp2Mul(c, a, b *ctGFp2) {
+	var tx, ty, sa, sb gfP
+	gfpMul(&tx, &a.x, &b.x)
+	gfpMul(&ty, &a.y, &b.y)
+	gfpAdd(&sa, &a.x, &a.y)
+	gfpAdd(&sb, &b.x, &b.y)
+	gfpMul(&sa, &sa, &sb)
+	gfpSub(&sa, &sa, &tx)
+	gfpSub(&c.x, &sa, &ty)
+	gfpSub(&c.y, &ty, &tx)
+}
+
+// ctGFp2Invert sets c to a⁻¹ = (-a.x·i+a.y)/(a.x²+a.y²), or to zero if a is
+// zero.
+func ctGFp2Invert(c, a *ctGFp2) {
+	var t, tx gfP
+	gfpSquare(&t, &a.x)
+	gfpSquare(&tx, &a.y)
+	gfpAdd(&t, &t, &tx)
+	gfpInvert(&t, &t)
+	gfpNeg(&tx, &a.x)
+	gfpMul(&c.x, &tx, &t)
+	gfpMul(&c.y, &a.y, &t)
+}
+
+func ctGFp2Cmov(c, a *ctGFp2, cond uint64) {
+	gfpCmov(&c.x, &a.x, cond)
+	gfpCmov(&c.y, &a.y, cond)
+}
+
+func ctGFp2Cswap(a, b *ctGFp2, cond uint64) {
+	gfpCswap(&a.x, &b.x, cond)
+	gfpCswap(&a.y, &b.y, cond)
+}
+
+// ctCurvePoint is a point of y²=x³+3 in homogeneous coordinates X:Y:Z.
+type ctCurvePoint struct {
+	x, y, z gfP
+}
+
+// curveB3 is 3·curveB.
+var curveB3 = newGFpFromBig(new(big.Int).Mul(big.NewInt(3), curveB))
+
+// newCTCurvePoint converts the Jacobian point a, x/z², y/z³, to x·z : y : z³.
+func newCTCurvePoint(a *curvePoint) *ctCurvePoint {
+	if a.IsInfinity() {
+		return &ctCurvePoint{y: *newGFpFromInt64(1)}
+	}
+	c := &ctCurvePoint{
+		x: *newGFpFromBig(a.x),
+		y: *newGFpFromBig(a.y),
+		z: *newGFpFromBig(a.z),
+	}
+	var zz gfP
+	gfpSquare(&zz, &c.z)
+	gfpMul(&c.x, &c.x, &c.z)
+	gfpMul(&c.z, &c.z, &zz)
+	return c
+}
+
+// affine sets out to the affine form X/Z : Y/Z : 1 of c, or to 0 : 1 : 0 if c
+// is ∞.
+func (c *ctCurvePoint) affine(out *curvePoint) {
+	var zInv, x, y gfP
+	gfpInvert(&zInv, &c.z)
+	gfpMul(&x, &c.x, &zInv)
+	gfpMul(&y, &c.y, &zInv)
+	one := *newGFpFromInt64(1)
+	z := one
+	inf := gfpIsZero(&c.z)
+	gfpCmov(&y, &one, inf)
+	gfpCmov(&z, &gfP{}, inf)
+	out.x.Set(x.toBig())
+	out.y.Set(y.toBig())
+	out.z.Set(z.toBig())
+	out.t.Set(z.toBig())
+}
+
+// ctCurveAdd sets c to a+b for any a and b, including a = b and ∞.
+func ctCurveAdd(c, a, b *ctCurvePoint) {
+	var t0, t1, t2, t3, t4, x3, y3, z3 gfP
+	gfpMul(&t0, &a.x, &b.x)
+	gfpMul(&t1, &a.y, &b.y)
+	gfpMul(&t2, &a.z, &b.z)
+	gfpAdd(&t3, &a.x, &a.y)
+	gfpAdd(&t4, &b.x, &b.y)
+	gfpMul(&t3, &t3, &t4)
+	gfpAdd(&t4, &t0, &t1)
+	gfpSub(&t3, &t3, &t4)
+	gfpAdd(&t4, &a.y, &a.z)
+	gfpAdd(&x3, &b.y, &b.z)
+	gfpMul(&t4, &t4, &x3)
+	gfpAdd(&x3, &t1, &t2)
+	gfpSub(&t4, &t4, &x3)
+	gfpAdd(&x3, &a.x, &a.z)
+	gfpAdd(&y3, &b.x, &b.z)
+	gfpMul(&x3, &x3, &y3)
+	gfpAdd(&y3, &t0, &t2)
+	gfpSub(&y3, &x3, &y3)
+	gfpAdd(&x3, &t0, &t0)
+	gfpAdd(&t0, &x3, &t0)
+	gfpMul(&t2, curveB3, &t2)
+	gfpAdd(&z3, &t1, &t2)
+	gfpSub(&t1, &t1, &t2)
+	gfpMul(&y3, curveB3, &y3)
+	gfpMul(&x3, &t4, &y3)
+	gfpMul(&t2, &t3, &t1)
+	gfpSub(&x3, &t2, &x3)
+	gfpMul(&y3, &y3, &t0)
+	gfpMul(&t1, &t1, &z3)
+	gfpAdd(&y3, &t1, &y3)
+	gfpMul(&t0, &t0, &t3)
+	gfpMul(&z3, &z3, &t4)
+	gfpAdd(&z3, &z3, &t0)
+	c.x, c.y, c.z = x3, y3, z3
+}
+
+func ctCurveCswap(a, b *ctCurvePoint, cond uint64) {
+	gfpCswap(&a.x, &b.x, cond)
+	gfpCswap(&a.y, &b.y, cond)
+	gfpCswap(&a.z, &b.z, cond)
+}
+
+// ctCurveSelect sets c to table[idx] by reading every entry, so that the
+// memory access pattern does not depend on idx.
+func ctCurveSelect(c *ctCurvePoint, table []ctCurvePoint, idx uint64) {
+	for j := range table {
+		cond := ctEqual(uint64(j), idx)
+		gfpCmov(&c.x, &table[j].x, cond)
+		gfpCmov(&c.y, &table[j].y, cond)
+		gfpCmov(&c.z, &table[j].z, cond)
+	}
+}
+
+// ctCurveCneg sets a to -a if cond is 1 and leaves it unchanged if cond is 0.
+func ctCurveCneg(a *ctCurvePoint, cond uint64) {
+	var y gfP
+	gfpNeg(&y, &a.y)
+	gfpCmov(&a.y, &y, cond)
+}
+
+// ctTwistPoint is a point of y²=x³+3/ξ over GF(p²) in homogeneous
+// coordinates X:Y:Z.
+type ctTwistPoint struct {
+	x, y, z ctGFp2
+}
+
+// twistB3 is 3·twistB.
+var twistB3 = newCTGFp2(&gfP2{
+	new(big.Int).Mul(big.NewInt(3), twistB.x),
+	new(big.Int).Mul(big.NewInt(3), twistB.y),
+})
+
+// newCTTwistPoint converts the Jacobian point a, x/z², y/z³, to x·z : y : z³.
+func newCTTwistPoint(a *twistPoint) *ctTwistPoint {
+	if a.IsInfinity() {
+		return &ctTwistPoint{y: ctGFp2{y: *newGFpFromInt64(1)}}
+	}
+	c := &ctTwistPoint{
+		x: *newCTGFp2(a.x),
+		y: *newCTGFp2(a.y),
+		z: *newCTGFp2(a.z),
+	}
+	var zz ctGFp2
+	ctGFp2Mul(&zz, &c.z, &c.z)
+	ctGFp2Mul(&c.x, &c.x, &c.z)
+	ctGFp2Mul(&c.z, &c.z, &zz)
+	return c
+}
+
+// affine sets out to the affine form X/Z : Y/Z : 1 of c, or to 0 : 1 : 0 if c
+// is ∞.
+func (c *ctTwistPoint) affine(out *twistPoint) {
+	var zInv, x, y ctGFp2
+	ctGFp2Invert(&zInv, &c.z)
+	ctGFp2Mul(&x, &c.x, &zInv)
+	ctGFp2Mul(&y, &c.y, &zInv)
+	one := ctGFp2{y: *newGFpFromInt64(1)}
+	z := one
+	inf := gfpIsZero(&c.z.x) & gfpIsZero(&c.z.y)
+	ctGFp2Cmov(&y, &one, inf)
+	ctGFp2Cmov(&z, &ctGFp2{}, inf)
+	out.x.Set(x.gfP2())
+	out.y.Set(y.gfP2())
+	out.z.Set(z.gfP2())
+	out.t.Set(z.gfP2())
+}
+
+// ctTwistAdd sets c to a+b for any a and b, including a = b and ∞.
+func ctTwistAdd(c, a, b *ctTwistPoint) {
+	var t0, t1, t2, t3, t4, x3, y3, z3 ctGFp2
+	ctGFp2Mul(&t0, &a.x, &b.x)
+	ctGFp2Mul(&t1, &a.y, &b.y)
+	ctGFp2Mul(&t2, &a.z, &b.z)
+	ctGFp2Add(&t3, &a.x, &a.y)
+	ctGFp2Add(&t4, &b.x, &b.y)
+	ctGFp2Mul(&t3, &t3, &t4)
+	ctGFp2Add(&t4, &t0, &t1)
+	ctGFp2Sub(&t3, &t3, &t4)
+	ctGFp2Add(&t4, &a.y, &a.z)
+	ctGFp2Add(&x3, &b.y, &b.z)
+	ctGFp2Mul(&t4, &t4, &x3)
+	ctGFp2Add(&x3, &t1, &t2)
+	ctGFp2Sub(&t4, &t4, &x3)
+	ctGFp2Add(&x3, &a.x, &a.z)
+	ctGFp2Add(&y3, &b.x, &b.z)
+	ctGFp2Mul(&x3, &x3, &y3)
+	ctGFp2Add(&y3, &t0, &t2)
+	ctGFp2Sub(&y3, &x3, &y3)
+	ctGFp2Add(&x3, &t0, &t0)
+	ctGFp2Add(&t0, &x3, &t0)
+	ctGFp2Mul(&t2, twistB3, &t2)
+	ctGFp2Add(&z3, &t1, &t2)
+	ctGFp2Sub(&t1, &t1, &t2)
+	ctGFp2Mul(&y3, twistB3, &y3)
+	ctGFp2Mul(&x3, &t4, &y3)
+	ctGFp2Mul(&t2, &t3, &t1)
+	ctGFp2Sub(&x3, &t2, &x3)
+	ctGFp2Mul(&y3, &y3, &t0)
+	ctGFp2Mul(&t1, &t1, &z3)
+	ctGFp2Add(&y3, &t1, &y3)
+	ctGFp2Mul(&t0, &t0, &t3)
+	ctGFp2Mul(&z3, &z3, &t4)
+	ctGFp2Add(&z3, &z3, &t0)
+	c.x, c.y, c.z = x3, y3, z3
+}
+
+func ctTwistCswap(a, b *ctTwistPoint, cond uint64) {
+	ctGFp2Cswap(&a.x, &b.x, cond)
+	ctGFp2Cswap(&a.y, &b.y, cond)
+	ctGFp2Cswap(&a.z, &b.z, cond)
+}
+
+// ctTwistSelect sets c to table[idx] by reading every entry, so that the
+// memory access pattern does not depend on idx.
+func ctTwistSelect(c *ctTwistPoint, table []ctTwistPoint, idx uint64) {
+	for j := range table {
+		cond := ctEqual(uint64(j), idx)
+		ctGFp2Cmov(&c.x, &table[j].x, cond)
+		ctGFp2Cmov(&c.y, &table[j].y, cond)
+		ctGFp2Cmov(&c.z, &table[j].z, cond)
+	}
+}
+
+// ctTwistCneg sets a to -a if cond is 1 and leaves it unchanged if cond is 0.
+func ctTwistCneg(a *ctTwistPoint, cond uint64) {
+	var y ctGFp2
+	gfpNeg(&y.x, &a.y.x)
+	gfpNeg(&y.y, &a.y.y)
+	ctGFp2Cmov(&a.y, &y, cond)
+}
+
+// ctEqual returns 1 if a = b and 0 otherwise, without branching.
+func ctEqual(a, b uint64) uint64 {
+	d := a ^ b
+	return 1 ^ (d|-d)>>63
+}
+
+// ladderLen returns the number of ladder steps for scalar. It depends only on
+// whether scalar needs more than ladderBits bits, which is never the case for
+// scalars reduced modulo Order.
+func ladderLen(scalar *big.Int) int {
+	if n := scalar.BitLen(); n > ladderBits {
+		return n
+	}
+	return ladderBits
+}
+
+// ctCurveMul sets c to scalar·a, for scalar >= 0, with a Montgomery ladder
+// keeping r1 - r0 = a after every step.
+func ctCurveMul(c, a *ctCurvePoint, scalar *big.Int) {
+	r0 := &ctCurvePoint{y: *newGFpFromInt64(1)}
+	r1 := *a
+	for i := ladderLen(scalar) - 1; i >= 0; i-- {
+		bit := uint64(scalar.Bit(i))
+		ctCurveCswap(r0, &r1, bit)
+		ctCurveAdd(&r1, r0, &r1)
+		ctCurveAdd(r0, r0, r0)
+		ctCurveCswap(r0, &r1, bit)
+	}
+	*c = *r0
+}
+
+// ctTwistMul sets c to scalar·a, for scalar >= 0, with a Montgomery ladder
+// keeping r1 - r0 = a after every step.
+func ctTwistMul(c, a *ctTwistPoint, scalar *big.Int) {
+	r0 := &ctTwistPoint{y: ctGFp2{y: *newGFpFromInt64(1)}}
+	r1 := *a
+	for i := ladderLen(scalar) - 1; i >= 0; i-- {
+		bit := uint64(scalar.Bit(i))
+		ctTwistCswap(r0, &r1, bit)
+		ctTwistAdd(&r1, r0, &r1)
+		ctTwistAdd(r0, r0, r0)
+		ctTwistCswap(r0, &r1, bit)
+	}
+	*c = *r0
+}
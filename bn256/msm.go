@@ -0,0 +1,232 @@
+package bn256
+
+import (
+	"math/big"
+)
+
+// Multi-scalar multiplication uses the bucket method of Pippenger: scalars
+// are cut into c-bit windows, and for every window, starting with the most
+// significant, each point is added into the bucket of its digit. The buckets
+// are summed with weights 1, 2, …, 2ᶜ-1 by a running sum, and the window
+// results are combined by c doublings each. For n points with b-bit scalars
+// this takes about b/c·(n+2ᶜ) additions.
+//
+// Unlike ScalarMult, the memory access pattern depends on the scalars, so
+// these functions are meant for verifiers and public scalars.
+
+// Both functions first split every k·P with the endomorphisms of glv.go into
+// two 127-bit multiplications in G₁ or four 65-bit ones in G₂, which halves
+// or quarters the number of windows for twice or four times the points.
+
+// mulCostG1 and mulCostG2 are the costs of the separate multiplication of one
+// point in additions, counting the conversion to affine form.
+const (
+	mulCostG1 = 2*glvBits + 32
+	mulCostG2 = 2*glsBits + 24
+)
+
+// msmWindow returns the window size c that minimises the number of additions
+// ⌈bits/c⌉·(c+n+2(2ᶜ-1)) for n points with scalars of the given length, or 0
+// if the given cost of separate multiplications is lower.
+func msmWindow(n, bits, separate int) int {
+	best, bestCost := 0, separate
+	for c := 1; c <= 16; c++ {
+		cost := (bits + c - 1) / c * (c + n + 2*(1<<uint(c)-1))
+		if cost < bestCost {
+			best, bestCost = c, cost
+		}
+	}
+	return best
+}
+
+// msmDigit returns bits [w·c, w·c+c) of k.
+func msmDigit(k *big.Int, w, c int) int {
+	d := 0
+	for i := c - 1; i >= 0; i-- {
+		d = d<<1 | int(k.Bit(w*c+i))
+	}
+	return d
+}
+
+// msmDecompose decomposes the scalars in the lattice l and returns the parts
+// as nonnegative scalars, with their signs, and the number of bits of the
+// largest. Part i of scalar j is at index j·len(basis)+i.
+func msmDecompose(l *lattice, scalars []*big.Int) ([]*big.Int, []uint64, int) {
+	var ks []*big.Int
+	var negs []uint64
+	maxBits := 0
+	for _, k := range scalars {
+		for _, ki := range l.decompose(new(big.Int).Mod(k, Order)) {
+			negs = append(negs, uint64(ki.Sign()>>1&1))
+			ks = append(ks, ki.Abs(ki))
+			if n := ki.BitLen(); n > maxBits {
+				maxBits = n
+			}
+		}
+	}
+	return ks, negs, maxBits
+}
+
+// MultiScalarMultG1 returns sum(scalars[i]·points[i]), or nil if the lengths
+// of points and scalars differ. Scalars are reduced modulo Order and may be
+// negative.
+func MultiScalarMultG1(points []*G1, scalars []*big.Int) *G1 {
+	if len(points) != len(scalars) {
+		return nil
+	}
+	ks, negs, maxBits := msmDecompose(glvLattice, scalars)
+	c := msmWindow(len(ks), maxBits, mulCostG1*len(points))
+	if c == 0 {
+		ret := new(G1).SetInfinity()
+		for i, P := range points {
+			ret.Add(ret, new(G1).ScalarMult(P, scalars[i]))
+		}
+		ret.p.MakeAffine(nil)
+		return ret
+	}
+	ps := make([]ctCurvePoint, len(ks))
+	for i, P := range points {
+		ps[2*i] = *newCTCurvePoint(P.p)
+		ctCurveEndo(&ps[2*i+1], &ps[2*i])
+	}
+	for i := range ps {
+		ctCurveCneg(&ps[i], negs[i])
+	}
+
+	inf := ctCurvePoint{y: *newGFpFromInt64(1)}
+	acc := inf
+	buckets := make([]ctCurvePoint, 1<<uint(c)-1)
+	for w := (maxBits+c-1)/c - 1; w >= 0; w-- {
+		for i := 0; i < c; i++ {
+			ctCurveAdd(&acc, &acc, &acc)
+		}
+		for j := range buckets {
+			buckets[j] = inf
+		}
+		for i, k := range ks {
+			if d := msmDigit(k, w, c); d != 0 {
+				ctCurveAdd(&buckets[d-1], &buckets[d-1], &ps[i])
+			}
+		}
+		// sum(j·bucket[j-1]) = sum over j of the suffix sums of the buckets
+		sum := inf
+		for j := len(buckets) - 1; j >= 0; j-- {
+			ctCurveAdd(&sum, &sum, &buckets[j])
+			ctCurveAdd(&acc, &acc, &sum)
+		}
+	}
+
+	out := newCurvePoint(nil)
+	acc.affine(out)
+	return &G1{out}
+}
+
+// MultiScalarMultG2 returns sum(scalars[i]·points[i]), or nil if the lengths
+// of points and scalars differ. Scalars are reduced modulo Order and may be
+// negative.
+func MultiScalarMultG2(points []*G2, scalars []*big.Int) *G2 {
+	if len(points) != len(scalars) {
+		return nil
+	}
+	ks, negs, maxBits := msmDecompose(glsLattice, scalars)
+	c := msmWindow(len(ks), maxBits, mulCostG2*len(points))
+	if c == 0 {
+		ret := new(G2).SetInfinity()
+		for i, P := range points {
+			ret.Add(ret, new(G2).ScalarMult(P, scalars[i]))
+		}
+		ret.p.MakeAffine(nil)
+		return ret
+	}
+	ps := make([]ctTwistPoint, len(ks))
+	for i, P := range points {
+		ps[4*i] = *newCTTwistPoint(P.p)
+		for j := 1; j < 4; j++ {
+			ctTwistPsi(&ps[4*i+j], &ps[4*i+j-1])
+		}
+	}
+	for i := range ps {
+		ctTwistCneg(&ps[i], negs[i])
+	}
+
+	inf := ctTwistPoint{y: ctGFp2{y: *newGFpFromInt64(1)}}
+	acc := inf
+	buckets := make([]ctTwistPoint, 1<<uint(c)-1)
+	for w := (maxBits+c-1)/c - 1; w >= 0; w-- {
+		for i := 0; i < c; i++ {
+			ctTwistAdd(&acc, &acc, &acc)
+		}
+		for j := range buckets {
+			buckets[j] = inf
+		}
+		for i, k := range ks {
+			if d := msmDigit(k, w, c); d != 0 {
+				ctTwistAdd(&buckets[d-1], &buckets[d-1], &ps[i])
+			}
+		}
+		sum := inf
+		for j := len(buckets) - 1; j >= 0; j-- {
+			ctTwistAdd(&sum, &sum, &buckets[j])
+			ctTwistAdd(&acc, &acc, &sum)
+		}
+	}
+
+	out := newTwistPoint(nil)
+	acc.affine(out)
+	return &G2{out}
+}
+
+// MultiScalarMultGT returns prod(elems[i]^scalars[i]), or nil if the lengths
+// of elems and scalars differ, by the bucket method with multiplications in
+// place of additions. Scalars are reduced modulo Order and may be negative.
+func MultiScalarMultGT(elems []*GT, scalars []*big.Int) *GT {
+	if len(elems) != len(scalars) {
+		return nil
+	}
+	pool := new(bnPool)
+	ks := make([]*big.Int, len(scalars))
+	maxBits := 0
+	for i, k := range scalars {
+		ks[i] = new(big.Int).Mod(k, Order)
+		if n := ks[i].BitLen(); n > maxBits {
+			maxBits = n
+		}
+	}
+	ret := newGFp12(nil).SetOne()
+	// a separate exponentiation takes about 3/2 multiplications per bit
+	c := msmWindow(len(ks), maxBits, len(ks)*(maxBits+maxBits/2))
+	if c == 0 {
+		t := newGFp12(pool)
+		for i, a := range elems {
+			t.Exp(a.p, ks[i], pool)
+			ret.Mul(ret, t, pool)
+		}
+		t.Put(pool)
+		return &GT{ret}
+	}
+
+	buckets := make([]*gfP12, 1<<uint(c)-1)
+	for j := range buckets {
+		buckets[j] = newGFp12(nil)
+	}
+	sum := newGFp12(nil)
+	for w := (maxBits+c-1)/c - 1; w >= 0; w-- {
+		for i := 0; i < c; i++ {
+			ret.Square(ret, pool)
+		}
+		for j := range buckets {
+			buckets[j].SetOne()
+		}
+		for i, k := range ks {
+			if d := msmDigit(k, w, c); d != 0 {
+				buckets[d-1].Mul(buckets[d-1], elems[i].p, pool)
+			}
+		}
+		sum.SetOne()
+		for j := len(buckets) - 1; j >= 0; j-- {
+			sum.Mul(sum, buckets[j], pool)
+			ret.Mul(ret, sum, pool)
+		}
+	}
+	return &GT{ret}
+}
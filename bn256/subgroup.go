@@ -0,0 +1,56 @@
+package bn256
+
+import (
+	"math/big"
+)
+
+// sixuSquared is 6u² = p - Order, so p ≡ 6u² mod Order. The endomorphism ψ
+// acts on G₂ as multiplication by p.
+var sixuSquared = new(big.Int).Mul(big.NewInt(6), new(big.Int).Mul(u, u))
+
+// psi sets c to ψ(a), the untwist-Frobenius-twist endomorphism of the twist,
+// and returns c. a must be in affine form. See the computation of Q1 in miller.
+func (c *twistPoint) psi(a *twistPoint, pool *bnPool) *twistPoint {
+	c.x.Conjugate(a.x)
+	c.x.Mul(c.x, xiToPMinus1Over3, pool)
+	c.y.Conjugate(a.y)
+	c.y.Mul(c.y, xiToPMinus1Over2, pool)
+	c.z.SetOne()
+	c.t.SetOne()
+	return c
+}
+
+// inSubgroup returns true iff c, which must be on the twist, is in G₂, the
+// subgroup of order Order. It uses the test ψ(c) = 6u²·c of Dai, Lin and Zhao,
+// "Fast subgroup membership testings for G1, G2 and GT on pairing-friendly
+// curves", https://eprint.iacr.org/2022/348.pdf, which needs a 128-bit scalar
+// multiplication instead of a 256-bit one.
+func (c *twistPoint) inSubgroup(pool *bnPool) bool {
+	if c.IsInfinity() {
+		return true
+	}
+	a := newTwistPoint(pool)
+	a.Set(c)
+	a.MakeAffine(pool)
+	lhs := newTwistPoint(pool).psi(a, pool)
+	r := newCTTwistPoint(a)
+	ctTwistMulPublic(r, r, sixuSquared)
+	rhs := newTwistPoint(pool)
+	r.affine(rhs)
+	if rhs.IsInfinity() {
+		return false
+	}
+	lhs.x.Sub(lhs.x, rhs.x)
+	lhs.y.Sub(lhs.y, rhs.y)
+	lhs.x.Minimal()
+	lhs.y.Minimal()
+	return lhs.x.IsZero() && lhs.y.IsZero()
+}
+
+// inSubgroup returns true iff e is in GT, the subgroup of order Order of the
+// cyclotomic subgroup of GF(p¹²)*, that is iff e^Order = 1. Since GF(p¹²)* is
+// cyclic, this subgroup is unique.
+func (e *gfP12) inSubgroup(pool *bnPool) bool {
+	t := newGFp12(pool).Exp(e, Order, pool)
+	return t.IsOne()
+}
@@ -199,23 +199,13 @@ func (c *twistPoint) Double(a *twistPoint, pool *bnPool) {
 	f.Put(pool)
 }
 
+// Mul sets c to scalar·a using the GLS endomorphism, so a must be in G₂;
+// points of the twist outside G₂ are multiplied with ctTwistMulPublic. The
+// point arithmetic takes time independent of a and scalar.
 func (c *twistPoint) Mul(a *twistPoint, scalar *big.Int, pool *bnPool) *twistPoint {
-	sum := newTwistPoint(pool)
-	sum.SetInfinity()
-	t := newTwistPoint(pool)
-
-	for i := scalar.BitLen(); i >= 0; i-- {
-		t.Double(sum, pool)
-		if scalar.Bit(i) != 0 {
-			sum.Add(t, a, pool)
-		} else {
-			sum.Set(t)
-		}
-	}
-
-	c.Set(sum)
-	sum.Put(pool)
-	t.Put(pool)
+	r := newCTTwistPoint(a)
+	ctTwistMulGLS(r, r, scalar)
+	r.affine(c)
 	return c
 }
 
@@ -5,6 +5,7 @@ package brs
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -31,71 +32,130 @@ type Signature struct {
 }
 
 /*
-ring: [[0,1,2],[1,2,3]] means {P0 or P1 or P2} and {P1 or P2 or P3}
-P1 can make a valid signature; P2 can make a valid signature; P0 and P3 can together make a valid signature
-xid: id of signer at each ring [1,1] means P1 signs for both sub-ring; [0,3] means P0 signs subring1 and P3 signs subring3
-N: total number of signers in the ring, 4
+NewSigner builds the SignerParams from caller-supplied rings and keys.
+rings[i] is the i-th sub-ring of public keys, indexes[i] is the position of the
+signer's key in rings[i] and privs[i] is the matching private key.
 */
-func initRing(ring [][]int64, xid []int64, N int64) (*SignerParams, *VerifierParams, error) {
-	var i int64
-	var err error
+func NewSigner(rings [][]*btcec.PublicKey, indexes []int, privs []*btcec.PrivateKey) (*SignerParams, error) {
+	length, err := ringLength(rings)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) != len(rings) || len(privs) != len(rings) {
+		return nil, errors.New("number of indexes and private keys must match the number of rings")
+	}
 
-	//initialize curve
 	curve := btcec.S256()
+	index := make([]int64, len(rings))
+	x := make([]*btcec.PrivateKey, len(rings))
+	for i := range rings {
+		if indexes[i] < 0 || indexes[i] >= len(rings[i]) {
+			return nil, fmt.Errorf("index %d is out of range for ring %d", indexes[i], i)
+		}
+		if privs[i] == nil {
+			return nil, fmt.Errorf("missing private key for ring %d", i)
+		}
+		pub := privs[i].PubKey()
+		P := rings[i][indexes[i]]
+		if pub.X.Cmp(P.X) != 0 || pub.Y.Cmp(P.Y) != 0 {
+			return nil, fmt.Errorf("private key does not match public key %d of ring %d", indexes[i], i)
+		}
+		index[i] = int64(indexes[i])
+		x[i] = privs[i]
+	}
 
-	//initialize SignerPrams
-	signer := &SignerParams{
-		curve: curve,
+	return &SignerParams{
+		curve:   curve,
+		pubkey:  rings,
+		length:  length,
+		index:   index,
+		privkey: x,
+	}, nil
+}
+
+/*
+NewVerifier builds the VerifierParams for the given rings of public keys.
+*/
+func NewVerifier(rings [][]*btcec.PublicKey) (*VerifierParams, error) {
+	length, err := ringLength(rings)
+	if err != nil {
+		return nil, err
 	}
+	return &VerifierParams{
+		curve:  btcec.S256(),
+		pubkey: rings,
+		length: length,
+	}, nil
+}
 
-	//initialize VerifierParams
-	verifier := &VerifierParams{
-		curve: curve,
+/*
+ringLength checks the shape of rings and returns the length of each sub-ring.
+*/
+func ringLength(rings [][]*btcec.PublicKey) ([]int64, error) {
+	if len(rings) == 0 {
+		return nil, errors.New("at least one ring is required")
 	}
+	length := make([]int64, len(rings))
+	for i := range rings {
+		if len(rings[i]) == 0 {
+			return nil, fmt.Errorf("ring %d is empty", i)
+		}
+		for j := range rings[i] {
+			if rings[i][j] == nil {
+				return nil, fmt.Errorf("missing public key %d of ring %d", j, i)
+			}
+		}
+		length[i] = int64(len(rings[i]))
+	}
+	return length, nil
+}
 
-	//initialize P, len, index
-	L := len(ring)
-	P := make([][]*btcec.PublicKey, L)
-	length := make([]int64, L)
-	index := make([]int64, L)
-	x := make([]*btcec.PrivateKey, L)
+/*
+ring: [[0,1,2],[1,2,3]] means {P0 or P1 or P2} and {P1 or P2 or P3}
+P1 can make a valid signature; P2 can make a valid signature; P0 and P3 can together make a valid signature
+xid: id of signer at each ring [1,1] means P1 signs for both sub-ring; [0,3] means P0 signs subring1 and P3 signs subring3
+N: total number of signers in the ring, 4
+*/
+func initRing(ring [][]int64, xid []int64, N int64) (*SignerParams, *VerifierParams, error) {
+	var i int64
+	var err error
 
 	//initialize N public keys
+	curve := btcec.S256()
 	priv := make([]*btcec.PrivateKey, N)
 	pub := make([]*btcec.PublicKey, N)
 	for i = 0; i < N; i++ {
 		priv[i], err = btcec.NewPrivateKey(curve)
 		if err != nil {
-			fmt.Errorf("error generating private key")
-			return signer, verifier, err
+			return nil, nil, err
 		}
 		pub[i] = priv[i].PubKey()
 	}
 
-	//calculate P, len and index based on ring
+	//calculate P, index and x based on ring
+	P := make([][]*btcec.PublicKey, len(ring))
+	index := make([]int, len(ring))
+	x := make([]*btcec.PrivateKey, len(ring))
 	for j := range ring {
-		length[j] = int64(len(ring[j]))
 		P[j] = make([]*btcec.PublicKey, len(ring[j]))
-
 		for k := range ring[j] {
 			P[j][k] = pub[ring[j][k]]
 			if ring[j][k] == xid[j] {
-				index[j] = int64(k)
+				index[j] = k
 				x[j] = priv[xid[j]]
 			}
 		}
 	}
 
-	signer.index = index
-	signer.length = length
-	signer.pubkey = P
-	signer.privkey = x
-
-	verifier.length = length
-	verifier.pubkey = P
-
+	signer, err := NewSigner(P, index, x)
+	if err != nil {
+		return nil, nil, err
+	}
+	verifier, err := NewVerifier(P)
+	if err != nil {
+		return nil, nil, err
+	}
 	return signer, verifier, nil
-
 }
 
 func (signer *SignerParams) Sign(msg []byte) *Signature {
@@ -227,6 +287,9 @@ func (signer *SignerParams) Sign(msg []byte) *Signature {
 
 func (verifier *VerifierParams) Verify(msg []byte, sig *Signature) bool {
 	L := len(verifier.pubkey)
+	if !sig.hasShape(verifier.length) {
+		return false
+	}
 
 	//initialize e
 	e := make([][]*big.Int, L)
@@ -293,3 +356,23 @@ func (verifier *VerifierParams) Verify(msg []byte, sig *Signature) bool {
 	}
 	return false
 }
+
+/*
+hasShape returns true iff the signature has one complete scalar per ring member.
+*/
+func (sig *Signature) hasShape(length []int64) bool {
+	if sig == nil || sig.e0 == nil || len(sig.s) != len(length) {
+		return false
+	}
+	for i := range sig.s {
+		if int64(len(sig.s[i])) != length[i] {
+			return false
+		}
+		for j := range sig.s[i] {
+			if sig.s[i][j] == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -2,6 +2,8 @@ package brs
 
 import (
 	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
 )
 
 func TestSignature(t *testing.T) {
@@ -41,3 +43,48 @@ func TestSignature(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestNewSigner(t *testing.T) {
+	curve := btcec.S256()
+	priv := make([]*btcec.PrivateKey, 4)
+	pub := make([]*btcec.PublicKey, 4)
+	for i := range priv {
+		priv[i], _ = btcec.NewPrivateKey(curve)
+		pub[i] = priv[i].PubKey()
+	}
+	rings := [][]*btcec.PublicKey{
+		[]*btcec.PublicKey{pub[0], pub[1], pub[2]},
+		[]*btcec.PublicKey{pub[1], pub[2], pub[3]},
+	}
+
+	//P0 and P3 sign with their own keys
+	signer, err := NewSigner(rings, []int{0, 2}, []*btcec.PrivateKey{priv[0], priv[3]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVerifier(rings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := signer.Sign([]byte("ddd"))
+	if !verifier.Verify([]byte("ddd"), signature) {
+		t.Errorf("Signature verification failed")
+	}
+
+	//private key does not match the public key at index
+	if _, err := NewSigner(rings, []int{0, 1}, []*btcec.PrivateKey{priv[0], priv[3]}); err == nil {
+		t.Errorf("expected error for mismatched private key")
+	}
+	//index out of range
+	if _, err := NewSigner(rings, []int{0, 3}, []*btcec.PrivateKey{priv[0], priv[3]}); err == nil {
+		t.Errorf("expected error for index out of range")
+	}
+	//dimension mismatch
+	if _, err := NewSigner(rings, []int{0}, []*btcec.PrivateKey{priv[0]}); err == nil {
+		t.Errorf("expected error for dimension mismatch")
+	}
+	//empty ring
+	if _, err := NewVerifier([][]*btcec.PublicKey{rings[0], {}}); err == nil {
+		t.Errorf("expected error for empty ring")
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Implements the linkable ring signature LSAG in its compact form, with key images
+*/
+package brs
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// KeyImageDST is the domain-separation tag of the hash Hp used for key images.
+const KeyImageDST = "blockchain-research/crypto/brs/key-image/v1"
+
+/*
+LinkableSigner signs for a single ring with a key image I = xHp(P), so that
+two signatures by the same key can be linked.
+*/
+type LinkableSigner struct {
+	curve   *btcec.KoblitzCurve
+	pubkey  []*btcec.PublicKey //P0...Pn-1
+	index   int                //the index of x's pubkey in P
+	privkey *btcec.PrivateKey  //user's private key at index
+}
+
+/*
+LinkableSignature is (c0, s0...sn-1, I).
+*/
+type LinkableSignature struct {
+	c0 *big.Int
+	s  []*big.Int
+	I  []*big.Int
+}
+
+/*
+NewLinkableSigner builds a LinkableSigner for the ring of public keys, where
+priv is the private key of ring[index].
+*/
+func NewLinkableSigner(ring []*btcec.PublicKey, index int, priv *btcec.PrivateKey) (*LinkableSigner, error) {
+	signer, err := NewSigner([][]*btcec.PublicKey{ring}, []int{index}, []*btcec.PrivateKey{priv})
+	if err != nil {
+		return nil, err
+	}
+	return &LinkableSigner{
+		curve:   signer.curve,
+		pubkey:  ring,
+		index:   index,
+		privkey: priv,
+	}, nil
+}
+
+/*
+KeyImage returns I = xHp(P) for the private key x of the public key P.
+*/
+func KeyImage(priv *btcec.PrivateKey) []*big.Int {
+	curve := btcec.S256()
+	hx, hy := hashPoint(priv.PubKey())
+	Ix, Iy := curve.ScalarMult(hx, hy, priv.D.Bytes())
+	return []*big.Int{Ix, Iy}
+}
+
+/*
+Sign signs msg for the ring. For the signer at index pi:
+L_pi = aG, R_pi = aHp(P_pi), c_pi+1 = H(M || L_pi || R_pi);
+for the other members i, with random s_i,
+L_i = s_iG + c_iP_i, R_i = s_iHp(P_i) + c_iI, c_i+1 = H(M || L_i || R_i);
+and finally s_pi = a - c_pi*x.
+*/
+func (signer *LinkableSigner) Sign(msg []byte) (*LinkableSignature, error) {
+	n := len(signer.pubkey)
+	N := signer.curve.N
+	M := HashMsgVerificationKey(msg, signer.pubkey)
+	I := KeyImage(signer.privkey)
+
+	c := make([]*big.Int, n)
+	s := make([]*big.Int, n)
+
+	a, err := rand.Int(rand.Reader, N)
+	if err != nil {
+		return nil, err
+	}
+	Lx, Ly := signer.curve.ScalarBaseMult(a.Bytes())
+	hx, hy := hashPoint(signer.pubkey[signer.index])
+	Rx, Ry := signer.curve.ScalarMult(hx, hy, a.Bytes())
+	c[(signer.index+1)%n] = linkableChallenge(M, Lx, Ly, Rx, Ry)
+
+	for k := 1; k < n; k++ {
+		i := (signer.index + k) % n
+		s[i], err = rand.Int(rand.Reader, N)
+		if err != nil {
+			return nil, err
+		}
+		c[(i+1)%n] = signer.step(M, signer.pubkey[i], I, s[i], c[i])
+	}
+
+	//s_pi = a - c_pi*x
+	cx := new(big.Int).Mul(c[signer.index], signer.privkey.D)
+	s[signer.index] = new(big.Int).Sub(a, cx)
+	s[signer.index].Mod(s[signer.index], N)
+
+	return &LinkableSignature{
+		c0: c[0],
+		s:  s,
+		I:  I,
+	}, nil
+}
+
+/*
+VerifyLinkable checks sig on msg for the ring by recomputing c_1...c_n from c0
+and checking c_n = c0.
+*/
+func VerifyLinkable(ring []*btcec.PublicKey, msg []byte, sig *LinkableSignature) bool {
+	if _, err := ringLength([][]*btcec.PublicKey{ring}); err != nil {
+		return false
+	}
+	curve := btcec.S256()
+	if sig == nil || sig.c0 == nil || len(sig.s) != len(ring) || len(sig.I) != 2 ||
+		sig.I[0] == nil || sig.I[1] == nil || !curve.IsOnCurve(sig.I[0], sig.I[1]) {
+		return false
+	}
+	verifier := &LinkableSigner{curve: curve, pubkey: ring}
+	M := HashMsgVerificationKey(msg, ring)
+
+	c := sig.c0
+	for i := range ring {
+		if sig.s[i] == nil {
+			return false
+		}
+		c = verifier.step(M, ring[i], sig.I, sig.s[i], c)
+	}
+	return c.Cmp(sig.c0) == 0
+}
+
+/*
+Link returns true iff both signatures were made with the same private key.
+It only compares the key images and does not verify the signatures, which
+carry neither their ring nor their message; callers must check both with
+VerifyLinkable first, as anyone can copy a key image into a forged signature.
+*/
+func Link(sigA, sigB *LinkableSignature) bool {
+	if sigA == nil || sigB == nil || len(sigA.I) != 2 || len(sigB.I) != 2 ||
+		sigA.I[0] == nil || sigA.I[1] == nil || sigB.I[0] == nil || sigB.I[1] == nil {
+		return false
+	}
+	return sigA.I[0].Cmp(sigB.I[0]) == 0 && sigA.I[1].Cmp(sigB.I[1]) == 0
+}
+
+/*
+KeyImage returns the key image I of the signature.
+*/
+func (sig *LinkableSignature) KeyImage() []*big.Int {
+	return sig.I
+}
+
+/*
+step computes c_i+1 = H(M || s_iG+c_iP_i || s_iHp(P_i)+c_iI).
+*/
+func (signer *LinkableSigner) step(M *big.Int, P *btcec.PublicKey, I []*big.Int, s, c *big.Int) *big.Int {
+	curve := signer.curve
+	sGx, sGy := curve.ScalarBaseMult(s.Bytes())
+	cPx, cPy := curve.ScalarMult(P.X, P.Y, c.Bytes())
+	Lx, Ly := curve.Add(sGx, sGy, cPx, cPy)
+
+	hx, hy := hashPoint(P)
+	sHx, sHy := curve.ScalarMult(hx, hy, s.Bytes())
+	cIx, cIy := curve.ScalarMult(I[0], I[1], c.Bytes())
+	Rx, Ry := curve.Add(sHx, sHy, cIx, cIy)
+	return linkableChallenge(M, Lx, Ly, Rx, Ry)
+}
+
+/*
+hashPoint computes Hp(P) = HashToCurve(P, KeyImageDST).
+*/
+func hashPoint(P *btcec.PublicKey) (*big.Int, *big.Int) {
+	return HashToCurve(P.SerializeCompressed(), []byte(KeyImageDST))
+}
+
+func linkableChallenge(M, Lx, Ly, Rx, Ry *big.Int) *big.Int {
+	c := HashBigInt([]*big.Int{M, Lx, Ly, Rx, Ry})
+	return c.Mod(c, btcec.S256().N)
+}
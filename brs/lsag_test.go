@@ -0,0 +1,87 @@
+package brs
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+func linkableRing(n int) ([]*btcec.PrivateKey, []*btcec.PublicKey) {
+	priv := make([]*btcec.PrivateKey, n)
+	pub := make([]*btcec.PublicKey, n)
+	for i := range priv {
+		priv[i], _ = btcec.NewPrivateKey(btcec.S256())
+		pub[i] = priv[i].PubKey()
+	}
+	return priv, pub
+}
+
+func TestLinkableSignature(t *testing.T) {
+	priv, ring := linkableRing(4)
+	signer, err := NewLinkableSigner(ring, 2, priv[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signer.Sign([]byte("ddd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyLinkable(ring, []byte("ddd"), sig) {
+		t.Errorf("Signature verification failed")
+	}
+	if VerifyLinkable(ring, []byte("eee"), sig) {
+		t.Errorf("Signature verified for another message")
+	}
+	if VerifyLinkable(ring[:3], []byte("ddd"), sig) {
+		t.Errorf("Signature verified for another ring")
+	}
+
+	//a ring of one
+	single, err := NewLinkableSigner(ring[:1], 0, priv[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig1, err := single.Sign([]byte("ddd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyLinkable(ring[:1], []byte("ddd"), sig1) {
+		t.Errorf("Signature verification failed for a ring of one")
+	}
+
+	if _, err := NewLinkableSigner(ring, 1, priv[2]); err == nil {
+		t.Errorf("expected error for mismatched private key")
+	}
+}
+
+func TestLink(t *testing.T) {
+	priv, ring := linkableRing(3)
+	signer, _ := NewLinkableSigner(ring, 1, priv[1])
+	sigA, _ := signer.Sign([]byte("ddd"))
+	sigB, _ := signer.Sign([]byte("eee"))
+
+	//the same key signing in another ring has the same key image
+	_, other := linkableRing(2)
+	ring2 := []*btcec.PublicKey{other[0], ring[1], other[1]}
+	signer2, _ := NewLinkableSigner(ring2, 1, priv[1])
+	sigC, _ := signer2.Sign([]byte("fff"))
+	if !VerifyLinkable(ring2, []byte("fff"), sigC) {
+		t.Errorf("Signature verification failed")
+	}
+
+	if !Link(sigA, sigB) || !Link(sigA, sigC) {
+		t.Errorf("Signatures by the same key were not linked")
+	}
+
+	signer3, _ := NewLinkableSigner(ring, 0, priv[0])
+	sigD, _ := signer3.Sign([]byte("ddd"))
+	if Link(sigA, sigD) {
+		t.Errorf("Signatures by different keys were linked")
+	}
+
+	//the key image cannot be swapped for another one
+	sigD.I = sigA.KeyImage()
+	if VerifyLinkable(ring, []byte("ddd"), sigD) {
+		t.Errorf("Signature verified with a foreign key image")
+	}
+}
@@ -0,0 +1,456 @@
+package brs
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+/*
+Wire format
+
+All integers are big-endian. Scalars are encoded as fixed 32-byte values and
+curve points as 33-byte compressed secp256k1 points.
+
+Signature:
+
+	version  (1 byte)
+	L        (uint32)          number of rings
+	len[i]   (uint32 * L)      length of each ring
+	e0       (32 bytes)
+	s[i][j]  (32 bytes each)   for 0<=i<L, 0<=j<len[i]
+
+ProofUL:
+
+	version  (1 byte)
+	H        (1 byte)          generator version
+	u        (uint32)
+	l        (uint32)
+	e0       (32 bytes)
+	C[i]     (33 bytes * l)
+	s[i][j]  (32 bytes each)   for 0<=i<l, 0<=j<u
+
+LinkableSignature:
+
+	version  (1 byte)
+	n        (uint32)          ring size
+	c0       (32 bytes)
+	I        (33 bytes)        key image
+	s[i]     (32 bytes each)   for 0<=i<n
+
+Proof:
+
+	len      (uint32)          length of the encoded proof1
+	proof1   (ProofUL)
+	proof2   (ProofUL)
+
+m is not encoded; it is recomputed from u and l when decoding.
+*/
+const (
+	scalarLen = 32
+	pointLen  = 33
+	uint32Len = 4
+
+	signatureVersion         byte = 1
+	proofULVersion           byte = 1
+	linkableSignatureVersion byte = 1
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Signature)(nil)
+	_ encoding.BinaryUnmarshaler = (*Signature)(nil)
+	_ encoding.BinaryMarshaler   = (*LinkableSignature)(nil)
+	_ encoding.BinaryUnmarshaler = (*LinkableSignature)(nil)
+	_ encoding.BinaryMarshaler   = (*ProofUL)(nil)
+	_ encoding.BinaryUnmarshaler = (*ProofUL)(nil)
+	_ encoding.BinaryMarshaler   = (*Proof)(nil)
+	_ encoding.BinaryUnmarshaler = (*Proof)(nil)
+)
+
+/*
+MarshalBinary encodes the signature into its canonical binary form.
+*/
+func (sig *Signature) MarshalBinary() ([]byte, error) {
+	if sig.e0 == nil || len(sig.s) == 0 {
+		return nil, errors.New("brs: incomplete signature")
+	}
+	ret := []byte{signatureVersion}
+	ret = appendUint32(ret, len(sig.s))
+	for i := range sig.s {
+		ret = appendUint32(ret, len(sig.s[i]))
+	}
+	ret = append(ret, hashBytes(sig.e0)...)
+	for i := range sig.s {
+		for j := range sig.s[i] {
+			b, err := scalarBytes(sig.s[i][j])
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, b...)
+		}
+	}
+	return ret, nil
+}
+
+/*
+UnmarshalBinary decodes a signature produced by MarshalBinary. Every scalar
+must be in [0, N).
+*/
+func (sig *Signature) UnmarshalBinary(data []byte) error {
+	r := &reader{data: data}
+	if v := r.byte(); v != signatureVersion {
+		if r.err != nil {
+			return r.err
+		}
+		return fmt.Errorf("brs: unsupported signature version %d", v)
+	}
+	L := r.uint32()
+	if r.err == nil && (L == 0 || uint64(L)*uint32Len > uint64(r.remaining())) {
+		return errors.New("brs: invalid number of rings")
+	}
+	length := make([]int, L)
+	total := uint64(0)
+	for i := range length {
+		length[i] = int(r.uint32())
+		if r.err == nil && length[i] == 0 {
+			return fmt.Errorf("brs: ring %d is empty", i)
+		}
+		total += uint64(length[i])
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if uint64(r.remaining()) != scalarLen+total*scalarLen {
+		return errors.New("brs: signature length does not match ring shape")
+	}
+
+	e0 := r.hash()
+	s := make([][]*big.Int, L)
+	for i := range s {
+		s[i] = make([]*big.Int, length[i])
+		for j := range s[i] {
+			s[i][j] = r.scalar()
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	sig.e0 = e0
+	sig.s = s
+	return nil
+}
+
+/*
+MarshalBinary encodes the linkable signature into its canonical binary form.
+*/
+func (sig *LinkableSignature) MarshalBinary() ([]byte, error) {
+	if len(sig.s) == 0 {
+		return nil, errors.New("brs: incomplete signature")
+	}
+	ret := []byte{linkableSignatureVersion}
+	ret = appendUint32(ret, len(sig.s))
+	c0, err := scalarBytes(sig.c0)
+	if err != nil {
+		return nil, err
+	}
+	ret = append(ret, c0...)
+	I, err := pointBytes(sig.I)
+	if err != nil {
+		return nil, err
+	}
+	ret = append(ret, I...)
+	for i := range sig.s {
+		b, err := scalarBytes(sig.s[i])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, b...)
+	}
+	return ret, nil
+}
+
+/*
+UnmarshalBinary decodes a linkable signature produced by MarshalBinary. Every
+scalar must be in [0, N) and the key image a valid compressed point.
+*/
+func (sig *LinkableSignature) UnmarshalBinary(data []byte) error {
+	r := &reader{data: data}
+	if v := r.byte(); v != linkableSignatureVersion {
+		if r.err != nil {
+			return r.err
+		}
+		return fmt.Errorf("brs: unsupported signature version %d", v)
+	}
+	n := uint64(r.uint32())
+	if r.err != nil {
+		return r.err
+	}
+	if n == 0 || uint64(r.remaining()) != scalarLen+pointLen+n*scalarLen {
+		return errors.New("brs: signature length does not match ring size")
+	}
+
+	c0 := r.scalar()
+	I := r.point()
+	s := make([]*big.Int, n)
+	for i := range s {
+		s[i] = r.scalar()
+	}
+	if r.err != nil {
+		return r.err
+	}
+	sig.c0 = c0
+	sig.I = I
+	sig.s = s
+	return nil
+}
+
+/*
+MarshalBinary encodes the range proof into its canonical binary form.
+*/
+func (proof *ProofUL) MarshalBinary() ([]byte, error) {
+	l := len(proof.C)
+	if proof.e0 == nil || l == 0 || len(proof.s) != l {
+		return nil, errors.New("brs: incomplete proof")
+	}
+	u := len(proof.s[0])
+	ret := []byte{proofULVersion, byte(proof.version)}
+	ret = appendUint32(ret, u)
+	ret = appendUint32(ret, l)
+	ret = append(ret, hashBytes(proof.e0)...)
+	for i := range proof.C {
+		b, err := pointBytes(proof.C[i])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, b...)
+	}
+	for i := range proof.s {
+		if len(proof.s[i]) != u {
+			return nil, errors.New("brs: incomplete proof")
+		}
+		for j := 0; j < u; j++ {
+			b, err := scalarBytes(proof.s[i][j])
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, b...)
+		}
+	}
+	return ret, nil
+}
+
+/*
+UnmarshalBinary decodes a range proof produced by MarshalBinary. Every scalar
+must be in [0, N) and every commitment must be a valid compressed point.
+*/
+func (proof *ProofUL) UnmarshalBinary(data []byte) error {
+	r := &reader{data: data}
+	if v := r.byte(); v != proofULVersion {
+		if r.err != nil {
+			return r.err
+		}
+		return fmt.Errorf("brs: unsupported proof version %d", v)
+	}
+	version := GeneratorVersion(r.byte())
+	if r.err == nil && version != GeneratorLegacy && version != GeneratorNUMS {
+		return fmt.Errorf("brs: unknown generator version %d", version)
+	}
+	u := uint64(r.uint32())
+	l := uint64(r.uint32())
+	if r.err != nil {
+		return r.err
+	}
+	if u < 2 || l == 0 || l > uint64(r.remaining()) {
+		return errors.New("brs: invalid proof base or length")
+	}
+	rem := uint64(r.remaining())
+	if rem < scalarLen+l*pointLen {
+		return errors.New("brs: proof length does not match its shape")
+	}
+	if body := rem - scalarLen - l*pointLen; body%(l*scalarLen) != 0 || body/(l*scalarLen) != u {
+		return errors.New("brs: proof length does not match its shape")
+	}
+
+	e0 := r.hash()
+	C := make([][]*big.Int, l)
+	for i := range C {
+		C[i] = r.point()
+	}
+	s := make([][]*big.Int, l)
+	for i := range s {
+		s[i] = make([]*big.Int, u)
+		for j := range s[i] {
+			s[i][j] = r.scalar()
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	_, m := GetBaseRepresentation(new(big.Int), int64(u), int64(l))
+
+	proof.version = version
+	proof.e0 = e0
+	proof.C = C
+	proof.s = s
+	proof.m = m
+	return nil
+}
+
+/*
+MarshalBinary encodes both halves of the [min,max) range proof.
+*/
+func (proof *Proof) MarshalBinary() ([]byte, error) {
+	if proof.proof1 == nil || proof.proof2 == nil {
+		return nil, errors.New("brs: incomplete proof")
+	}
+	first, err := proof.proof1.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	second, err := proof.proof2.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	ret := appendUint32(nil, len(first))
+	ret = append(ret, first...)
+	return append(ret, second...), nil
+}
+
+/*
+UnmarshalBinary decodes a range proof produced by MarshalBinary.
+*/
+func (proof *Proof) UnmarshalBinary(data []byte) error {
+	r := &reader{data: data}
+	n := r.uint32()
+	if r.err != nil {
+		return r.err
+	}
+	if uint64(n) > uint64(r.remaining()) {
+		return errors.New("brs: unexpected end of data")
+	}
+	first, second := &ProofUL{}, &ProofUL{}
+	if err := first.UnmarshalBinary(r.data[:n]); err != nil {
+		return err
+	}
+	if err := second.UnmarshalBinary(r.data[n:]); err != nil {
+		return err
+	}
+	proof.proof1 = first
+	proof.proof2 = second
+	return nil
+}
+
+func appendUint32(b []byte, v int) []byte {
+	var buf [uint32Len]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(v))
+	return append(b, buf[:]...)
+}
+
+// hashBytes encodes a hash output, which is not reduced modulo N.
+func hashBytes(e *big.Int) []byte {
+	ret := make([]byte, scalarLen)
+	b := e.Bytes()
+	copy(ret[scalarLen-len(b):], b)
+	return ret
+}
+
+func scalarBytes(k *big.Int) ([]byte, error) {
+	if k == nil || k.Sign() < 0 || k.Cmp(btcec.S256().N) >= 0 {
+		return nil, errors.New("brs: scalar out of range")
+	}
+	ret := make([]byte, scalarLen)
+	b := k.Bytes()
+	copy(ret[scalarLen-len(b):], b)
+	return ret, nil
+}
+
+func pointBytes(P []*big.Int) ([]byte, error) {
+	if len(P) != 2 || P[0] == nil || P[1] == nil || !btcec.S256().IsOnCurve(P[0], P[1]) {
+		return nil, errors.New("brs: invalid curve point")
+	}
+	pub := &btcec.PublicKey{Curve: btcec.S256(), X: P[0], Y: P[1]}
+	return pub.SerializeCompressed(), nil
+}
+
+/*
+reader consumes data front to back and records the first decoding error.
+*/
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) remaining() int {
+	return len(r.data)
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = errors.New("brs: unexpected end of data")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.next(uint32Len)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) hash() *big.Int {
+	b := r.next(scalarLen)
+	if b == nil {
+		return nil
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+func (r *reader) scalar() *big.Int {
+	k := r.hash()
+	if k != nil && k.Cmp(btcec.S256().N) >= 0 {
+		r.err = errors.New("brs: scalar out of range")
+		return nil
+	}
+	return k
+}
+
+func (r *reader) point() []*big.Int {
+	b := r.next(pointLen)
+	if b == nil {
+		return nil
+	}
+	if b[0] != 0x02 && b[0] != 0x03 {
+		r.err = errors.New("brs: invalid point encoding")
+		return nil
+	}
+	if new(big.Int).SetBytes(b[1:]).Cmp(btcec.S256().P) >= 0 {
+		r.err = errors.New("brs: point coordinate out of range")
+		return nil
+	}
+	pub, err := btcec.ParsePubKey(b, btcec.S256())
+	if err != nil {
+		r.err = fmt.Errorf("brs: invalid point: %v", err)
+		return nil
+	}
+	return []*big.Int{pub.X, pub.Y}
+}
@@ -0,0 +1,129 @@
+package brs
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestSignatureMarshal(t *testing.T) {
+	ring := [][]int64{[]int64{0, 1, 2}, []int64{1, 2, 3}}
+	signer, verifier, err := initRing(ring, []int64{0, 3}, 4)
+	if err != nil {
+		t.FailNow()
+	}
+	signature := signer.Sign([]byte("ddd"))
+
+	data, err := signature.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1+4+2*4+32+6*32 {
+		t.Errorf("unexpected signature length %d", len(data))
+	}
+	signature2 := &Signature{}
+	if err := signature2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !verifier.Verify([]byte("ddd"), signature2) {
+		t.Errorf("Signature verification failed after unmarshal")
+	}
+
+	//truncated data must be rejected without panicking
+	for i := 0; i < len(data); i++ {
+		if err := new(Signature).UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("expected error for truncated signature of length %d", i)
+		}
+	}
+
+	//scalar >= N must be rejected
+	bad := append([]byte{}, data...)
+	for i := len(bad) - 32; i < len(bad); i++ {
+		bad[i] = 0xff
+	}
+	if err := new(Signature).UnmarshalBinary(bad); err == nil {
+		t.Errorf("expected error for out of range scalar")
+	}
+
+	//a signature for a different ring shape must not verify
+	signer2, _, err := initRing([][]int64{[]int64{0, 1}}, []int64{0}, 2)
+	if err != nil {
+		t.FailNow()
+	}
+	if verifier.Verify([]byte("ddd"), signer2.Sign([]byte("ddd"))) {
+		t.Errorf("Signature with wrong shape verified")
+	}
+}
+
+func TestProofULMarshal(t *testing.T) {
+	brs := SetupUL(10, 3)
+	value, _ := rand.Int(rand.Reader, new(big.Int).SetInt64(1000))
+	proof, rsum := brs.ProveUL(value)
+	cmx, cmy := Commit(value, rsum, brs.hx, brs.hy)
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2+4+4+32+3*33+3*10*32 {
+		t.Errorf("unexpected proof length %d", len(data))
+	}
+	proof2 := &ProofUL{}
+	if err := proof2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !brs.VerifyUL(proof2, cmx, cmy) {
+		t.Errorf("Proof verification failed after unmarshal")
+	}
+
+	//truncated data must be rejected without panicking
+	for i := 0; i < len(data); i++ {
+		if err := new(ProofUL).UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("expected error for truncated proof of length %d", i)
+		}
+	}
+
+	//a commitment that is not on the curve must be rejected
+	bad := append([]byte{}, data...)
+	bad[2+4+4+32] = 0x04
+	if err := new(ProofUL).UnmarshalBinary(bad); err == nil {
+		t.Errorf("expected error for invalid point prefix")
+	}
+
+	//a proof for another base must not verify
+	if SetupUL(10, 2).VerifyUL(proof2, cmx, cmy) {
+		t.Errorf("Proof with wrong shape verified")
+	}
+}
+
+func TestLinkableSignatureMarshal(t *testing.T) {
+	priv, ring := linkableRing(3)
+	signer, err := NewLinkableSigner(ring, 0, priv[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signer.Sign([]byte("ddd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1+4+32+33+3*32 {
+		t.Errorf("unexpected signature length %d", len(data))
+	}
+	sig2 := &LinkableSignature{}
+	if err := sig2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyLinkable(ring, []byte("ddd"), sig2) || !Link(sig, sig2) {
+		t.Errorf("Signature verification failed after unmarshal")
+	}
+
+	for i := 0; i < len(data); i++ {
+		if err := new(LinkableSignature).UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("expected error for truncated signature of length %d", i)
+		}
+	}
+}
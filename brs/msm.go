@@ -0,0 +1,404 @@
+package brs
+
+import (
+	"math/big"
+	"math/bits"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+/*
+fieldElement is an element of GF(P) for the secp256k1 prime P in Montgomery
+form, a*R mod P with R = 2^256, stored as four little-endian 64-bit limbs.
+btcec's Add and Double take and return affine big.Int points and so pay an
+inversion per call, which the many additions of MultiScalarMult cannot afford.
+*/
+type fieldElement [4]uint64
+
+var (
+	fieldP  = limbs(btcec.S256().P)
+	fieldNP = func() uint64 {
+		mod := new(big.Int).Lsh(big.NewInt(1), 64)
+		inv := new(big.Int).ModInverse(new(big.Int).Mod(btcec.S256().P, mod), mod)
+		return -inv.Uint64()
+	}()
+	fieldR2  = fieldElement(limbs(new(big.Int).Mod(new(big.Int).Lsh(big.NewInt(1), 512), btcec.S256().P)))
+	fieldOne = newFieldElement(big.NewInt(1))
+)
+
+/*
+jacobianPoint is x/z^2, y/z^3 on secp256k1, or the point at infinity if z = 0.
+*/
+type jacobianPoint struct {
+	x, y, z fieldElement
+}
+
+/*
+affinePoint is a finite point (x, y) on secp256k1.
+*/
+type affinePoint struct {
+	x, y fieldElement
+}
+
+/*
+MultiScalarMult returns sum(scalars[i]*points[i]) on secp256k1 with the bucket
+method of Pippenger, or (nil, nil) if the lengths of points and scalars differ.
+Points are (x, y) pairs with (0, 0) for the point at infinity, as in btcec.
+Scalars are reduced modulo N and may be negative. The running time depends on
+the scalars, which must be public.
+*/
+func MultiScalarMult(points [][]*big.Int, scalars []*big.Int) (*big.Int, *big.Int) {
+	if len(points) != len(scalars) {
+		return nil, nil
+	}
+	curve := btcec.S256()
+	var ps []*affinePoint
+	var ks []*big.Int
+	maxBits := 0
+	for i, P := range points {
+		k := new(big.Int).Mod(scalars[i], curve.N)
+		if k.Sign() == 0 || (P[0].Sign() == 0 && P[1].Sign() == 0) {
+			continue
+		}
+		ps = append(ps, &affinePoint{newFieldElement(P[0]), newFieldElement(P[1])})
+		ks = append(ks, k)
+		if n := k.BitLen(); n > maxBits {
+			maxBits = n
+		}
+	}
+
+	c := msmWindow(len(ps))
+	if c == 0 {
+		x, y := new(big.Int), new(big.Int)
+		for i, P := range ps {
+			kx, ky := curve.ScalarMult(P.x.big(), P.y.big(), ks[i].Bytes())
+			x, y = curve.Add(x, y, kx, ky)
+		}
+		return x, y
+	}
+
+	var acc jacobianPoint
+	buckets := make([]jacobianPoint, 1<<uint(c)-1)
+	for w := (maxBits+c-1)/c - 1; w >= 0; w-- {
+		for i := 0; i < c; i++ {
+			acc.double(&acc)
+		}
+		for j := range buckets {
+			buckets[j] = jacobianPoint{}
+		}
+		for i, k := range ks {
+			d := 0
+			for b := c - 1; b >= 0; b-- {
+				d = d<<1 | int(k.Bit(w*c+b))
+			}
+			if d != 0 {
+				buckets[d-1].addAffine(&buckets[d-1], ps[i])
+			}
+		}
+		//sum(j*bucket[j-1]) is the sum of the suffix sums of the buckets
+		var sum jacobianPoint
+		for j := len(buckets) - 1; j >= 0; j-- {
+			sum.add(&sum, &buckets[j])
+			acc.add(&acc, &sum)
+		}
+	}
+	return acc.affine()
+}
+
+/*
+msmWindow returns the window size c that minimises the number of additions
+ceil(256/c)*(c+n+2(2^c-1)) for n points, or 0 if separate multiplications are
+cheaper. btcec's own multiplication uses the secp256k1 endomorphism, which
+makes it the faster choice for up to eight points.
+*/
+func msmWindow(n int) int {
+	if n <= 8 {
+		return 0
+	}
+	best, bestCost := 0, 0
+	for c := 1; c <= 16; c++ {
+		cost := (256 + c - 1) / c * (c + n + 2*(1<<uint(c)-1))
+		if best == 0 || cost < bestCost {
+			best, bestCost = c, cost
+		}
+	}
+	return best
+}
+
+func limbs(a *big.Int) (ret [4]uint64) {
+	var buf [32]byte
+	a.FillBytes(buf[:])
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			ret[i] |= uint64(buf[31-8*i-j]) << (8 * uint(j))
+		}
+	}
+	return ret
+}
+
+func newFieldElement(a *big.Int) fieldElement {
+	e := fieldElement(limbs(new(big.Int).Mod(a, btcec.S256().P)))
+	fieldMul(&e, &e, &fieldR2)
+	return e
+}
+
+func (e *fieldElement) big() *big.Int {
+	var t fieldElement
+	fieldMul(&t, e, &fieldElement{1})
+	var buf [32]byte
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			buf[31-8*i-j] = byte(t[i] >> (8 * uint(j)))
+		}
+	}
+	return new(big.Int).SetBytes(buf[:])
+}
+
+func (e *fieldElement) isZero() bool {
+	return e[0]|e[1]|e[2]|e[3] == 0
+}
+
+/*
+fieldReduce sets c to t - P if the five-limb value t is at least P, and to t
+otherwise.
+*/
+func fieldReduce(c *fieldElement, t *[5]uint64) {
+	var r fieldElement
+	var b uint64
+	r[0], b = bits.Sub64(t[0], fieldP[0], 0)
+	r[1], b = bits.Sub64(t[1], fieldP[1], b)
+	r[2], b = bits.Sub64(t[2], fieldP[2], b)
+	r[3], b = bits.Sub64(t[3], fieldP[3], b)
+	_, b = bits.Sub64(t[4], 0, b)
+	if b == 0 {
+		*c = r
+	} else {
+		copy(c[:], t[:4])
+	}
+}
+
+func fieldAdd(c, a, b *fieldElement) {
+	var t [5]uint64
+	var carry uint64
+	t[0], carry = bits.Add64(a[0], b[0], 0)
+	t[1], carry = bits.Add64(a[1], b[1], carry)
+	t[2], carry = bits.Add64(a[2], b[2], carry)
+	t[3], carry = bits.Add64(a[3], b[3], carry)
+	t[4] = carry
+	fieldReduce(c, &t)
+}
+
+func fieldSub(c, a, b *fieldElement) {
+	var t fieldElement
+	var borrow uint64
+	t[0], borrow = bits.Sub64(a[0], b[0], 0)
+	t[1], borrow = bits.Sub64(a[1], b[1], borrow)
+	t[2], borrow = bits.Sub64(a[2], b[2], borrow)
+	t[3], borrow = bits.Sub64(a[3], b[3], borrow)
+	if borrow != 0 {
+		var carry uint64
+		t[0], carry = bits.Add64(t[0], fieldP[0], 0)
+		t[1], carry = bits.Add64(t[1], fieldP[1], carry)
+		t[2], carry = bits.Add64(t[2], fieldP[2], carry)
+		t[3], _ = bits.Add64(t[3], fieldP[3], carry)
+	}
+	*c = t
+}
+
+/*
+fieldMul sets c to a*b*R^-1 mod P using coarsely integrated operand scanning
+Montgomery multiplication.
+*/
+func fieldMul(c, a, b *fieldElement) {
+	var t [6]uint64
+	for i := 0; i < 4; i++ {
+		var C uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[j], b[i])
+			var c1, c2 uint64
+			lo, c1 = bits.Add64(lo, t[j], 0)
+			lo, c2 = bits.Add64(lo, C, 0)
+			t[j] = lo
+			C = hi + c1 + c2
+		}
+		var c3 uint64
+		t[4], c3 = bits.Add64(t[4], C, 0)
+		t[5] = c3
+
+		m := t[0] * fieldNP
+		hi, lo := bits.Mul64(m, fieldP[0])
+		_, c1 := bits.Add64(lo, t[0], 0)
+		C = hi + c1
+		for j := 1; j < 4; j++ {
+			hi, lo := bits.Mul64(m, fieldP[j])
+			var c1, c2 uint64
+			lo, c1 = bits.Add64(lo, t[j], 0)
+			lo, c2 = bits.Add64(lo, C, 0)
+			t[j-1] = lo
+			C = hi + c1 + c2
+		}
+		t[3], c1 = bits.Add64(t[4], C, 0)
+		t[4] = t[5] + c1
+	}
+	var r [5]uint64
+	copy(r[:], t[:5])
+	fieldReduce(c, &r)
+}
+
+func (a *jacobianPoint) isInfinity() bool {
+	return a.z.isZero()
+}
+
+/*
+affine converts a to (x, y), or (0, 0) for the point at infinity.
+*/
+func (a *jacobianPoint) affine() (*big.Int, *big.Int) {
+	if a.isInfinity() {
+		return new(big.Int), new(big.Int)
+	}
+	P := btcec.S256().P
+	zInv := new(big.Int).ModInverse(a.z.big(), P)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	x := new(big.Int).Mul(a.x.big(), zInv2)
+	x.Mod(x, P)
+	y := new(big.Int).Mul(a.y.big(), zInv2.Mul(zInv2, zInv))
+	y.Mod(y, P)
+	return x, y
+}
+
+/*
+double sets c to 2a, following
+http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#doubling-dbl-2009-l
+*/
+func (c *jacobianPoint) double(a *jacobianPoint) {
+	if a.isInfinity() {
+		*c = *a
+		return
+	}
+	var A, B, C, D, E, F, x, y, z fieldElement
+	fieldMul(&A, &a.x, &a.x)
+	fieldMul(&B, &a.y, &a.y)
+	fieldMul(&C, &B, &B)
+	fieldAdd(&D, &a.x, &B)
+	fieldMul(&D, &D, &D)
+	fieldSub(&D, &D, &A)
+	fieldSub(&D, &D, &C)
+	fieldAdd(&D, &D, &D)
+	fieldAdd(&E, &A, &A)
+	fieldAdd(&E, &E, &A)
+	fieldMul(&F, &E, &E)
+
+	fieldSub(&x, &F, &D)
+	fieldSub(&x, &x, &D)
+	fieldSub(&y, &D, &x)
+	fieldMul(&y, &y, &E)
+	fieldAdd(&C, &C, &C)
+	fieldAdd(&C, &C, &C)
+	fieldAdd(&C, &C, &C)
+	fieldSub(&y, &y, &C)
+	fieldMul(&z, &a.y, &a.z)
+	fieldAdd(&z, &z, &z)
+	c.x, c.y, c.z = x, y, z
+}
+
+/*
+add sets c to a+b, following
+http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-add-2007-bl
+*/
+func (c *jacobianPoint) add(a, b *jacobianPoint) {
+	if a.isInfinity() {
+		*c = *b
+		return
+	}
+	if b.isInfinity() {
+		*c = *a
+		return
+	}
+	var z1z1, z2z2, u1, u2, s1, s2, h, r fieldElement
+	fieldMul(&z1z1, &a.z, &a.z)
+	fieldMul(&z2z2, &b.z, &b.z)
+	fieldMul(&u1, &a.x, &z2z2)
+	fieldMul(&u2, &b.x, &z1z1)
+	fieldMul(&s1, &b.z, &z2z2)
+	fieldMul(&s1, &a.y, &s1)
+	fieldMul(&s2, &a.z, &z1z1)
+	fieldMul(&s2, &b.y, &s2)
+	fieldSub(&h, &u2, &u1)
+	fieldSub(&r, &s2, &s1)
+	if h.isZero() {
+		if r.isZero() {
+			c.double(a)
+		} else {
+			*c = jacobianPoint{}
+		}
+		return
+	}
+
+	var z fieldElement
+	fieldAdd(&z, &a.z, &b.z)
+	fieldMul(&z, &z, &z)
+	fieldSub(&z, &z, &z1z1)
+	fieldSub(&z, &z, &z2z2)
+	fieldMul(&z, &z, &h)
+	c.addFinish(&u1, &s1, &h, &r, &z)
+}
+
+/*
+addAffine sets c to a+b, following
+http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-madd-2007-bl
+*/
+func (c *jacobianPoint) addAffine(a *jacobianPoint, b *affinePoint) {
+	if a.isInfinity() {
+		c.x, c.y, c.z = b.x, b.y, fieldOne
+		return
+	}
+	var z1z1, u2, s2, h, r fieldElement
+	fieldMul(&z1z1, &a.z, &a.z)
+	fieldMul(&u2, &b.x, &z1z1)
+	fieldMul(&s2, &a.z, &z1z1)
+	fieldMul(&s2, &b.y, &s2)
+	fieldSub(&h, &u2, &a.x)
+	fieldSub(&r, &s2, &a.y)
+	if h.isZero() {
+		if r.isZero() {
+			c.double(a)
+		} else {
+			*c = jacobianPoint{}
+		}
+		return
+	}
+
+	var z, hh fieldElement
+	fieldAdd(&z, &a.z, &h)
+	fieldMul(&z, &z, &z)
+	fieldSub(&z, &z, &z1z1)
+	fieldMul(&hh, &h, &h)
+	fieldSub(&z, &z, &hh)
+	u1, s1 := a.x, a.y
+	c.addFinish(&u1, &s1, &h, &r, &z)
+}
+
+/*
+addFinish completes both additions given U1, S1, H = U2-U1, S2-S1 and Z3:
+I = 4H^2, J = HI, r = 2(S2-S1), V = U1I, X3 = r^2-J-2V and
+Y3 = r(V-X3)-2S1J.
+*/
+func (c *jacobianPoint) addFinish(u1, s1, h, r, z *fieldElement) {
+	var i, j, v, x, y fieldElement
+	fieldAdd(&i, h, h)
+	fieldMul(&i, &i, &i)
+	fieldMul(&j, h, &i)
+	fieldAdd(r, r, r)
+	fieldMul(&v, u1, &i)
+
+	fieldMul(&x, r, r)
+	fieldSub(&x, &x, &j)
+	fieldSub(&x, &x, &v)
+	fieldSub(&x, &x, &v)
+	fieldSub(&y, &v, &x)
+	fieldMul(&y, &y, r)
+	fieldMul(&j, s1, &j)
+	fieldAdd(&j, &j, &j)
+	fieldSub(&y, &y, &j)
+	c.x, c.y, c.z = x, y, *z
+}
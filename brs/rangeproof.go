@@ -5,45 +5,150 @@ package brs
 
 import (
 	"crypto/rand"
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/btcsuite/btcd/btcec"
 )
 
+/*
+GeneratorVersion identifies how the second Pedersen generator H is derived.
+Commitments and proofs are only meaningful under the generator they were
+created with.
+*/
+type GeneratorVersion uint8
+
+const (
+	// GeneratorLegacy is H = h*G for the hard-coded scalar h below. Since h is
+	// public, anyone can open a commitment to any value; it is kept only to
+	// verify commitments created before GeneratorNUMS.
+	GeneratorLegacy GeneratorVersion = 1
+	// GeneratorNUMS is H = HashToCurve(nil, GeneratorDST).
+	GeneratorNUMS GeneratorVersion = 2
+)
+
+// GeneratorDST is the domain-separation tag used to derive H for GeneratorNUMS.
+const GeneratorDST = "blockchain-research/crypto/brs/pedersen-H/v2"
+
+// legacyH is log_G(H) for GeneratorLegacy.
+const legacyH = "18560948149108576432482904553159745978835170526553990798435819795989606410925"
+
+/*
+GeneratorH returns the Pedersen generator H for the given version.
+*/
+func GeneratorH(version GeneratorVersion) (*big.Int, *big.Int, error) {
+	switch version {
+	case GeneratorLegacy:
+		hx, hy := btcec.S256().ScalarBaseMult(GetBigInt(legacyH).Bytes())
+		return hx, hy, nil
+	case GeneratorNUMS:
+		hx, hy := HashToCurve(nil, []byte(GeneratorDST))
+		return hx, hy, nil
+	}
+	return nil, nil, fmt.Errorf("brs: unknown generator version %d", version)
+}
+
 type ParamsUL struct {
-	curve *btcec.KoblitzCurve
-	hx    *big.Int
-	hy    *big.Int
-	u     int64
-	l     int64
+	curve   *btcec.KoblitzCurve
+	hx      *big.Int
+	hy      *big.Int
+	u       int64
+	l       int64
+	version GeneratorVersion
 }
 
 /*
 ProofUL contains the proof generated by prover which will be used for verification by verifiers
 */
 type ProofUL struct {
-	e0 *big.Int
-	C  [][]*big.Int
-	s  [][]*big.Int
-	m  []*big.Int
+	version GeneratorVersion
+	e0      *big.Int
+	C       [][]*big.Int
+	s       [][]*big.Int
+	m       []*big.Int
 }
 
+/*
+SetupUL sets up the parameters for proving ranges [0,u^l) with the
+nothing-up-my-sleeve generator H.
+*/
 func SetupUL(u, l int64) *ParamsUL {
-	h := GetBigInt("18560948149108576432482904553159745978835170526553990798435819795989606410925")
-	curve := btcec.S256()
-	hx, hy := curve.ScalarBaseMult(h.Bytes())
-	return &ParamsUL{
-		curve: curve,
-		hx:    hx,
-		hy:    hy,
-		u:     u,
-		l:     l,
+	params, _ := setupUL(u, l, GeneratorNUMS)
+	return params
+}
+
+/*
+SetupULLegacy sets up the parameters with the legacy generator H, whose
+discrete logarithm is public. Use it only to verify existing proofs.
+*/
+func SetupULLegacy(u, l int64) *ParamsUL {
+	params, _ := setupUL(u, l, GeneratorLegacy)
+	return params
+}
+
+func setupUL(u, l int64, version GeneratorVersion) (*ParamsUL, error) {
+	hx, hy, err := GeneratorH(version)
+	if err != nil {
+		return nil, err
 	}
+	return &ParamsUL{
+		curve:   btcec.S256(),
+		hx:      hx,
+		hy:      hy,
+		u:       u,
+		l:       l,
+		version: version,
+	}, nil
+}
+
+/*
+H returns the Pedersen generator used for commitments under these parameters.
+*/
+func (p *ParamsUL) H() (*big.Int, *big.Int) {
+	return p.hx, p.hy
+}
 
+/*
+Version returns the generator version of these parameters.
+*/
+func (p *ParamsUL) Version() GeneratorVersion {
+	return p.version
 }
 
-//v is the number to prove in range [0,u^l)
+/*
+ProveUL proves that number is in range [0,u^l) with randomly chosen blinding
+factors. It returns the proof and the blinding factor rsum of the commitment
+Commit(number, rsum, H) that the proof verifies against, or nil if number is
+out of range.
+*/
 func (p *ParamsUL) ProveUL(number *big.Int) (*ProofUL, *big.Int) {
+	rsum, err := rand.Int(rand.Reader, p.curve.N)
+	if err != nil {
+		return nil, nil
+	}
+	proof, err := p.ProveULWithBlinding(number, rsum)
+	if err != nil {
+		return nil, nil
+	}
+	return proof, rsum
+}
+
+/*
+ProveULWithBlinding proves that value is in range [0,u^l) for the existing
+commitment Commit(value, blind, H). The per-digit blinding factors are chosen
+to sum to blind modulo N.
+
+Each digit i is committed as C^i = commit(m^iv^i, r^i) and signed with a
+Borromean ring over the keys C^i-jm^iH, 0<=j<u. The ring is closed at j=0 by
+R^i = s^i_0G-e^i_u-1C^i, so every digit, including zero digits, has a freely
+chosen blinding factor.
+*/
+func (p *ParamsUL) ProveULWithBlinding(value, blind *big.Int) (*ProofUL, error) {
+	if value.Sign() < 0 || value.Cmp(p.upperBound()) >= 0 {
+		return nil, errors.New("brs: value is out of range")
+	}
+
 	//initialize e l * u
 	e := make([][]*big.Int, p.l)
 	for i := range e {
@@ -59,12 +164,6 @@ func (p *ParamsUL) ProveUL(number *big.Int) (*ProofUL, *big.Int) {
 	//initialize k len: l
 	var k = make([]*big.Int, p.l)
 
-	//initialize karr l*u
-	var karr = make([][]*big.Int, p.l)
-	for i := range karr {
-		karr[i] = make([]*big.Int, p.u)
-	}
-
 	//initialize C l*2
 	var C = make([][]*big.Int, p.l)
 	for i := range C {
@@ -77,71 +176,60 @@ func (p *ParamsUL) ProveUL(number *big.Int) (*ProofUL, *big.Int) {
 		s[i] = make([]*big.Int, p.u)
 	}
 
-	//initialize rsum
-	rsum := new(big.Int).SetInt64(0)
-	//initialize r len: l
-	var r = make([]*big.Int, p.l)
-
 	//Step 1
-	v, m := GetBaseRepresentation(number, p.u, p.l)
+	v, m := GetBaseRepresentation(value, p.u, p.l)
 
-	//Step 2
+	//choose r^i at random for all but the last digit and set the last one
+	//so that sum(r^i) = blind
+	var err error
+	r := make([]*big.Int, p.l)
+	rsum := new(big.Int).SetInt64(0)
 	var i, j int64
-	for i = 0; i < p.l; i++ {
-		if v[i].Sign() == 0 {
-			//random k^i_0 from Zq
-			karr[i][0], _ = rand.Int(rand.Reader, p.curve.N)
-			//set R^i = k^i_0G,
-			R[i][0], R[i][1] = p.curve.ScalarBaseMult(karr[i][0].Bytes())
-
-		} else {
-			//random r^i from Zq
-			r[i], _ = rand.Int(rand.Reader, p.curve.N)
-			rsum = new(big.Int).Add(rsum, r[i])
-
-			//C^i = commit(m^iv^i,r^i)
-			mvi := new(big.Int).Mul(m[i], v[i])
-			C[i][0], C[i][1] = Commit(mvi, r[i], p.hx, p.hy)
-
-			// random k_^i from Zq
-			k[i], _ = rand.Int(rand.Reader, p.curve.N)
-
-			// compute e^i_vi=H(k^iG)
-			kiGx, kiGy := p.curve.ScalarBaseMult(k[i].Bytes())
-			e[i][v[i].Int64()] = Hash([]*big.Int{kiGx, kiGy})
-
-			//for each j \in {v^i+1,...,m-1}
-			for j = v[i].Int64() + 1; j < p.u; j++ {
-				//random sij from Zq
-				s[i][j], _ = rand.Int(rand.Reader, p.curve.N)
-
-				//calculate s^i_jG
-				p1x, p1y := p.curve.ScalarBaseMult(s[i][j].Bytes())
+	for i = 0; i < p.l-1; i++ {
+		if r[i], err = rand.Int(rand.Reader, p.curve.N); err != nil {
+			return nil, err
+		}
+		rsum.Add(rsum, r[i])
+	}
+	r[p.l-1] = new(big.Int).Sub(blind, rsum)
+	r[p.l-1].Mod(r[p.l-1], p.curve.N)
 
-				//calculate -eC
-				eneg := new(big.Int).Neg(e[i][j-1])
-				eneg = new(big.Int).Mod(eneg, p.curve.N)
-				prodCx, prodCy := p.curve.ScalarMult(C[i][0], C[i][1], eneg.Bytes())
+	//Step 2
+	for i = 0; i < p.l; i++ {
+		//C^i = commit(m^iv^i,r^i)
+		mvi := new(big.Int).Mul(m[i], v[i])
+		C[i][0], C[i][1] = Commit(mvi, r[i], p.hx, p.hy)
 
-				//calculate sG-eC
-				p2x, p2y := p.curve.Add(p1x, p1y, prodCx, prodCy)
+		// random k^i from Zq
+		if k[i], err = rand.Int(rand.Reader, p.curve.N); err != nil {
+			return nil, err
+		}
+		kiGx, kiGy := p.curve.ScalarBaseMult(k[i].Bytes())
 
-				//calculate ejmH
-				prod := new(big.Int).Mul(e[i][j-1], new(big.Int).Mul(new(big.Int).SetInt64(j), m[i]))
-				prod = prod.Mod(prod, p.curve.N)
-				prodHx, prodHy := p.curve.ScalarMult(p.hx, p.hy, prod.Bytes())
+		if v[i].Sign() == 0 {
+			//the signer closes the ring: R^i = k^iG
+			R[i][0], R[i][1] = kiGx, kiGy
+			continue
+		}
 
-				//calculate s^i_jG-e^i_j-1[C^i-jm^iH]
-				px, py := p.curve.Add(p2x, p2y, prodHx, prodHy)
+		// compute e^i_vi=H(k^iG)
+		e[i][v[i].Int64()] = Hash([]*big.Int{kiGx, kiGy})
 
-				//compute eij= H(s^i_jG-e^i_j-1[C^i-jm^iH])
-				e[i][j] = Hash([]*big.Int{px, py})
+		//for each j \in {v^i+1,...,u-1}
+		for j = v[i].Int64() + 1; j < p.u; j++ {
+			//random sij from Zq
+			if s[i][j], err = rand.Int(rand.Reader, p.curve.N); err != nil {
+				return nil, err
 			}
+			//compute eij= H(s^i_jG-e^i_j-1[C^i-jm^iH])
+			e[i][j] = p.challenge(s[i][j], e[i][j-1], C[i], j, m[i])
+		}
 
-			//compute R^i = e^i_m-1C^1
-			R[i][0], R[i][1] = p.curve.ScalarMult(C[i][0], C[i][1], e[i][p.u-1].Bytes())
-
+		//random s^i_0 and R^i = s^i_0G-e^i_u-1C^i
+		if s[i][0], err = rand.Int(rand.Reader, p.curve.N); err != nil {
+			return nil, err
 		}
+		R[i][0], R[i][1] = p.closeRing(s[i][0], e[i][p.u-1], C[i])
 	}
 
 	//Step 3: set e0=H(R0||..||Rn-1)
@@ -155,92 +243,78 @@ func (p *ParamsUL) ProveUL(number *big.Int) (*ProofUL, *big.Int) {
 	//Step 4:
 	for i = 0; i < p.l; i++ {
 		e[i][0] = e0
-		if v[i].Sign() == 0 {
-			//for j \in {1...,m-1}
-			for j = 1; j < p.u; j++ {
-				//random k^i_j from Zq
-				karr[i][j], _ = rand.Int(rand.Reader, p.curve.N)
-
-				//kijG
-				kGx, kGy := p.curve.ScalarBaseMult(karr[i][j].Bytes())
-
-				//e^i_j-1m^ij
-				emj := new(big.Int).Mul(new(big.Int).Mul(e[i][j-1], m[i]), new(big.Int).SetInt64(j))
-				emj = new(big.Int).Mod(emj, p.curve.N)
-
-				//e^i_j-1m^ijH
-				emjHx, emjHy := p.curve.ScalarMult(p.hx, p.hy, emj.Bytes())
-
-				//ijG+e^i_j-1m^ijH
-				px, py := p.curve.Add(kGx, kGy, emjHx, emjHy)
-
-				//e[i][j]=H(kijG+e^i_j-1m^ijH)
-				e[i][j] = Hash([]*big.Int{px, py})
-			}
-
-			//compute C^i = R^i / e^i_m-1
-			einverse := new(big.Int).ModInverse(e[i][p.u-1], p.curve.N)
-			keinverse := new(big.Int).Mul(karr[i][0], einverse)
-			keinverse = new(big.Int).Mod(keinverse, p.curve.N)
-			rsum = new(big.Int).Add(rsum, keinverse)
-			C[i][0], C[i][1] = p.curve.ScalarBaseMult(keinverse.Bytes())
-
-			//for j \in {1...,m-1}
-			for j = 1; j < p.u; j++ {
-				ke := new(big.Int).Mul(karr[i][0], e[i][j-1])
-				einverse := new(big.Int).ModInverse(e[i][p.u-1], p.curve.N)
-				kee := new(big.Int).Mul(ke, einverse)
-
-				s[i][j] = new(big.Int).Add(karr[i][j], kee)
-				s[i][j] = new(big.Int).Mod(s[i][j], p.curve.N)
-			}
-		} else {
-			//for j \in {1...,m-1}
-			for j = 1; j < v[i].Int64(); j++ {
-				//random sij from Zq
-				s[i][j], _ = rand.Int(rand.Reader, p.curve.N)
-
-				//calculate s^i_jG
-				p1x, p1y := p.curve.ScalarBaseMult(s[i][j].Bytes())
-
-				//calculate -eC
-				eneg := new(big.Int).Neg(e[i][j-1])
-				eneg = new(big.Int).Mod(eneg, p.curve.N)
-				prodCx, prodCy := p.curve.ScalarMult(C[i][0], C[i][1], eneg.Bytes())
-
-				//calculate sG-eC
-				p2x, p2y := p.curve.Add(p1x, p1y, prodCx, prodCy)
-
-				//calculate ejmH
-				prod := new(big.Int).Mul(e[i][j-1], new(big.Int).Mul(new(big.Int).SetInt64(j), m[i]))
-				prod = prod.Mod(prod, p.curve.N)
-				prodHx, prodHy := p.curve.ScalarMult(p.hx, p.hy, prod.Bytes())
-
-				//calculate s^i_jG-e^i_j-1[C^i-jm^iH]
-				px, py := p.curve.Add(p2x, p2y, prodHx, prodHy)
 
-				//compute eij= H(s^i_jG-e^i_j-1[C^i-jm^iH])
-				e[i][j] = Hash([]*big.Int{px, py})
+		//for j \in {1...,v^i-1}, or {1,...,u-1} for a zero digit
+		end := v[i].Int64()
+		if end == 0 {
+			end = p.u
+		}
+		for j = 1; j < end; j++ {
+			//random sij from Zq
+			if s[i][j], err = rand.Int(rand.Reader, p.curve.N); err != nil {
+				return nil, err
 			}
-			//set s^i_vi = k^i + e^i_vi-1r^i
-			eri := new(big.Int).Mul(e[i][v[i].Int64()-1], r[i])
-			s[i][v[i].Int64()] = new(big.Int).Add(k[i], eri)
-			s[i][v[i].Int64()] = new(big.Int).Mod(s[i][v[i].Int64()], p.curve.N) //?optional?
-
+			e[i][j] = p.challenge(s[i][j], e[i][j-1], C[i], j, m[i])
 		}
+
+		//set s^i_vi = k^i + e^i_vi-1r^i, where e^i_-1 = e^i_u-1
+		prev := e[i][end-1]
+		eri := new(big.Int).Mul(prev, r[i])
+		s[i][v[i].Int64()] = new(big.Int).Add(k[i], eri)
+		s[i][v[i].Int64()].Mod(s[i][v[i].Int64()], p.curve.N)
 	}
 
 	//Step 5:
 	return &ProofUL{
-		e0: e0,
-		C:  C,
-		s:  s,
-		m:  m,
-	}, rsum
+		version: p.version,
+		e0:      e0,
+		C:       C,
+		s:       s,
+		m:       m,
+	}, nil
+}
+
+/*
+challenge computes H(sG-e[C-jmH]).
+*/
+func (p *ParamsUL) challenge(s, e *big.Int, C []*big.Int, j int64, m *big.Int) *big.Int {
+	//calculate sG
+	p1x, p1y := p.curve.ScalarBaseMult(s.Bytes())
+
+	//calculate -eC
+	eneg := new(big.Int).Neg(e)
+	eneg = new(big.Int).Mod(eneg, p.curve.N)
+	prodCx, prodCy := p.curve.ScalarMult(C[0], C[1], eneg.Bytes())
+
+	//calculate sG-eC
+	p2x, p2y := p.curve.Add(p1x, p1y, prodCx, prodCy)
+
+	//calculate ejmH
+	prod := new(big.Int).Mul(e, new(big.Int).Mul(new(big.Int).SetInt64(j), m))
+	prod = prod.Mod(prod, p.curve.N)
+	prodHx, prodHy := p.curve.ScalarMult(p.hx, p.hy, prod.Bytes())
+
+	//calculate sG-e[C-jmH]
+	px, py := p.curve.Add(p2x, p2y, prodHx, prodHy)
+	return Hash([]*big.Int{px, py})
+}
+
+/*
+closeRing computes R = s0G-eC.
+*/
+func (p *ParamsUL) closeRing(s0, e *big.Int, C []*big.Int) (*big.Int, *big.Int) {
+	p1x, p1y := p.curve.ScalarBaseMult(s0.Bytes())
+	eneg := new(big.Int).Neg(e)
+	eneg = new(big.Int).Mod(eneg, p.curve.N)
+	prodCx, prodCy := p.curve.ScalarMult(C[0], C[1], eneg.Bytes())
+	return p.curve.Add(p1x, p1y, prodCx, prodCy)
 }
 
 func (v *ParamsUL) VerifyUL(proof *ProofUL, cmx *big.Int, cmy *big.Int) bool {
 	var i, j int64
+	if !proof.hasShape(v.u, v.l) || proof.version != v.version {
+		return false
+	}
 	//initialize e l * u
 	var e = make([][]*big.Int, v.l)
 	for i := range e {
@@ -257,30 +331,11 @@ func (v *ParamsUL) VerifyUL(proof *ProofUL, cmx *big.Int, cmy *big.Int) bool {
 	for i = 0; i < v.l; i++ {
 		e[i][0] = proof.e0
 		for j = 1; j < v.u; j++ {
-			//calculate s^i_jG
-			p1x, p1y := v.curve.ScalarBaseMult(proof.s[i][j].Bytes())
-
-			//calculate -eC
-			eneg := new(big.Int).Neg(e[i][j-1])
-			eneg = new(big.Int).Mod(eneg, v.curve.N)
-			prodCx, prodCy := v.curve.ScalarMult(proof.C[i][0], proof.C[i][1], eneg.Bytes())
-
-			//calculate sG-eC
-			p2x, p2y := v.curve.Add(p1x, p1y, prodCx, prodCy)
-
-			//calculate ejmH
-			prod := new(big.Int).Mul(e[i][j-1], new(big.Int).Mul(new(big.Int).SetInt64(j), proof.m[i]))
-			prod = prod.Mod(prod, v.curve.N)
-			prodHx, prodHy := v.curve.ScalarMult(v.hx, v.hy, prod.Bytes())
-
-			//calculate s^i_jG-e^i_j-1[C^i-jm^iH]
-			px, py := v.curve.Add(p2x, p2y, prodHx, prodHy)
-
 			//compute eij= H(s^i_jG-e^i_j-1[C^i-jm^iH])
-			e[i][j] = Hash([]*big.Int{px, py})
+			e[i][j] = v.challenge(proof.s[i][j], e[i][j-1], proof.C[i], j, proof.m[i])
 		}
-		//compute R^i = e^i_m-1C^1
-		R[i][0], R[i][1] = v.curve.ScalarMult(proof.C[i][0], proof.C[i][1], e[i][v.u-1].Bytes())
+		//compute R^i = s^i_0G-e^i_u-1C^i
+		R[i][0], R[i][1] = v.closeRing(proof.s[i][0], e[i][v.u-1], proof.C[i])
 	}
 
 	//Step 2:set ehat0=H(R0||..||Rn-1)
@@ -311,3 +366,150 @@ func (v *ParamsUL) VerifyUL(proof *ProofUL, cmx *big.Int, cmy *big.Int) bool {
 
 	return true
 }
+
+/*
+hasShape returns true iff the proof carries l digit commitments, the ring
+scalars for a base u decomposition and the digit weights m^i = u^i.
+*/
+func (proof *ProofUL) hasShape(u, l int64) bool {
+	if proof == nil || proof.e0 == nil ||
+		int64(len(proof.C)) != l || int64(len(proof.s)) != l || int64(len(proof.m)) != l {
+		return false
+	}
+	_, m := GetBaseRepresentation(new(big.Int), u, l)
+	var i, j int64
+	for i = 0; i < l; i++ {
+		if len(proof.C[i]) != 2 || proof.C[i][0] == nil || proof.C[i][1] == nil ||
+			int64(len(proof.s[i])) != u || proof.m[i] == nil || proof.m[i].Cmp(m[i]) != 0 {
+			return false
+		}
+		for j = 0; j < u; j++ {
+			if proof.s[i][j] == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//Setup, Prove and Verify set up the parameters, generate the proof and verify
+//the proof for the case where the range is [min,max)
+
+/*
+Params contains the parameters for proving ranges [min,max). The range is
+proven as two ranges [0,u^l) for the shifted values x-min and x-max+u^l,
+where u^l >= max-min, so max-min does not need to be a power of u.
+*/
+type Params struct {
+	ul       *ParamsUL
+	min, max *big.Int
+}
+
+/*
+Proof contains the two proofs of the shifted values for a range [min,max).
+*/
+type Proof struct {
+	proof1 *ProofUL //proves x-max+u^l in [0,u^l)
+	proof2 *ProofUL //proves x-min in [0,u^l)
+}
+
+// rangeBase is the base u used by Setup. Base 4 gives the smallest proofs for
+// this construction: l*u scalars with l = log_u(max-min).
+const rangeBase = 4
+
+/*
+Setup configures the parameters for proving that a committed value is in
+[min,max).
+*/
+func Setup(min, max int64) (*Params, error) {
+	if min >= max {
+		return nil, errors.New("brs: min must be less than max")
+	}
+	width := new(big.Int).Sub(big.NewInt(max), big.NewInt(min))
+
+	//smallest l with u^l >= max-min
+	var l int64
+	ul := big.NewInt(1)
+	for ul.Cmp(width) < 0 {
+		ul.Mul(ul, big.NewInt(rangeBase))
+		l++
+	}
+	if l == 0 {
+		l = 1
+	}
+	return &Params{
+		ul:  SetupUL(rangeBase, l),
+		min: big.NewInt(min),
+		max: big.NewInt(max),
+	}, nil
+}
+
+/*
+H returns the Pedersen generator used for commitments under these parameters.
+*/
+func (p *Params) H() (*big.Int, *big.Int) {
+	return p.ul.H()
+}
+
+/*
+Prove proves that value is in [min,max) for the commitment
+Commit(value, blind, H).
+*/
+func (p *Params) Prove(value, blind *big.Int) (*Proof, error) {
+	if value.Cmp(p.min) < 0 || value.Cmp(p.max) >= 0 {
+		return nil, errors.New("brs: value is out of range")
+	}
+	// x - max + u^l
+	xb := new(big.Int).Sub(value, p.max)
+	xb.Add(xb, p.ul.upperBound())
+	first, err := p.ul.ProveULWithBlinding(xb, blind)
+	if err != nil {
+		return nil, err
+	}
+
+	// x - min
+	xa := new(big.Int).Sub(value, p.min)
+	second, err := p.ul.ProveULWithBlinding(xa, blind)
+	if err != nil {
+		return nil, err
+	}
+	return &Proof{
+		proof1: first,
+		proof2: second,
+	}, nil
+}
+
+/*
+Verify checks that the commitment (cmx, cmy) opens to a value in [min,max).
+*/
+func (p *Params) Verify(proof *Proof, cmx, cmy *big.Int) bool {
+	if proof == nil || proof.proof1 == nil || proof.proof2 == nil {
+		return false
+	}
+	// C - max*H + u^l*H commits to x - max + u^l
+	shift := new(big.Int).Sub(p.ul.upperBound(), p.max)
+	c1x, c1y := p.ul.shift(cmx, cmy, shift)
+	// C - min*H commits to x - min
+	c2x, c2y := p.ul.shift(cmx, cmy, new(big.Int).Neg(p.min))
+
+	return p.ul.VerifyUL(proof.proof1, c1x, c1y) && p.ul.VerifyUL(proof.proof2, c2x, c2y)
+}
+
+/*
+upperBound returns u^l.
+*/
+func (p *ParamsUL) upperBound() *big.Int {
+	return new(big.Int).Exp(big.NewInt(p.u), big.NewInt(p.l), nil)
+}
+
+/*
+shift returns C + d*H.
+*/
+func (p *ParamsUL) shift(cx, cy, d *big.Int) (*big.Int, *big.Int) {
+	d = new(big.Int).Mod(d, p.curve.N)
+	if d.Sign() == 0 {
+		return cx, cy
+	}
+	dHx, dHy := p.curve.ScalarMult(p.hx, p.hy, d.Bytes())
+	return p.curve.Add(cx, cy, dHx, dHy)
+}
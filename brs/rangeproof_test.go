@@ -49,3 +49,117 @@ func TestRange(t *testing.T) {
 	}
 
 }
+
+func TestGeneratorVersion(t *testing.T) {
+	//the NUMS generator is reproducible from the published tag
+	hx, hy := HashToCurve(nil, []byte(GeneratorDST))
+	params := SetupUL(10, 3)
+	if px, py := params.H(); px.Cmp(hx) != 0 || py.Cmp(hy) != 0 {
+		t.Errorf("SetupUL does not use the NUMS generator")
+	}
+	if !params.curve.IsOnCurve(hx, hy) {
+		t.Errorf("NUMS generator is not on the curve")
+	}
+
+	legacy := SetupULLegacy(10, 3)
+	if legacy.Version() != GeneratorLegacy || params.Version() != GeneratorNUMS {
+		t.Errorf("unexpected generator versions")
+	}
+
+	//a legacy proof only verifies in legacy mode
+	value := new(big.Int).SetInt64(123)
+	proof, rsum := legacy.ProveUL(value)
+	cmx, cmy := Commit(value, rsum, legacy.hx, legacy.hy)
+	if !legacy.VerifyUL(proof, cmx, cmy) {
+		t.Errorf("legacy proof verification failed")
+	}
+	if params.VerifyUL(proof, cmx, cmy) {
+		t.Errorf("legacy proof verified under the NUMS generator")
+	}
+	if _, _, err := GeneratorH(0); err == nil {
+		t.Errorf("expected error for unknown generator version")
+	}
+}
+
+func TestRangeWithBlinding(t *testing.T) {
+	brs := SetupUL(10, 4)
+	for _, x := range []int64{0, 7, 1000, 9999} {
+		value := new(big.Int).SetInt64(x)
+		blind, _ := rand.Int(rand.Reader, brs.curve.N)
+		cmx, cmy := Commit(value, blind, brs.hx, brs.hy)
+
+		proof, err := brs.ProveULWithBlinding(value, blind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !brs.VerifyUL(proof, cmx, cmy) {
+			t.Errorf("Proof verification failed for %d", x)
+		}
+
+		//the proof must not verify against a commitment to another value
+		otherx, othery := Commit(new(big.Int).SetInt64(x+1), blind, brs.hx, brs.hy)
+		if brs.VerifyUL(proof, otherx, othery) {
+			t.Errorf("Proof verified against the wrong commitment for %d", x)
+		}
+	}
+
+	if _, err := brs.ProveULWithBlinding(new(big.Int).SetInt64(10000), big.NewInt(1)); err == nil {
+		t.Errorf("expected error for value out of range")
+	}
+	if _, err := brs.ProveULWithBlinding(new(big.Int).SetInt64(-1), big.NewInt(1)); err == nil {
+		t.Errorf("expected error for negative value")
+	}
+}
+
+func TestRangeMinMax(t *testing.T) {
+	//the width 1000-18 is not a power of the base
+	params, err := Setup(18, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hx, hy := params.H()
+	for _, x := range []int64{18, 500, 999} {
+		value := new(big.Int).SetInt64(x)
+		blind, _ := rand.Int(rand.Reader, params.ul.curve.N)
+		cmx, cmy := Commit(value, blind, hx, hy)
+
+		proof, err := params.Prove(value, blind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !params.Verify(proof, cmx, cmy) {
+			t.Errorf("Proof verification failed for %d", x)
+		}
+
+		data, err := proof.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof2 := &Proof{}
+		if err := proof2.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !params.Verify(proof2, cmx, cmy) {
+			t.Errorf("Proof verification failed after unmarshal for %d", x)
+		}
+	}
+
+	for _, x := range []int64{17, 1000} {
+		if _, err := params.Prove(new(big.Int).SetInt64(x), big.NewInt(1)); err == nil {
+			t.Errorf("expected error for %d out of range", x)
+		}
+	}
+
+	//a proof for an in-range value must not verify against a commitment
+	//to an out-of-range value with the same blinding factor
+	blind, _ := rand.Int(rand.Reader, params.ul.curve.N)
+	proof, _ := params.Prove(new(big.Int).SetInt64(999), blind)
+	cmx, cmy := Commit(new(big.Int).SetInt64(1000), blind, hx, hy)
+	if params.Verify(proof, cmx, cmy) {
+		t.Errorf("Proof verified for a value out of range")
+	}
+
+	if _, err := Setup(5, 5); err == nil {
+		t.Errorf("expected error for empty range")
+	}
+}
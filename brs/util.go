@@ -2,6 +2,7 @@ package brs
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/btcsuite/btcd/btcec"
@@ -61,6 +62,44 @@ func HashMsgVerificationKey(msg []byte, keys []*btcec.PublicKey) *big.Int {
 	return HashMsg(msg, a)
 }
 
+/*
+HashToCurve deterministically maps msg to a point on secp256k1 whose discrete
+logarithm with respect to G is unknown. dst is a domain-separation tag.
+
+The map is try-and-increment: for ctr = 0, 1, ... it sets
+x = SHA-256(dst || len(dst) || msg || ctr), where len(dst) is one byte and ctr
+is a 4-byte big-endian integer, and returns the first (x, y) on the curve with
+x < P and y even. A dst longer than 255 bytes is first replaced by
+SHA-256("H2C-OVERSIZE-DST-" || dst), as in RFC 9380, so that its length fits
+in the byte.
+*/
+func HashToCurve(msg, dst []byte) (*big.Int, *big.Int) {
+	if len(dst) > 255 {
+		h := sha256.Sum256(append([]byte("H2C-OVERSIZE-DST-"), dst...))
+		dst = h[:]
+	}
+	s256 := btcec.S256()
+	var buf [4]byte
+	for ctr := uint32(0); ; ctr++ {
+		binary.BigEndian.PutUint32(buf[:], ctr)
+		digest := sha256.New()
+		digest.Write(dst)
+		digest.Write([]byte{byte(len(dst))})
+		digest.Write(msg)
+		digest.Write(buf[:])
+		x := digest.Sum(nil)
+		if new(big.Int).SetBytes(x).Cmp(s256.P) >= 0 {
+			continue
+		}
+		//0x02 selects the even y coordinate
+		P, err := btcec.ParsePubKey(append([]byte{0x02}, x...), s256)
+		if err != nil {
+			continue
+		}
+		return P.X, P.Y
+	}
+}
+
 /*
 Commit method corresponds to the Pedersen commitment scheme. Namely, given input
 message x, and randomness r, it outputs g^x.h^r.
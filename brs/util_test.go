@@ -1,6 +1,7 @@
 package brs
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
 	"fmt"
@@ -94,3 +95,81 @@ func TestBase(t *testing.T) {
 	assert.Equal(t, mBase, m, "The two arrays should be the same.")
 
 }
+
+func TestHashToCurveLongDST(t *testing.T) {
+	//long tags are hashed down: otherwise the length byte of a 256-byte tag
+	//is 0 and that of the tag extended by 0 is 1, and both hash tag||0||1
+	long := bytes.Repeat([]byte{'d'}, 256)
+	x1, _ := HashToCurve([]byte{1}, long)
+	x2, _ := HashToCurve(nil, append(long, 0))
+	if x1.Cmp(x2) == 0 {
+		t.Errorf("HashToCurve tags of 256 bytes or more collide")
+	}
+}
+
+func TestMultiScalarMult(t *testing.T) {
+	s256 := btcec.S256()
+	for _, n := range []int{0, 1, 8, 9, 40} {
+		points := make([][]*big.Int, n)
+		scalars := make([]*big.Int, n)
+		x, y := new(big.Int), new(big.Int)
+		for i := range points {
+			d, _ := rand.Int(rand.Reader, s256.N)
+			px, py := s256.ScalarBaseMult(d.Bytes())
+			points[i] = []*big.Int{px, py}
+			scalars[i], _ = rand.Int(rand.Reader, s256.N)
+			switch i {
+			case 1:
+				scalars[i].Neg(scalars[i])
+			case 2:
+				points[i] = []*big.Int{new(big.Int), new(big.Int)}
+			case 3:
+				scalars[i].SetInt64(0)
+			case 4:
+				//doubling inside a bucket
+				points[i] = points[0]
+				scalars[i] = scalars[0]
+			}
+			if scalars[i].Sign() == 0 || points[i][0].Sign() == 0 {
+				continue
+			}
+			k := new(big.Int).Mod(scalars[i], s256.N)
+			kx, ky := s256.ScalarMult(points[i][0], points[i][1], k.Bytes())
+			x, y = s256.Add(x, y, kx, ky)
+		}
+		gx, gy := MultiScalarMult(points, scalars)
+		assert.Equal(t, x, gx, "x for %d points", n)
+		assert.Equal(t, y, gy, "y for %d points", n)
+	}
+
+	//-10P + ... + 10P is the point at infinity
+	px, py := s256.ScalarBaseMult(big.NewInt(5).Bytes())
+	points := make([][]*big.Int, 21)
+	scalars := make([]*big.Int, 21)
+	for i := range points {
+		points[i] = []*big.Int{px, py}
+		scalars[i] = big.NewInt(int64(i - 10))
+	}
+	x, y := MultiScalarMult(points, scalars)
+	assert.Equal(t, 0, x.Sign())
+	assert.Equal(t, 0, y.Sign())
+}
+
+func benchmarkMultiScalarMult(b *testing.B, n int) {
+	s256 := btcec.S256()
+	points := make([][]*big.Int, n)
+	scalars := make([]*big.Int, n)
+	for i := range points {
+		k, _ := rand.Int(rand.Reader, s256.N)
+		px, py := s256.ScalarBaseMult(k.Bytes())
+		points[i] = []*big.Int{px, py}
+		scalars[i], _ = rand.Int(rand.Reader, s256.N)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MultiScalarMult(points, scalars)
+	}
+}
+
+func BenchmarkMultiScalarMult16(b *testing.B)  { benchmarkMultiScalarMult(b, 16) }
+func BenchmarkMultiScalarMult128(b *testing.B) { benchmarkMultiScalarMult(b, 128) }
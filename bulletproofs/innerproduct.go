@@ -0,0 +1,84 @@
+package bulletproofs
+
+import (
+	"math/big"
+)
+
+/*
+innerProductProof is the logarithmic-size argument of protocol 2 in the
+Bulletproofs paper that the prover knows a, b with
+P = <a,G> + <b,H> + <a,b>U.
+*/
+type innerProductProof struct {
+	L, R []*Point
+	a, b *big.Int
+}
+
+/*
+proveInnerProduct runs the recursive inner-product argument for the vectors
+a, b over generators G, H and U. len(a) must be a power of two.
+*/
+func proveInnerProduct(G, H []*Point, U *Point, a, b []*big.Int, t *transcript) *innerProductProof {
+	proof := &innerProductProof{}
+	n := len(a)
+	for n > 1 {
+		n = n / 2
+		cL := innerProduct(a[:n], b[n:])
+		cR := innerProduct(a[n:], b[:n])
+
+		//L = <a_lo,G_hi> + <b_hi,H_lo> + cL*U
+		L := multiExp(G[n:], a[:n]).add(multiExp(H[:n], b[n:])).add(U.mul(cL))
+		//R = <a_hi,G_lo> + <b_lo,H_hi> + cR*U
+		R := multiExp(G[:n], a[n:]).add(multiExp(H[n:], b[:n])).add(U.mul(cR))
+		proof.L = append(proof.L, L)
+		proof.R = append(proof.R, R)
+
+		t.appendPoints(L, R)
+		x := t.challenge()
+		xinv := inverse(x)
+
+		//fold the generators and the vectors
+		G2 := make([]*Point, n)
+		H2 := make([]*Point, n)
+		for i := 0; i < n; i++ {
+			G2[i] = G[i].mul(xinv).add(G[n+i].mul(x))
+			H2[i] = H[i].mul(x).add(H[n+i].mul(xinv))
+		}
+		a = vectorAdd(vectorScale(a[:n], x), vectorScale(a[n:], xinv))
+		b = vectorAdd(vectorScale(b[:n], xinv), vectorScale(b[n:], x))
+		G, H = G2, H2
+	}
+	proof.a = a[0]
+	proof.b = b[0]
+	return proof
+}
+
+/*
+verificationScalars replays the challenges of the argument and returns them
+together with s, where s[i] is the product over the rounds j of x_j if bit j
+of i (counted from the most significant) is set and x_j^-1 otherwise. The
+folded generators are then <s,G> and <s^-1,H>.
+*/
+func (proof *innerProductProof) verificationScalars(n int, t *transcript) (x, s []*big.Int) {
+	k := len(proof.L)
+	x = make([]*big.Int, k)
+	xinv := make([]*big.Int, k)
+	for j := 0; j < k; j++ {
+		t.appendPoints(proof.L[j], proof.R[j])
+		x[j] = t.challenge()
+		xinv[j] = inverse(x[j])
+	}
+
+	s = make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		s[i] = big.NewInt(1)
+		for j := 0; j < k; j++ {
+			if i>>uint(k-1-j)&1 == 1 {
+				s[i] = mod(s[i].Mul(s[i], x[j]))
+			} else {
+				s[i] = mod(s[i].Mul(s[i], xinv[j]))
+			}
+		}
+	}
+	return x, s
+}
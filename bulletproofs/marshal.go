@@ -0,0 +1,193 @@
+package bulletproofs
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+/*
+Wire format
+
+Scalars are encoded as fixed 32-byte big-endian values and points as 33-byte
+compressed secp256k1 points, with 33 zero bytes for the point at infinity.
+
+RangeProof:
+
+	version     (1 byte)
+	rounds      (1 byte)       number of inner-product rounds, log2(64*m)
+	A, S, T1, T2 (33 bytes each)
+	taux, mu, t (32 bytes each)
+	L[j], R[j]  (33 bytes each) for 0<=j<rounds
+	a, b        (32 bytes each)
+*/
+const (
+	scalarLen = 32
+	pointLen  = 33
+
+	rangeProofVersion byte = 1
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*RangeProof)(nil)
+	_ encoding.BinaryUnmarshaler = (*RangeProof)(nil)
+)
+
+/*
+MarshalBinary encodes the range proof into its canonical binary form. It
+returns an error if a field of the proof is missing.
+*/
+func (proof *RangeProof) MarshalBinary() ([]byte, error) {
+	if !proof.complete() {
+		return nil, errors.New("bulletproofs: incomplete proof")
+	}
+	ret := []byte{rangeProofVersion, byte(len(proof.ipp.L))}
+	for _, P := range []*Point{proof.A, proof.S, proof.T1, proof.T2} {
+		ret = append(ret, encodePoint(P)...)
+	}
+	for _, k := range []*big.Int{proof.taux, proof.mu, proof.t} {
+		ret = append(ret, encodeScalar(k)...)
+	}
+	for j := range proof.ipp.L {
+		ret = append(ret, encodePoint(proof.ipp.L[j])...)
+		ret = append(ret, encodePoint(proof.ipp.R[j])...)
+	}
+	ret = append(ret, encodeScalar(proof.ipp.a)...)
+	ret = append(ret, encodeScalar(proof.ipp.b)...)
+	return ret, nil
+}
+
+/*
+UnmarshalBinary decodes a range proof produced by MarshalBinary. Every scalar
+must be in [0, N) and every point a valid compressed point.
+*/
+func (proof *RangeProof) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return errors.New("bulletproofs: unexpected end of data")
+	}
+	if data[0] != rangeProofVersion {
+		return fmt.Errorf("bulletproofs: unsupported proof version %d", data[0])
+	}
+	rounds := int(data[1])
+	if len(data) != 2+4*pointLen+3*scalarLen+2*rounds*pointLen+2*scalarLen {
+		return errors.New("bulletproofs: proof length does not match its rounds")
+	}
+	data = data[2:]
+
+	var err error
+	points := make([]*Point, 4+2*rounds)
+	for i := 0; i < 4; i++ {
+		if points[i], err = decodePoint(data[:pointLen]); err != nil {
+			return err
+		}
+		data = data[pointLen:]
+	}
+	scalars := make([]*big.Int, 5)
+	for i := 0; i < 3; i++ {
+		if scalars[i], err = decodeScalar(data[:scalarLen]); err != nil {
+			return err
+		}
+		data = data[scalarLen:]
+	}
+	for i := 4; i < len(points); i++ {
+		if points[i], err = decodePoint(data[:pointLen]); err != nil {
+			return err
+		}
+		data = data[pointLen:]
+	}
+	for i := 3; i < 5; i++ {
+		if scalars[i], err = decodeScalar(data[:scalarLen]); err != nil {
+			return err
+		}
+		data = data[scalarLen:]
+	}
+
+	ipp := &innerProductProof{
+		L: make([]*Point, rounds),
+		R: make([]*Point, rounds),
+		a: scalars[3],
+		b: scalars[4],
+	}
+	for j := 0; j < rounds; j++ {
+		ipp.L[j] = points[4+2*j]
+		ipp.R[j] = points[5+2*j]
+	}
+	proof.A, proof.S, proof.T1, proof.T2 = points[0], points[1], points[2], points[3]
+	proof.taux, proof.mu, proof.t = scalars[0], scalars[1], scalars[2]
+	proof.ipp = ipp
+	return nil
+}
+
+/*
+complete reports whether every point and scalar of the proof is set.
+*/
+func (proof *RangeProof) complete() bool {
+	ipp := proof.ipp
+	if ipp == nil || len(ipp.L) != len(ipp.R) || ipp.a == nil || ipp.b == nil {
+		return false
+	}
+	for _, P := range append([]*Point{proof.A, proof.S, proof.T1, proof.T2}, append(ipp.L, ipp.R...)...) {
+		if P == nil || P.X == nil || P.Y == nil {
+			return false
+		}
+	}
+	return proof.taux != nil && proof.mu != nil && proof.t != nil
+}
+
+func encodeScalar(k *big.Int) []byte {
+	ret := make([]byte, scalarLen)
+	b := new(big.Int).Mod(k, curve.N).Bytes()
+	copy(ret[scalarLen-len(b):], b)
+	return ret
+}
+
+func decodeScalar(b []byte) (*big.Int, error) {
+	k := new(big.Int).SetBytes(b)
+	if k.Cmp(curve.N) >= 0 {
+		return nil, errors.New("bulletproofs: scalar out of range")
+	}
+	return k, nil
+}
+
+/*
+encodePoint returns the compressed encoding of P, or 33 zero bytes for the
+point at infinity.
+*/
+func encodePoint(P *Point) []byte {
+	if P.isInfinity() {
+		return make([]byte, pointLen)
+	}
+	return (&btcec.PublicKey{Curve: curve, X: P.X, Y: P.Y}).SerializeCompressed()
+}
+
+/*
+decodePoint is the inverse of encodePoint.
+*/
+func decodePoint(b []byte) (*Point, error) {
+	if isZero(b) {
+		return infinity(), nil
+	}
+	if b[0] != 0x02 && b[0] != 0x03 {
+		return nil, errors.New("bulletproofs: invalid point encoding")
+	}
+	if new(big.Int).SetBytes(b[1:]).Cmp(curve.P) >= 0 {
+		return nil, errors.New("bulletproofs: point coordinate out of range")
+	}
+	pub, err := btcec.ParsePubKey(b, curve)
+	if err != nil {
+		return nil, fmt.Errorf("bulletproofs: invalid point: %v", err)
+	}
+	return &Point{pub.X, pub.Y}, nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+/*
+Package bulletproofs implements the range proofs of "Bulletproofs: Short Proofs
+for Confidential Transactions and More" by Bünz et al., https://eprint.iacr.org/2017/1066.pdf,
+over secp256k1.
+
+Commitments follow the brs convention V = gamma*G + v*H, where G is the curve
+base point and H is brs' nothing-up-my-sleeve generator, so a commitment
+created with brs.Commit can be proven in range here. Proofs have size
+2*log2(64*m)+4 points and 5 scalars for m aggregated values and need no
+trusted setup.
+*/
+package bulletproofs
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/brs"
+)
+
+// BitSize is the number of bits n each value is proven to fit in.
+const BitSize = 64
+
+// GeneratorDST is the domain-separation tag used to derive the vector
+// generators G_i, H_i and the inner-product generator U with brs.HashToCurve.
+const GeneratorDST = "blockchain-research/crypto/bulletproofs/generators/v1"
+
+/*
+Params contains the generators for proofs aggregating up to m values.
+*/
+type Params struct {
+	G  *Point   //blinding generator, the curve base point
+	H  *Point   //value generator, brs.GeneratorH(brs.GeneratorNUMS)
+	U  *Point   //inner-product generator
+	Gi []*Point //vector generators, len n*m
+	Hi []*Point //vector generators, len n*m
+	m  int
+}
+
+/*
+Setup derives the generators for proofs aggregating up to m values. m must be
+a power of two.
+*/
+func Setup(m int) (*Params, error) {
+	if m <= 0 || m&(m-1) != 0 {
+		return nil, errors.New("bulletproofs: m must be a power of two")
+	}
+	hx, hy, err := brs.GeneratorH(brs.GeneratorNUMS)
+	if err != nil {
+		return nil, err
+	}
+	params := &Params{
+		G:  &Point{curve.Gx, curve.Gy},
+		H:  &Point{hx, hy},
+		U:  hashToPoint("U", 0),
+		Gi: make([]*Point, BitSize*m),
+		Hi: make([]*Point, BitSize*m),
+		m:  m,
+	}
+	for i := range params.Gi {
+		params.Gi[i] = hashToPoint("G", uint32(i))
+		params.Hi[i] = hashToPoint("H", uint32(i))
+	}
+	return params, nil
+}
+
+/*
+Commit returns the Pedersen commitment gamma*G + v*H, the same point as
+brs.Commit(v, gamma, H).
+*/
+func (params *Params) Commit(v, gamma *big.Int) *Point {
+	x, y := brs.Commit(v, gamma, params.H.X, params.H.Y)
+	return &Point{x, y}
+}
+
+/*
+hashToPoint returns brs.HashToCurve(label || i, GeneratorDST), where i is a
+4-byte big-endian integer.
+*/
+func hashToPoint(label string, i uint32) *Point {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], i)
+	x, y := brs.HashToCurve(append([]byte(label), buf[:]...), []byte(GeneratorDST))
+	return &Point{x, y}
+}
@@ -0,0 +1,335 @@
+package bulletproofs
+
+import (
+	"errors"
+	"math/big"
+)
+
+/*
+RangeProof proves that each of m committed values is in [0, 2^64).
+*/
+type RangeProof struct {
+	A, S, T1, T2 *Point
+	taux, mu, t  *big.Int
+	ipp          *innerProductProof
+}
+
+const transcriptLabel = "blockchain-research/crypto/bulletproofs/rangeproof/v1"
+
+/*
+Prove generates an aggregated range proof that values[j] is in [0, 2^64) for
+the commitments Commit(values[j], gammas[j]). The number of values must be a
+power of two no larger than the m the parameters were set up for.
+*/
+func (params *Params) Prove(values, gammas []*big.Int) (*RangeProof, error) {
+	m := len(values)
+	if m == 0 || m&(m-1) != 0 || m > params.m || len(gammas) != m {
+		return nil, errors.New("bulletproofs: invalid number of values")
+	}
+	n := BitSize
+	N := n * m
+	bound := new(big.Int).Lsh(big.NewInt(1), BitSize)
+	V := make([]*Point, m)
+	for j := range values {
+		if values[j].Sign() < 0 || values[j].Cmp(bound) >= 0 {
+			return nil, errors.New("bulletproofs: value is out of range")
+		}
+		V[j] = params.Commit(values[j], gammas[j])
+	}
+	Gi, Hi := params.Gi[:N], params.Hi[:N]
+
+	//aL is the bit decomposition of the values and aR = aL - 1
+	aL := make([]*big.Int, N)
+	aR := make([]*big.Int, N)
+	for j := range values {
+		for i := 0; i < n; i++ {
+			aL[j*n+i] = big.NewInt(int64(values[j].Bit(i)))
+			aR[j*n+i] = mod(new(big.Int).Sub(aL[j*n+i], big.NewInt(1)))
+		}
+	}
+
+	//A = alpha*G + <aL,Gi> + <aR,Hi>
+	alpha, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	A := params.G.mul(alpha).add(multiExp(Gi, aL)).add(multiExp(Hi, aR))
+
+	//S = rho*G + <sL,Gi> + <sR,Hi>
+	sL := make([]*big.Int, N)
+	sR := make([]*big.Int, N)
+	for i := 0; i < N; i++ {
+		if sL[i], err = randomScalar(); err != nil {
+			return nil, err
+		}
+		if sR[i], err = randomScalar(); err != nil {
+			return nil, err
+		}
+	}
+	rho, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	S := params.G.mul(rho).add(multiExp(Gi, sL)).add(multiExp(Hi, sR))
+
+	t := newTranscript(transcriptLabel)
+	t.appendPoints(V...)
+	t.appendPoints(A, S)
+	y := t.challenge()
+	z := t.challenge()
+
+	//l(X) = (aL - z) + sL*X
+	//r(X) = y^N o (aR + z + sR*X) + z^(1+j)*2^n for the j-th block
+	yN := powers(y, N)
+	zj := powers(z, m+2)
+	twoN := powers(big.NewInt(2), n)
+	l0 := vectorAddScalar(aL, new(big.Int).Neg(z))
+	l1 := sL
+	r0 := vectorHadamard(yN, vectorAddScalar(aR, z))
+	for j := 0; j < m; j++ {
+		for i := 0; i < n; i++ {
+			r0[j*n+i] = mod(r0[j*n+i].Add(r0[j*n+i], new(big.Int).Mul(zj[j+2], twoN[i])))
+		}
+	}
+	r1 := vectorHadamard(yN, sR)
+
+	//t(X) = <l(X),r(X)> = t0 + t1*X + t2*X^2
+	t1 := mod(new(big.Int).Add(innerProduct(l0, r1), innerProduct(l1, r0)))
+	t2 := innerProduct(l1, r1)
+
+	tau1, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	tau2, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	T1 := params.Commit(t1, tau1)
+	T2 := params.Commit(t2, tau2)
+
+	t.appendPoints(T1, T2)
+	x := t.challenge()
+
+	//taux = tau2*x^2 + tau1*x + sum(z^(1+j)*gamma_j)
+	x2 := mod(new(big.Int).Mul(x, x))
+	taux := new(big.Int).Add(new(big.Int).Mul(tau2, x2), new(big.Int).Mul(tau1, x))
+	for j := range gammas {
+		taux.Add(taux, new(big.Int).Mul(zj[j+2], gammas[j]))
+	}
+	mod(taux)
+	//mu = alpha + rho*x
+	mu := mod(new(big.Int).Add(alpha, new(big.Int).Mul(rho, x)))
+
+	l := vectorAdd(l0, vectorScale(l1, x))
+	r := vectorAdd(r0, vectorScale(r1, x))
+	that := innerProduct(l, r)
+
+	t.appendScalars(taux, mu, that)
+	w := t.challenge()
+
+	//run the inner-product argument over Gi and Hi' = y^-i*Hi
+	yinv := powers(inverse(y), N)
+	Hprime := make([]*Point, N)
+	for i := range Hprime {
+		Hprime[i] = Hi[i].mul(yinv[i])
+	}
+	ipp := proveInnerProduct(Gi, Hprime, params.U.mul(w), l, r, t)
+
+	return &RangeProof{
+		A:    A,
+		S:    S,
+		T1:   T1,
+		T2:   T2,
+		taux: taux,
+		mu:   mu,
+		t:    that,
+		ipp:  ipp,
+	}, nil
+}
+
+/*
+Verify checks that proof shows every commitment in V opens to a value in
+[0, 2^64).
+*/
+func (params *Params) Verify(proof *RangeProof, V []*Point) bool {
+	return params.BatchVerify([]*RangeProof{proof}, [][]*Point{V})
+}
+
+/*
+BatchVerify checks proofs[k] against the commitments V[k] for every k. The
+verification equations of all proofs are combined with random weights into a
+single multi-exponentiation, so it returns true iff all proofs are valid
+(except with negligible probability).
+*/
+func (params *Params) BatchVerify(proofs []*RangeProof, V [][]*Point) bool {
+	if len(proofs) != len(V) {
+		return false
+	}
+	N := len(params.Gi)
+	batch := &verificationBatch{
+		Gi: make([]*big.Int, N),
+		Hi: make([]*big.Int, N),
+		G:  new(big.Int),
+		H:  new(big.Int),
+		U:  new(big.Int),
+	}
+	for i := 0; i < N; i++ {
+		batch.Gi[i] = new(big.Int)
+		batch.Hi[i] = new(big.Int)
+	}
+	for k := range proofs {
+		weight, err := randomScalar()
+		if err != nil {
+			return false
+		}
+		if !params.addToBatch(batch, proofs[k], V[k], weight) {
+			return false
+		}
+	}
+
+	points := append([]*Point{params.G, params.H, params.U}, params.Gi...)
+	points = append(points, params.Hi...)
+	points = append(points, batch.points...)
+	scalars := append([]*big.Int{batch.G, batch.H, batch.U}, batch.Gi...)
+	scalars = append(scalars, batch.Hi...)
+	scalars = append(scalars, batch.scalars...)
+	return multiExp(points, scalars).isInfinity()
+}
+
+/*
+verificationBatch accumulates the scalars of the verification equations of
+several proofs. The generators are shared between proofs; the proof-specific
+points are collected in points.
+*/
+type verificationBatch struct {
+	Gi, Hi  []*big.Int
+	G, H, U *big.Int
+	points  []*Point
+	scalars []*big.Int
+}
+
+func (b *verificationBatch) addPoint(P *Point, k *big.Int) {
+	b.points = append(b.points, P)
+	b.scalars = append(b.scalars, mod(k))
+}
+
+/*
+addToBatch adds the verification equations of proof, each multiplied by
+weight, to batch. The two equations checked are
+
+	t*H + taux*G = sum(z^(2+j)*V_j) + delta(y,z)*H + x*T1 + x^2*T2
+	A + x*S - z*<1,Gi> + <z + z^(1+j)*2^n*y^-i,Hi> - mu*G + w*t*U
+		+ sum(x_j^2*L_j + x_j^-2*R_j) = a*<s,Gi> + b*<s^-1*y^-i,Hi> + w*a*b*U
+
+where the first is multiplied by a random c before adding them.
+*/
+func (params *Params) addToBatch(batch *verificationBatch, proof *RangeProof, V []*Point, weight *big.Int) bool {
+	m := len(V)
+	if m == 0 || m&(m-1) != 0 || m > params.m || !proof.wellFormed(m) {
+		return false
+	}
+	n := BitSize
+	N := n * m
+
+	t := newTranscript(transcriptLabel)
+	t.appendPoints(V...)
+	t.appendPoints(proof.A, proof.S)
+	y := t.challenge()
+	z := t.challenge()
+	t.appendPoints(proof.T1, proof.T2)
+	x := t.challenge()
+	t.appendScalars(proof.taux, proof.mu, proof.t)
+	w := t.challenge()
+	xs, s := proof.ipp.verificationScalars(N, t)
+
+	c, err := randomScalar()
+	if err != nil {
+		return false
+	}
+	cw := mod(new(big.Int).Mul(c, weight))
+
+	yN := powers(y, N)
+	yinv := powers(inverse(y), N)
+	zj := powers(z, m+3)
+	twoN := powers(big.NewInt(2), n)
+	x2 := mod(new(big.Int).Mul(x, x))
+
+	//delta(y,z) = (z-z^2)*<1,y^N> - sum(z^(3+j)*<1,2^n>)
+	sumY := new(big.Int)
+	for i := range yN {
+		sumY.Add(sumY, yN[i])
+	}
+	sum2 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(n)), big.NewInt(1))
+	delta := new(big.Int).Mul(new(big.Int).Sub(z, zj[2]), sumY)
+	for j := 0; j < m; j++ {
+		delta.Sub(delta, new(big.Int).Mul(zj[j+3], sum2))
+	}
+	mod(delta)
+
+	//first equation, times c*weight:
+	//(t - delta)*H + taux*G - sum(z^(2+j)*V_j) - x*T1 - x^2*T2 = 0
+	hScalar := new(big.Int).Sub(proof.t, delta)
+	batch.H.Add(batch.H, new(big.Int).Mul(cw, hScalar))
+	batch.G.Add(batch.G, new(big.Int).Mul(cw, proof.taux))
+	for j := range V {
+		batch.addPoint(V[j], new(big.Int).Neg(new(big.Int).Mul(cw, zj[j+2])))
+	}
+	batch.addPoint(proof.T1, new(big.Int).Neg(new(big.Int).Mul(cw, x)))
+	batch.addPoint(proof.T2, new(big.Int).Neg(new(big.Int).Mul(cw, x2)))
+
+	//second equation, times weight
+	batch.addPoint(proof.A, weight)
+	batch.addPoint(proof.S, new(big.Int).Mul(weight, x))
+	for j := range xs {
+		xj2 := mod(new(big.Int).Mul(xs[j], xs[j]))
+		batch.addPoint(proof.ipp.L[j], new(big.Int).Mul(weight, xj2))
+		batch.addPoint(proof.ipp.R[j], new(big.Int).Mul(weight, inverse(xj2)))
+	}
+	batch.G.Sub(batch.G, new(big.Int).Mul(weight, proof.mu))
+	wab := new(big.Int).Mul(proof.ipp.a, proof.ipp.b)
+	batch.U.Add(batch.U, new(big.Int).Mul(weight, new(big.Int).Mul(w, new(big.Int).Sub(proof.t, wab))))
+	for j := 0; j < m; j++ {
+		for i := 0; i < n; i++ {
+			k := j*n + i
+			//Gi: -z - a*s_i
+			gi := new(big.Int).Add(z, new(big.Int).Mul(proof.ipp.a, s[k]))
+			batch.Gi[k].Sub(batch.Gi[k], new(big.Int).Mul(weight, gi))
+			//Hi: z + (z^(2+j)*2^i - b*s_i^-1)*y^-k
+			hi := new(big.Int).Mul(zj[j+2], twoN[i])
+			hi.Sub(hi, new(big.Int).Mul(proof.ipp.b, inverse(s[k])))
+			hi.Mul(hi, yinv[k])
+			hi.Add(hi, z)
+			batch.Hi[k].Add(batch.Hi[k], new(big.Int).Mul(weight, hi))
+		}
+	}
+	mod(batch.G)
+	mod(batch.H)
+	mod(batch.U)
+	return true
+}
+
+/*
+wellFormed returns true iff the proof has all its fields and log2(64*m)
+inner-product rounds.
+*/
+func (proof *RangeProof) wellFormed(m int) bool {
+	if proof == nil || proof.A == nil || proof.S == nil || proof.T1 == nil || proof.T2 == nil ||
+		proof.taux == nil || proof.mu == nil || proof.t == nil || proof.ipp == nil ||
+		proof.ipp.a == nil || proof.ipp.b == nil {
+		return false
+	}
+	rounds := 0
+	for N := BitSize * m; N > 1; N = N / 2 {
+		rounds++
+	}
+	if len(proof.ipp.L) != rounds || len(proof.ipp.R) != rounds {
+		return false
+	}
+	for j := range proof.ipp.L {
+		if proof.ipp.L[j] == nil || proof.ipp.R[j] == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package bulletproofs
+
+import (
+	"crypto/rand"
+	"log"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/blockchain-research/crypto/brs"
+)
+
+func randomValues(t *testing.T, m int) ([]*big.Int, []*big.Int) {
+	values := make([]*big.Int, m)
+	gammas := make([]*big.Int, m)
+	bound := new(big.Int).Lsh(big.NewInt(1), BitSize)
+	for j := 0; j < m; j++ {
+		values[j], _ = rand.Int(rand.Reader, bound)
+		gammas[j], _ = randomScalar()
+	}
+	return values, gammas
+}
+
+func commitments(params *Params, values, gammas []*big.Int) []*Point {
+	V := make([]*Point, len(values))
+	for j := range values {
+		V[j] = params.Commit(values[j], gammas[j])
+	}
+	return V
+}
+
+func TestRangeProof(t *testing.T) {
+	params, err := Setup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, gammas := randomValues(t, 1)
+
+	start := time.Now()
+	proof, err := params.Prove(values, gammas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("Prove took %s", time.Since(start))
+
+	V := commitments(params, values, gammas)
+	start = time.Now()
+	if !params.Verify(proof, V) {
+		t.Errorf("Proof verification failed")
+	}
+	log.Printf("Verify took %s", time.Since(start))
+
+	//the commitment follows the brs convention
+	hx, hy, _ := brs.GeneratorH(brs.GeneratorNUMS)
+	cx, cy := brs.Commit(values[0], gammas[0], hx, hy)
+	if !V[0].equal(&Point{cx, cy}) {
+		t.Errorf("Commitment differs from brs.Commit")
+	}
+
+	//a proof must not verify against another commitment
+	other := params.Commit(new(big.Int).Add(values[0], big.NewInt(1)), gammas[0])
+	if params.Verify(proof, []*Point{other}) {
+		t.Errorf("Proof verified against the wrong commitment")
+	}
+
+	//values of 2^64 or more cannot be proven
+	tooBig := new(big.Int).Lsh(big.NewInt(1), BitSize)
+	if _, err := params.Prove([]*big.Int{tooBig}, gammas); err == nil {
+		t.Errorf("expected error for value out of range")
+	}
+}
+
+func TestAggregatedRangeProof(t *testing.T) {
+	params, err := Setup(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []int{1, 2, 4} {
+		values, gammas := randomValues(t, m)
+		proof, err := params.Prove(values, gammas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		V := commitments(params, values, gammas)
+		if !params.Verify(proof, V) {
+			t.Errorf("Aggregated proof verification failed for m=%d", m)
+		}
+		//a different order of commitments must be rejected
+		if m > 1 {
+			V[0], V[1] = V[1], V[0]
+			if params.Verify(proof, V) {
+				t.Errorf("Aggregated proof verified with swapped commitments")
+			}
+		}
+	}
+	if _, err := params.Prove(make([]*big.Int, 3), make([]*big.Int, 3)); err == nil {
+		t.Errorf("expected error for m not a power of two")
+	}
+	if _, err := Setup(3); err == nil {
+		t.Errorf("expected error for m not a power of two")
+	}
+}
+
+func TestBatchVerify(t *testing.T) {
+	params, err := Setup(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var proofs []*RangeProof
+	var V [][]*Point
+	for _, m := range []int{1, 2, 2} {
+		values, gammas := randomValues(t, m)
+		proof, err := params.Prove(values, gammas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proofs = append(proofs, proof)
+		V = append(V, commitments(params, values, gammas))
+	}
+	if !params.BatchVerify(proofs, V) {
+		t.Errorf("Batch verification failed")
+	}
+
+	//one bad proof fails the batch
+	V[1][0] = V[1][0].add(params.H)
+	if params.BatchVerify(proofs, V) {
+		t.Errorf("Batch verification accepted an invalid proof")
+	}
+}
+
+func TestRangeProofMarshal(t *testing.T) {
+	params, err := Setup(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, gammas := randomValues(t, 2)
+	proof, err := params.Prove(values, gammas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//logarithmic size: 2*log2(128)+4 points and 5 scalars
+	if len(data) != 2+(2*7+4)*pointLen+5*scalarLen {
+		t.Errorf("unexpected proof length %d", len(data))
+	}
+	proof2 := &RangeProof{}
+	if err := proof2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !params.Verify(proof2, commitments(params, values, gammas)) {
+		t.Errorf("Proof verification failed after unmarshal")
+	}
+
+	for i := 0; i < len(data); i += 7 {
+		if err := new(RangeProof).UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("expected error for truncated proof of length %d", i)
+		}
+	}
+
+	//the point at infinity round-trips
+	proof2.T1 = infinity()
+	data, err = proof2.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof3 := &RangeProof{}
+	if err := proof3.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !proof3.T1.isInfinity() {
+		t.Errorf("point at infinity not preserved")
+	}
+	//a zero prefix with a nonzero coordinate is not the point at infinity
+	data[2+2*pointLen+1] = 1
+	if err := new(RangeProof).UnmarshalBinary(data); err == nil {
+		t.Errorf("expected error for invalid point encoding")
+	}
+
+	//missing fields are reported instead of dereferenced
+	if _, err := new(RangeProof).MarshalBinary(); err == nil {
+		t.Errorf("expected error for zero-value proof")
+	}
+	proof3.S = nil
+	if _, err := proof3.MarshalBinary(); err == nil {
+		t.Errorf("expected error for proof without S")
+	}
+}
@@ -0,0 +1,182 @@
+package bulletproofs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/brs"
+	"github.com/btcsuite/btcd/btcec"
+)
+
+var curve = btcec.S256()
+
+/*
+Point is an affine point on secp256k1. The point at infinity is (0, 0), which
+is the convention used by btcec.
+*/
+type Point struct {
+	X, Y *big.Int
+}
+
+func infinity() *Point {
+	return &Point{new(big.Int), new(big.Int)}
+}
+
+func (P *Point) isInfinity() bool {
+	return P.X.Sign() == 0 && P.Y.Sign() == 0
+}
+
+func (P *Point) add(Q *Point) *Point {
+	x, y := curve.Add(P.X, P.Y, Q.X, Q.Y)
+	return &Point{x, y}
+}
+
+func (P *Point) mul(k *big.Int) *Point {
+	k = new(big.Int).Mod(k, curve.N)
+	if k.Sign() == 0 || P.isInfinity() {
+		return infinity()
+	}
+	x, y := curve.ScalarMult(P.X, P.Y, k.Bytes())
+	return &Point{x, y}
+}
+
+func (P *Point) neg() *Point {
+	if P.isInfinity() {
+		return infinity()
+	}
+	return &Point{new(big.Int).Set(P.X), new(big.Int).Sub(curve.P, P.Y)}
+}
+
+func (P *Point) equal(Q *Point) bool {
+	return P.X.Cmp(Q.X) == 0 && P.Y.Cmp(Q.Y) == 0
+}
+
+/*
+multiExp computes sum(scalars[i]*points[i]).
+*/
+func multiExp(points []*Point, scalars []*big.Int) *Point {
+	ps := make([][]*big.Int, len(points))
+	for i, P := range points {
+		ps[i] = []*big.Int{P.X, P.Y}
+	}
+	x, y := brs.MultiScalarMult(ps, scalars)
+	return &Point{x, y}
+}
+
+func randomScalar() (*big.Int, error) {
+	for {
+		k, err := rand.Int(rand.Reader, curve.N)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() > 0 {
+			return k, nil
+		}
+	}
+}
+
+func mod(a *big.Int) *big.Int {
+	return a.Mod(a, curve.N)
+}
+
+func inverse(a *big.Int) *big.Int {
+	return new(big.Int).ModInverse(a, curve.N)
+}
+
+/*
+powers returns [1, x, x^2, ..., x^(n-1)] modulo N.
+*/
+func powers(x *big.Int, n int) []*big.Int {
+	ret := make([]*big.Int, n)
+	cur := big.NewInt(1)
+	for i := range ret {
+		ret[i] = cur
+		cur = mod(new(big.Int).Mul(cur, x))
+	}
+	return ret
+}
+
+func innerProduct(a, b []*big.Int) *big.Int {
+	sum := new(big.Int)
+	for i := range a {
+		sum.Add(sum, new(big.Int).Mul(a[i], b[i]))
+	}
+	return mod(sum)
+}
+
+func vectorAdd(a, b []*big.Int) []*big.Int {
+	ret := make([]*big.Int, len(a))
+	for i := range a {
+		ret[i] = mod(new(big.Int).Add(a[i], b[i]))
+	}
+	return ret
+}
+
+func vectorHadamard(a, b []*big.Int) []*big.Int {
+	ret := make([]*big.Int, len(a))
+	for i := range a {
+		ret[i] = mod(new(big.Int).Mul(a[i], b[i]))
+	}
+	return ret
+}
+
+func vectorScale(a []*big.Int, k *big.Int) []*big.Int {
+	ret := make([]*big.Int, len(a))
+	for i := range a {
+		ret[i] = mod(new(big.Int).Mul(a[i], k))
+	}
+	return ret
+}
+
+func vectorAddScalar(a []*big.Int, k *big.Int) []*big.Int {
+	ret := make([]*big.Int, len(a))
+	for i := range a {
+		ret[i] = mod(new(big.Int).Add(a[i], k))
+	}
+	return ret
+}
+
+/*
+transcript derives Fiat-Shamir challenges from everything absorbed so far.
+Each challenge is H(state || data) reduced modulo N and becomes the new state.
+*/
+type transcript struct {
+	state []byte
+}
+
+func newTranscript(label string) *transcript {
+	h := sha256.Sum256([]byte(label))
+	return &transcript{state: h[:]}
+}
+
+func (t *transcript) appendPoints(points ...*Point) {
+	digest := sha256.New()
+	digest.Write(t.state)
+	for _, P := range points {
+		digest.Write(encodePoint(P))
+	}
+	t.state = digest.Sum(nil)
+}
+
+func (t *transcript) appendScalars(scalars ...*big.Int) {
+	digest := sha256.New()
+	digest.Write(t.state)
+	for _, k := range scalars {
+		digest.Write(encodeScalar(k))
+	}
+	t.state = digest.Sum(nil)
+}
+
+func (t *transcript) challenge() *big.Int {
+	for {
+		digest := sha256.New()
+		digest.Write(t.state)
+		digest.Write([]byte("challenge"))
+		t.state = digest.Sum(nil)
+		c := mod(new(big.Int).SetBytes(t.state))
+		if c.Sign() != 0 {
+			return c
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package ccs08
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+// batchWeightBits is the size of the random coefficients used to combine the
+// checks of a batch. A batch containing an invalid proof passes with
+// probability at most 2^-batchWeightBits.
+const batchWeightBits = 128
+
+/*
+VerifyBatch validates many [a,b) range proofs at once, proofs[i] against the
+commitment cms[i] as in Verify. It returns true and -1 if every proof is valid.
+Otherwise it returns false and the index of the first invalid proof, found by
+verifying the proofs one by one.
+*/
+func (verifier *Verifier) VerifyBatch(proofs []*Proof, cms []*bn256.G2) (bool, int, error) {
+	if len(proofs) != len(cms) {
+		return false, -1, errors.New("number of proofs and commitments differ")
+	}
+	uls := make([]*ProofUL, 0, 2*len(proofs))
+	for i, proof := range proofs {
+		if proof == nil || cms[i] == nil || !verifier.wellFormed(proof.proof1) || !verifier.wellFormed(proof.proof2) ||
+			!verifier.matchesCommitment(proof, cms[i]) {
+			return false, i, nil
+		}
+		uls = append(uls, proof.proof1, proof.proof2)
+	}
+	ok, err := verifier.verifyULBatch(uls)
+	if err != nil {
+		return false, -1, err
+	}
+	if ok {
+		return true, -1, nil
+	}
+	for i, proof := range proofs {
+		ok, err := verifier.Verify(proof, cms[i])
+		if err != nil {
+			return false, -1, err
+		}
+		if !ok {
+			return false, i, nil
+		}
+	}
+	return false, -1, errors.New("batch verification failed but every proof is valid")
+}
+
+/*
+VerifyULBatch is VerifyBatch for [0,u^l) range proofs.
+*/
+func (v *Verifier) VerifyULBatch(proofs []*ProofUL) (bool, int, error) {
+	for i, proof := range proofs {
+		if !v.wellFormed(proof) {
+			return false, i, nil
+		}
+	}
+	ok, err := v.verifyULBatch(proofs)
+	if err != nil {
+		return false, -1, err
+	}
+	if ok {
+		return true, -1, nil
+	}
+	for i, proof := range proofs {
+		ok, err := v.VerifyUL(proof)
+		if err != nil {
+			return false, -1, err
+		}
+		if !ok {
+			return false, i, nil
+		}
+	}
+	return false, -1, errors.New("batch verification failed but every proof is valid")
+}
+
+/*
+verifyULBatch combines the checks of VerifyUL for all proofs with random
+weights. For proof j with weight d_j the commitment checks become the single
+G2 equation
+
+	sum(d_j.c_j.C_j) + (sum(d_j.zr_j)).H + (sum(d_j.sum(zsig_ji.u^i))).g - sum(d_j.D_j) == 0
+
+checked with one multi-scalar multiplication, and for every digit i with
+weight w_ji the checks a_ji == e(y,V_ji)^c_j.e(g,V_ji)^-zsig_ji.e(g,g)^zv_ji
+become the single multi-pairing
+
+	prod(a_ji^w_ji) == prod(e(w_ji.(c_j.y - zsig_ji.g), V_ji)).e(sum(w_ji.zv_ji).g, g)
+
+whose left side is one multi-exponentiation in GT.
+*/
+func (v *Verifier) verifyULBatch(proofs []*ProofUL) (bool, error) {
+	if len(proofs) == 0 {
+		return true, nil
+	}
+	var (
+		points  []*bn256.G2
+		scalars []*big.Int
+		g1s     []*bn256.G1
+		g2s     []*bn256.G2
+		as      []*bn256.GT
+		ws      []*big.Int
+	)
+	zr := new(big.Int)
+	zsig := new(big.Int)
+	zv := new(big.Int)
+
+	for _, proof := range proofs {
+		if !v.validChallenge(proof) {
+			return false, nil
+		}
+		d, err := batchWeight()
+		if err != nil {
+			return false, err
+		}
+		points = append(points, proof.C, proof.D)
+		scalars = append(scalars, Mod(Multiply(d, proof.c), bn256.Order), Mod(new(big.Int).Neg(d), bn256.Order))
+		zr.Add(zr, Multiply(d, proof.zr))
+
+		ui := big.NewInt(1)
+		u := big.NewInt(v.params.u)
+		for i := int64(0); i < v.params.l; i++ {
+			zsig.Add(zsig, Multiply(d, Multiply(proof.zsig[i], ui)))
+			ui = Multiply(ui, u)
+
+			w, err := batchWeight()
+			if err != nil {
+				return false, err
+			}
+			g1s = append(g1s, new(bn256.G1).ScalarMult(v.digitG1(proof.c, proof.zsig[i]), w))
+			g2s = append(g2s, proof.V[i])
+			as = append(as, proof.a[i])
+			ws = append(ws, w)
+			zv.Add(zv, Multiply(w, proof.zv[i]))
+		}
+	}
+	points = append(points, v.params.H, G2)
+	scalars = append(scalars, Mod(zr, bn256.Order), Mod(zsig, bn256.Order))
+	if !bn256.MultiScalarMultG2(points, scalars).IsZero() {
+		return false, nil
+	}
+
+	g1s = append(g1s, new(bn256.G1).ScalarBaseMult(Mod(zv, bn256.Order)))
+	g2s = append(g2s, G2)
+	a := bn256.MultiScalarMultGT(as, ws)
+	return bytes.Equal(bn256.MultiPair(g1s, g2s).Marshal(), a.Marshal()), nil
+}
+
+/*
+wellFormed reports whether proof has the shape VerifyUL expects, so that
+verifying it cannot panic.
+*/
+func (v *Verifier) wellFormed(proof *ProofUL) bool {
+	l := int(v.params.l)
+	if proof == nil || proof.D == nil || proof.C == nil || proof.c == nil || proof.zr == nil ||
+		len(proof.V) != l || len(proof.a) != l || len(proof.zsig) != l || len(proof.zv) != l {
+		return false
+	}
+	for i := 0; i < l; i++ {
+		if proof.V[i] == nil || proof.a[i] == nil || proof.zsig[i] == nil || proof.zv[i] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func batchWeight() (*big.Int, error) {
+	w, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), batchWeightBits))
+	if err != nil {
+		return nil, err
+	}
+	return w.Add(w, big.NewInt(1)), nil
+}
@@ -26,48 +26,36 @@ import (
 
 /*
 SetupUL generates the signature for the interval [0,u^l).
+It runs an Issuer and discards it, so the private key is not kept anywhere;
+use NewIssuer to keep the issuer apart from the provers.
 The value of u should be roughly b/log(b), but we can choose smaller values in
 order to get smaller parameters, at the cost of having worse performance.
 SetupUL returns Prover and Verifier struct
 */
 func SetupUL(u, l int64) (*Prover, *Verifier, error) {
+	return setupUL(u, l, GeneratorHashToCurve)
+}
 
-	var (
-		i   int64
-		err error
-	)
-	prover := &Prover{
-		params: &ParamsULProver{},
-	}
-	verifier := &Verifier{
-		params: &ParamsULVerifier{},
-	}
+/*
+SetupULLegacy is SetupUL with the legacy commitment generator H, whose discrete
+logarithm is public. Use it only to verify commitments made before
+GeneratorNUMS was introduced.
+*/
+func SetupULLegacy(u, l int64) (*Prover, *Verifier, error) {
+	return setupUL(u, l, GeneratorLegacy)
+}
 
-	prover.params.kp, err = keygen()
+func setupUL(u, l int64, version GeneratorVersion) (*Prover, *Verifier, error) {
+	issuer, err := newIssuer(u, l, version)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	prover.params.signatures = make(map[string]*bn256.G2)
-	for i = 0; i < u; i++ {
-		sig_i, err := sign(new(big.Int).SetInt64(i), prover.params.kp.privk)
-		if err != nil {
-			return nil, nil, err
-		}
-		prover.params.signatures[strconv.FormatInt(i, 10)] = sig_i
+	pp, err := issuer.Params()
+	if err != nil {
+		return nil, nil, err
 	}
-	//TODO: protect the 'master' key
-	h := GetBigInt("18560948149108576432482904553159745978835170526553990798435819795989606410925")
-	prover.params.H = new(bn256.G2).ScalarBaseMult(h)
-	prover.params.u = u
-	prover.params.l = l
-
-	verifier.params.H = prover.params.H
-	verifier.params.u = prover.params.u
-	verifier.params.l = prover.params.l
-	verifier.params.pubk = prover.params.kp.pubk
-
-	return prover, verifier, nil
+	// the issuer, and with it the private key, is dropped here
+	return newProver(pp, 0, 0), newVerifier(pp, 0, 0), nil
 }
 
 /*
@@ -100,7 +88,7 @@ func (p *Prover) ProveUL(x, r *big.Int, cm *bn256.G2) (*ProofUL, error) {
 	}
 
 	// D = H^m
-	D := new(bn256.G2).ScalarMult(p.params.H, m)
+	D := scalarMultH(p.params.H, m)
 	for i = 0; i < p.params.l; i++ {
 		v[i], err = rand.Int(rand.Reader, bn256.Order)
 		if err != nil {
@@ -137,11 +125,7 @@ func (p *Prover) ProveUL(x, r *big.Int, cm *bn256.G2) (*ProofUL, error) {
 	// so that it is possible to delegate the commitment computation to an external party.
 	proof_out.C = cm //Commit(x, r, p.H)
 	// Fiat-Shamir heuristic
-	proof_out.c, err = Hash(proof_out.a, proof_out.D)
-	if err != nil {
-		return nil, err
-	}
-	proof_out.c = Mod(proof_out.c, bn256.Order)
+	proof_out.c = challengeUL(p.params.H, p.params.pubk, p.params.u, p.params.l, &proof_out)
 
 	proof_out.zr = Sub(m, Multiply(r, proof_out.c))
 	proof_out.zr = Mod(proof_out.zr, bn256.Order)
@@ -163,18 +147,24 @@ func (v *Verifier) VerifyUL(proof *ProofUL) (bool, error) {
 		i      int64
 		D      *bn256.G2
 		r1, r2 bool
-		p1, p2 *bn256.GT
+		p1     *bn256.GT
 	)
+	if !v.wellFormed(proof) {
+		return false, errors.New("malformed proof")
+	}
+	if !v.validChallenge(proof) {
+		return false, nil
+	}
 	// D == C^c.h^ zr.g^zsig ?
-	D = new(bn256.G2).ScalarMult(proof.C, proof.c)
-	D.Add(D, new(bn256.G2).ScalarMult(v.params.H, proof.zr))
+	zsig := new(big.Int)
 	for i = 0; i < v.params.l; i++ {
 		ui := new(big.Int).Exp(new(big.Int).SetInt64(v.params.u), new(big.Int).SetInt64(i), nil)
-		muizsigi := new(big.Int).Mul(proof.zsig[i], ui)
-		muizsigi = Mod(muizsigi, bn256.Order)
-		aux := new(bn256.G2).ScalarBaseMult(muizsigi)
-		D.Add(D, aux)
+		zsig.Add(zsig, new(big.Int).Mul(proof.zsig[i], ui))
 	}
+	D = bn256.MultiScalarMultG2(
+		[]*bn256.G2{proof.C, v.params.H, G2},
+		[]*big.Int{proof.c, proof.zr, Mod(zsig, bn256.Order)},
+	)
 
 	DBytes := D.Marshal()
 	pDBytes := proof.D.Marshal()
@@ -183,13 +173,11 @@ func (v *Verifier) VerifyUL(proof *ProofUL) (bool, error) {
 	r2 = true
 	for i = 0; i < v.params.l; i++ {
 		// a == [e(V,y)^c].[e(V,g)^-zsig].[e(g,g)^zv]
-		p1 = bn256.Pair(v.params.pubk, proof.V[i])
-		p1.ScalarMult(p1, proof.c)
-		p2 = bn256.Pair(G1, proof.V[i])
-		p2.ScalarMult(p2, proof.zsig[i])
-		p2.Invert(p2)
-		p1.Add(p1, p2)
-		p1.Add(p1, new(bn256.GT).ScalarMult(E, proof.zv[i]))
+		//   == e(c.y - zsig.g, V).e(zv.g, g)
+		p1 = bn256.MultiPair(
+			[]*bn256.G1{v.digitG1(proof.c, proof.zsig[i]), new(bn256.G1).ScalarBaseMult(proof.zv[i])},
+			[]*bn256.G2{proof.V[i], G2},
+		)
 
 		pBytes := p1.Marshal()
 		aBytes := proof.a[i].Marshal()
@@ -198,6 +186,28 @@ func (v *Verifier) VerifyUL(proof *ProofUL) (bool, error) {
 	return r1 && r2, nil
 }
 
+/*
+validChallenge checks that proof.c is the challenge of the proof's transcript,
+so that the prover could not choose it.
+*/
+func (v *Verifier) validChallenge(proof *ProofUL) bool {
+	c := challengeUL(v.params.H, v.params.pubk, v.params.u, v.params.l, proof)
+	return c.Cmp(proof.c) == 0
+}
+
+/*
+digitG1 returns c.y - zsig.g, so that e(y,V)^c.e(g,V)^-zsig = e(c.y - zsig.g, V).
+*/
+func (v *Verifier) digitG1(c, zsig *big.Int) *bn256.G1 {
+	var g1 *bn256.G1
+	if v.params.pubkBase != nil {
+		g1 = v.params.pubkBase.ScalarMult(c)
+	} else {
+		g1 = new(bn256.G1).ScalarMult(v.params.pubk, c)
+	}
+	return g1.Add(g1, new(bn256.G1).ScalarBaseMult(Mod(new(big.Int).Neg(zsig), bn256.Order)))
+}
+
 //Setup, Prove, Verify generates the parameters, generate proof, verify proof
 //for the case where the range is in [a,b)
 /*
@@ -226,14 +236,14 @@ func Setup(a, b int64) (*Prover, *Verifier, error) {
 				l = l + 1
 			}
 			prover, verifier, err := SetupUL(u, l)
-			prover.a = a
-			prover.b = b
-			verifier.a = a
-			verifier.b = b
 			if err != nil {
 				fmt.Println("SetupUL error")
 				return nil, nil, err
 			}
+			prover.a = a
+			prover.b = b
+			verifier.a = a
+			verifier.b = b
 			return prover, verifier, nil
 		}
 		return nil, nil, errors.New("u is zero")
@@ -273,9 +283,18 @@ func (prover *Prover) Prove(x, r *big.Int) (*Proof, error) {
 }
 
 /*
-Verify is responsible for validating the proof.
+Verify is responsible for validating the proof against the commitment
+cm = Commit(x, r, H) held by the verifier. The two halves of the proof must be
+for cm.g^(u^l-b) and cm.g^-a, the commitments to x-b+u^l and x-a, so that
+together they prove a <= x < b for the x committed in cm.
 */
-func (verifier *Verifier) Verify(proof *Proof) (bool, error) {
+func (verifier *Verifier) Verify(proof *Proof, cm *bn256.G2) (bool, error) {
+	if proof == nil || cm == nil || !verifier.wellFormed(proof.proof1) || !verifier.wellFormed(proof.proof2) {
+		return false, errors.New("malformed proof")
+	}
+	if !verifier.matchesCommitment(proof, cm) {
+		return false, nil
+	}
 	first, err := verifier.VerifyUL(proof.proof1)
 	if err != nil {
 		fmt.Println("Failed to verifyUL")
@@ -288,3 +307,19 @@ func (verifier *Verifier) Verify(proof *Proof) (bool, error) {
 	}
 	return first && second, nil
 }
+
+/*
+matchesCommitment checks that the halves of the well-formed proof are for the
+commitments cm.g^(u^l-b) and cm.g^-a.
+*/
+func (verifier *Verifier) matchesCommitment(proof *Proof, cm *bn256.G2) bool {
+	ul := new(big.Int).Exp(new(big.Int).SetInt64(verifier.params.u), new(big.Int).SetInt64(verifier.params.l), nil)
+	// x - b + ul
+	shift := Mod(new(big.Int).Sub(ul, new(big.Int).SetInt64(verifier.b)), bn256.Order)
+	first := new(bn256.G2).Add(cm, new(bn256.G2).ScalarBaseMult(shift))
+	// x - a
+	shift = Mod(new(big.Int).SetInt64(-verifier.a), bn256.Order)
+	second := new(bn256.G2).Add(cm, new(bn256.G2).ScalarBaseMult(shift))
+	return bytes.Equal(proof.proof1.C.Marshal(), first.Marshal()) &&
+		bytes.Equal(proof.proof2.C.Marshal(), second.Marshal())
+}
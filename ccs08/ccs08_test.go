@@ -1,10 +1,18 @@
 package ccs08
 
 import (
+	"bytes"
 	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"math/big"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/blockchain-research/crypto/bn256"
 )
@@ -66,12 +74,16 @@ func TestZKRP_UL_Marshal(t *testing.T) {
 	//marshal and unmarshal verifier
 	paramBytes := verifier.Marshal()
 	verifier2 := &Verifier{}
-	verifier2.Unmarshal(paramBytes)
+	if err := verifier2.Unmarshal(paramBytes); err != nil {
+		t.Fatal(err)
+	}
 
 	//marshal and unmarshal proof
 	proofBytes := proof.Marshal()
 	proof2 := &ProofUL{}
-	Unmarshal(proofBytes, proof2)
+	if err := Unmarshal(proofBytes, proof2); err != nil {
+		t.Fatal(err)
+	}
 
 	//verifier verify the proof
 	result, err := verifier2.VerifyUL(proof2)
@@ -99,7 +111,7 @@ func TestZKRP(t *testing.T) {
 	//generate the pedersen commitment
 	r, _ := rand.Int(rand.Reader, bn256.Order)
 	x := new(big.Int).SetInt64(76)
-	//cm, _ := Commit(x, r, prover.params.H)
+	cm, _ := Commit(x, r, prover.params.H)
 
 	//prover generate the proof
 	proof, err := prover.Prove(x, r)
@@ -109,7 +121,7 @@ func TestZKRP(t *testing.T) {
 	}
 
 	//verifier verify the proof
-	result, err := verifier.Verify(proof)
+	result, err := verifier.Verify(proof, cm)
 	if err != nil {
 		t.Errorf("failed to verify")
 		t.FailNow()
@@ -121,3 +133,995 @@ func TestZKRP(t *testing.T) {
 	}
 	t.Log("The value is in the range [a,b)")
 }
+
+func TestVerifyCommitment(t *testing.T) {
+	prover, verifier, err := Setup(10, 100)
+	if err != nil {
+		t.FailNow()
+	}
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	proof, err := prover.Prove(new(big.Int).SetInt64(50), r)
+	if err != nil {
+		t.FailNow()
+	}
+	cm, _ := Commit(new(big.Int).SetInt64(50), r, prover.params.H)
+	if result, err := verifier.Verify(proof, cm); err != nil || !result {
+		t.Errorf("rejected a valid proof")
+	}
+
+	//a proof for an in-range value does not prove anything about another commitment
+	for _, x := range []int64{500, 51, 5} {
+		other, _ := Commit(new(big.Int).SetInt64(x), r, prover.params.H)
+		if result, err := verifier.Verify(proof, other); err != nil || result {
+			t.Errorf("accepted a proof for 50 against a commitment to %d", x)
+		}
+	}
+	r2, _ := rand.Int(rand.Reader, bn256.Order)
+	other, _ := Commit(new(big.Int).SetInt64(50), r2, prover.params.H)
+	if result, _ := verifier.Verify(proof, other); result {
+		t.Errorf("accepted a proof against a commitment with other randomness")
+	}
+	if _, err := verifier.Verify(proof, nil); err == nil {
+		t.Errorf("expected an error without a commitment")
+	}
+}
+
+func TestGeneratorVersion(t *testing.T) {
+	prover, verifier, err := SetupUL(10, 5)
+	if err != nil {
+		t.FailNow()
+	}
+	H, _ := GeneratorH(GeneratorHashToCurve)
+	if !bytes.Equal(prover.params.H.Marshal(), H.Marshal()) ||
+		!bytes.Equal(verifier.params.H.Marshal(), H.Marshal()) {
+		t.Errorf("SetupUL does not use the hash-to-curve generator")
+	}
+	if !bytes.Equal(H.Marshal(), bn256.HashToG2(nil, []byte(GeneratorHashToCurveDST)).Marshal()) {
+		t.Errorf("hash-to-curve generator is not reproducible from the tag")
+	}
+
+	//the NUMS generator of earlier parameters is still available
+	nums, _ := GeneratorH(GeneratorNUMS)
+	if !bytes.Equal(nums.Marshal(), bn256.HashG2(nil, []byte(GeneratorDST)).Marshal()) {
+		t.Errorf("NUMS generator is not reproducible from the tag")
+	}
+	if bytes.Equal(nums.Marshal(), H.Marshal()) {
+		t.Errorf("NUMS and hash-to-curve generators coincide")
+	}
+	numsProver, numsVerifier, err := setupUL(10, 5, GeneratorNUMS)
+	if err != nil {
+		t.FailNow()
+	}
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	x := new(big.Int).SetInt64(123)
+	cm, _ := Commit(x, r, numsProver.params.H)
+	proof, err := numsProver.ProveUL(x, r, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numsVerifier2 := &Verifier{}
+	if err := numsVerifier2.Unmarshal(numsVerifier.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := numsVerifier2.VerifyUL(proof); !result {
+		t.Errorf("proof under the NUMS generator failed to verify after decoding")
+	}
+
+	legacy, _, err := SetupULLegacy(10, 5)
+	if err != nil {
+		t.FailNow()
+	}
+	if bytes.Equal(legacy.params.H.Marshal(), H.Marshal()) {
+		t.Errorf("legacy and NUMS generators coincide")
+	}
+	if _, err := GeneratorH(0); err == nil {
+		t.Errorf("expected error for unknown generator version")
+	}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	prover, verifier, err := Setup(0, 100)
+	if err != nil {
+		t.FailNow()
+	}
+	var proofs []*Proof
+	var cms []*bn256.G2
+	for _, x := range []int64{0, 42, 99} {
+		r, _ := rand.Int(rand.Reader, bn256.Order)
+		proof, err := prover.Prove(new(big.Int).SetInt64(x), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cm, _ := Commit(new(big.Int).SetInt64(x), r, prover.params.H)
+		proofs = append(proofs, proof)
+		cms = append(cms, cm)
+	}
+	ok, bad, err := verifier.VerifyBatch(proofs, cms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || bad != -1 {
+		t.Errorf("Batch verification failed")
+	}
+
+	//tamper with the commitment of the second proof
+	proofs[1].proof2.C = new(bn256.G2).Add(proofs[1].proof2.C, G2)
+	ok, bad, err = verifier.VerifyBatch(proofs, cms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok || bad != 1 {
+		t.Errorf("expected proof 1 to be rejected, got %t, %d", ok, bad)
+	}
+
+	//a malformed proof is reported without panicking
+	proofs[1] = &Proof{proof1: proofs[0].proof1, proof2: &ProofUL{}}
+	if ok, bad, _ := verifier.VerifyBatch(proofs, cms); ok || bad != 1 {
+		t.Errorf("expected malformed proof 1 to be rejected, got %t, %d", ok, bad)
+	}
+
+	//every proof needs its commitment
+	if _, _, err := verifier.VerifyBatch(proofs, cms[:2]); err == nil {
+		t.Errorf("expected an error for missing commitments")
+	}
+}
+
+func TestVerifyULBatch(t *testing.T) {
+	prover, verifier, err := SetupUL(10, 3)
+	if err != nil {
+		t.FailNow()
+	}
+	var proofs []*ProofUL
+	for _, x := range []int64{7, 176, 999} {
+		r, _ := rand.Int(rand.Reader, bn256.Order)
+		cm, _ := Commit(new(big.Int).SetInt64(x), r, prover.params.H)
+		proof, err := prover.ProveUL(new(big.Int).SetInt64(x), r, cm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proofs = append(proofs, proof)
+	}
+	if ok, bad, err := verifier.VerifyULBatch(proofs); err != nil || !ok || bad != -1 {
+		t.Errorf("Batch verification failed")
+	}
+
+	//a digit response that does not match its pairing check
+	proofs[2].zv[1] = Add(proofs[2].zv[1], big.NewInt(1))
+	if ok, bad, _ := verifier.VerifyULBatch(proofs); ok || bad != 2 {
+		t.Errorf("expected proof 2 to be rejected, got %t, %d", ok, bad)
+	}
+}
+
+func TestBonehBoyenSignature(t *testing.T) {
+	kp, err := keygen()
+	if err != nil {
+		t.FailNow()
+	}
+	m := new(big.Int).SetInt64(7)
+	sig, err := sign(m, kp.privk)
+	if err != nil {
+		t.FailNow()
+	}
+	if ok, err := verify(sig, m, kp.pubk); err != nil || !ok {
+		t.Errorf("Signature verification failed")
+	}
+	if ok, _ := verify(sig, new(big.Int).SetInt64(8), kp.pubk); ok {
+		t.Errorf("Signature verified for another message")
+	}
+}
+
+func TestZKRP_UL_MarshalCompressed(t *testing.T) {
+	prover, verifier, err := SetupUL(10, 5)
+	if err != nil {
+		t.FailNow()
+	}
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	x := new(big.Int).SetInt64(176)
+	cm, _ := Commit(x, r, prover.params.H)
+	proof, err := prover.ProveUL(x, r, cm)
+	if err != nil {
+		t.FailNow()
+	}
+
+	proofBytes := proof.MarshalCompressed()
+	if saved := len(proof.Marshal()) - len(proofBytes); saved != 7*63 {
+		t.Errorf("compression saved %d bytes", saved)
+	}
+	proof2 := &ProofUL{}
+	if err := UnmarshalCompressed(proofBytes, proof2); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := verifier.VerifyUL(proof2); !result {
+		t.Errorf("Proof verification failed after compressed round trip")
+	}
+
+	if err := UnmarshalCompressed(proofBytes[1:], &ProofUL{}); err == nil {
+		t.Errorf("expected error for truncated proof")
+	}
+	bad := append([]byte{}, proofBytes...)
+	bad[headerSize+4] ^= 0xff
+	if err := UnmarshalCompressed(bad, &ProofUL{}); err == nil {
+		t.Errorf("expected error for invalid point")
+	}
+	//Unmarshal accepts either encoding, UnmarshalCompressed only its own
+	if err := Unmarshal(proofBytes, &ProofUL{}); err != nil {
+		t.Error(err)
+	}
+	if err := UnmarshalCompressed(proof.Marshal(), &ProofUL{}); err == nil {
+		t.Errorf("expected error for uncompressed proof")
+	}
+}
+
+func TestVerifyULMalformed(t *testing.T) {
+	prover, verifier, err := SetupUL(10, 3)
+	if err != nil {
+		t.FailNow()
+	}
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	x := new(big.Int).SetInt64(17)
+	cm, _ := Commit(x, r, prover.params.H)
+	proof, err := prover.ProveUL(x, r, cm)
+	if err != nil {
+		t.FailNow()
+	}
+
+	//a G2 element that fails to decode is reported by Unmarshal
+	proofBytes := proof.Marshal()
+	proofBytes[headerSize+4] ^= 0xff
+	if err := Unmarshal(proofBytes, &ProofUL{}); err == nil {
+		t.Errorf("expected error for malformed proof")
+	}
+	if err := Unmarshal(proofBytes[:100], &ProofUL{}); err == nil {
+		t.Errorf("expected error for short proof")
+	}
+
+	//an incomplete proof is still rejected by VerifyUL
+	if result, err := verifier.VerifyUL(&ProofUL{}); err == nil || result {
+		t.Errorf("expected error for malformed proof")
+	}
+}
+
+func TestMarshalProof(t *testing.T) {
+	prover, verifier, err := Setup(18, 200)
+	if err != nil {
+		t.FailNow()
+	}
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	x := new(big.Int).SetInt64(40)
+	cm, _ := Commit(x, r, prover.params.H)
+	proof, err := prover.Prove(x, r)
+	if err != nil {
+		t.FailNow()
+	}
+
+	prover2 := &Prover{}
+	if err := prover2.Unmarshal(prover.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if prover2.a != 18 || prover2.b != 200 {
+		t.Errorf("bounds not preserved: [%d, %d)", prover2.a, prover2.b)
+	}
+	verifier2 := &Verifier{}
+	if err := verifier2.Unmarshal(verifier.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if verifier2.a != 18 || verifier2.b != 200 {
+		t.Errorf("bounds not preserved: [%d, %d)", verifier2.a, verifier2.b)
+	}
+
+	//a proof from the restored prover verifies with the restored verifier
+	proof2, err := prover2.Prove(x, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof3 := &Proof{}
+	if err := proof3.Unmarshal(proof2.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := verifier2.Verify(proof3, cm); err != nil || !result {
+		t.Errorf("restored proof failed to verify: %v", err)
+	}
+	proof4 := &Proof{}
+	if err := proof4.Unmarshal(proof.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := verifier.Verify(proof4, cm); err != nil || !result {
+		t.Errorf("proof failed to verify after round trip: %v", err)
+	}
+	proof5 := &Proof{}
+	if err := proof5.Unmarshal(proof.MarshalCompressed()); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := verifier.Verify(proof5, cm); err != nil || !result {
+		t.Errorf("proof failed to verify after compressed round trip: %v", err)
+	}
+
+	//the headerless encoding of earlier versions still decodes
+	legacy := append(verifier.params.H.Marshal(), verifier.params.pubk.Marshal()...)
+	for _, x := range []int64{verifier.params.u, verifier.params.l} {
+		b := make([]byte, binary.MaxVarintLen64)
+		binary.PutVarint(b, x)
+		legacy = append(legacy, b...)
+	}
+	verifier3 := &Verifier{}
+	if err := verifier3.Unmarshal(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if verifier3.params.u != verifier.params.u || verifier3.params.l != verifier.params.l {
+		t.Errorf("legacy verifier decoded as u = %d, l = %d", verifier3.params.u, verifier3.params.l)
+	}
+}
+
+func TestUnmarshalLegacyProofUL(t *testing.T) {
+	//a proof with u = 4, l = 2 as encoded by the first ProofUL.Marshal
+	fixture, err := os.ReadFile("testdata/proofUL-baseline.hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := hex.DecodeString(strings.TrimSpace(string(fixture)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if L, ok := legacyProofULDigits(len(legacy)); !ok || L != 2 {
+		t.Fatalf("fixture of %d bytes not recognised as l = 2", len(legacy))
+	}
+	p := &ProofUL{}
+	if err := Unmarshal(legacy, p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.V) != 2 || len(p.zsig) != 2 {
+		t.Errorf("legacy proof decoded with l = %d", len(p.V))
+	}
+	//the elements are the same, only the header is new
+	if m := p.Marshal(); !bytes.Equal(m[headerSize+4:], legacy) {
+		t.Errorf("legacy proof does not re-encode to the same elements")
+	}
+
+	//lengths that fit no l, and corrupt elements, are still rejected
+	for _, m := range [][]byte{legacy[:len(legacy)-1], append(legacy, 0), legacy[:320]} {
+		if err := Unmarshal(m, &ProofUL{}); err == nil {
+			t.Errorf("malformed legacy proof of %d bytes accepted", len(m))
+		}
+	}
+	bad := append([]byte{}, legacy...)
+	bad[len(bad)-32] = 0xff
+	if err := Unmarshal(bad, &ProofUL{}); err == nil {
+		t.Errorf("legacy proof with out of range zr accepted")
+	}
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	prover, verifier, err := Setup(0, 100)
+	if err != nil {
+		t.FailNow()
+	}
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	x := new(big.Int).SetInt64(7)
+	proof, err := prover.Prove(x, r)
+	if err != nil {
+		t.FailNow()
+	}
+	issuer, err := NewIssuer(10, 3)
+	if err != nil {
+		t.FailNow()
+	}
+	pp, err := issuer.Params()
+	if err != nil {
+		t.FailNow()
+	}
+
+	unmarshalUL := func(m []byte) error { return Unmarshal(m, &ProofUL{}) }
+	encodings := []struct {
+		name      string
+		m         []byte
+		unmarshal func([]byte) error
+		// field is the offset of a group element in the encoding
+		field int
+	}{
+		{"proofUL", proof.proof1.Marshal(), unmarshalUL, headerSize + 4},
+		{"proofUL compressed", proof.proof1.MarshalCompressed(), unmarshalUL, headerSize + 4},
+		{"proof", proof.Marshal(), (&Proof{}).Unmarshal, 2 * (headerSize + 4)},
+		{"proof compressed", proof.MarshalCompressed(), (&Proof{}).Unmarshal, 2 * (headerSize + 4)},
+		{"prover", prover.Marshal(), (&Prover{}).Unmarshal, headerSize + 32},
+		{"verifier", verifier.Marshal(), (&Verifier{}).Unmarshal, headerSize + 32},
+		{"params", pp.Marshal(), (&PublicParams{}).Unmarshal, headerSize + 16},
+	}
+	for _, e := range encodings {
+		corrupt := func(f func(m []byte) []byte) []byte {
+			return f(append([]byte{}, e.m...))
+		}
+		cases := map[string][]byte{
+			"empty":     nil,
+			"truncated": e.m[:len(e.m)-1],
+			"trailing":  append(append([]byte{}, e.m...), 0),
+			"version":   corrupt(func(m []byte) []byte { m[0] = encodingVersion + 1; return m }),
+			"kind":      corrupt(func(m []byte) []byte { m[1] ^= 0xff; return m }),
+			"length": corrupt(func(m []byte) []byte {
+				binary.BigEndian.PutUint32(m[2:], uint32(len(m)))
+				return m
+			}),
+			"u": corrupt(func(m []byte) []byte {
+				if strings.HasPrefix(e.name, "proof") {
+					binary.BigEndian.PutUint32(m[headerSize:], 0xffffffff)
+				} else {
+					binary.BigEndian.PutUint64(m[headerSize:], 1)
+				}
+				return m
+			}),
+			"point": corrupt(func(m []byte) []byte { m[e.field] ^= 0xff; return m }),
+		}
+		for name, m := range cases {
+			if err := e.unmarshal(m); err == nil {
+				t.Errorf("%s: expected error for %s", e.name, name)
+			}
+		}
+		if err := e.unmarshal(e.m); err != nil {
+			t.Errorf("%s: %v", e.name, err)
+		}
+	}
+
+	//the kind of encoding is checked
+	if err := (&Verifier{}).Unmarshal(prover.Marshal()); err == nil {
+		t.Errorf("expected error for prover decoded as verifier")
+	}
+
+	//bounds with a > b are rejected
+	m := verifier.Marshal()
+	binary.BigEndian.PutUint64(m[headerSize+16:], 101)
+	if err := (&Verifier{}).Unmarshal(m); err == nil {
+		t.Errorf("expected error for a > b")
+	}
+
+	//a valid point that is not the signature on its digit is rejected
+	m = prover.Marshal()
+	sigs := m[headerSize+32+128+64:]
+	copy(sigs[:128], sigs[128:256])
+	if err := (&Prover{}).Unmarshal(m); err == nil {
+		t.Errorf("expected error for invalid signature")
+	}
+}
+
+func TestIssuer(t *testing.T) {
+	issuer, err := NewIssuer(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := issuer.Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the parameters are published in serialized form
+	pp2 := &PublicParams{}
+	if err := pp2.Unmarshal(pp.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	prover, err := NewProver(pp2, 18, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVerifier(pp2, 18, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	x := new(big.Int).SetInt64(40)
+	cm, _ := Commit(x, r, prover.params.H)
+	proof, err := prover.Prove(x, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := verifier.Verify(proof, cm); err != nil || !result {
+		t.Errorf("proof with issued parameters failed to verify: %v", err)
+	}
+
+	//a signature under another key is rejected by the prover
+	other, err := NewIssuer(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherpp, err := other.Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp2.signatures[4] = otherpp.signatures[4]
+	if _, err := NewProver(pp2, 18, 200); err == nil {
+		t.Errorf("expected error for signature under another key")
+	}
+	pp2.signatures[4] = nil
+	if err := pp2.Validate(); err == nil {
+		t.Errorf("expected error for missing signature")
+	}
+
+	if _, err := NewVerifier(pp, 200, 18); err == nil {
+		t.Errorf("expected error for a > b")
+	}
+	if _, err := NewIssuer(1, 3); err == nil {
+		t.Errorf("expected error for u < 2")
+	}
+}
+
+/*
+simulateUL returns a proof for cm with challenge c that satisfies every equation
+checked by VerifyUL, made without the signatures and for any committed value.
+*/
+func simulateUL(v *Verifier, cm *bn256.G2, c *big.Int) *ProofUL {
+	l := v.params.l
+	proof := &ProofUL{
+		V:    make([]*bn256.G2, l),
+		a:    make([]*bn256.GT, l),
+		zsig: make([]*big.Int, l),
+		zv:   make([]*big.Int, l),
+		C:    cm,
+		c:    c,
+	}
+	proof.zr, _ = rand.Int(rand.Reader, bn256.Order)
+	zsig := new(big.Int)
+	ui := big.NewInt(1)
+	for i := int64(0); i < l; i++ {
+		_, proof.V[i], _ = bn256.RandomG2(rand.Reader)
+		proof.zsig[i], _ = rand.Int(rand.Reader, bn256.Order)
+		proof.zv[i], _ = rand.Int(rand.Reader, bn256.Order)
+		proof.a[i] = bn256.MultiPair(
+			[]*bn256.G1{v.digitG1(c, proof.zsig[i]), new(bn256.G1).ScalarBaseMult(proof.zv[i])},
+			[]*bn256.G2{proof.V[i], G2},
+		)
+		zsig.Add(zsig, Multiply(proof.zsig[i], ui))
+		ui = Multiply(ui, big.NewInt(v.params.u))
+	}
+	proof.D = bn256.MultiScalarMultG2(
+		[]*bn256.G2{cm, v.params.H, G2},
+		[]*big.Int{c, proof.zr, Mod(zsig, bn256.Order)},
+	)
+	return proof
+}
+
+func TestVerifyULChallenge(t *testing.T) {
+	prover, verifier, err := SetupUL(10, 3)
+	if err != nil {
+		t.FailNow()
+	}
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	x := new(big.Int).SetInt64(5000)
+	cm, _ := Commit(x, r, prover.params.H)
+
+	//a prover that chooses the challenge can prove anything
+	c, _ := rand.Int(rand.Reader, bn256.Order)
+	forged := simulateUL(verifier, cm, c)
+	if result, err := verifier.VerifyUL(forged); err != nil || result {
+		t.Errorf("accepted a proof with a chosen challenge")
+	}
+	if result, _, _ := verifier.VerifyULBatch([]*ProofUL{forged}); result {
+		t.Errorf("batch accepted a proof with a chosen challenge")
+	}
+
+	//the challenge binds the statement and the parameters
+	proof, err := prover.ProveUL(new(big.Int).SetInt64(17), r, cm)
+	if err != nil {
+		t.FailNow()
+	}
+	base := challengeUL(verifier.params.H, verifier.params.pubk, 10, 3, proof)
+	if base.Cmp(proof.c) != 0 {
+		t.Errorf("prover and verifier disagree on the challenge")
+	}
+	if challengeUL(verifier.params.H, verifier.params.pubk, 10, 4, proof).Cmp(base) == 0 {
+		t.Errorf("challenge does not depend on l")
+	}
+	other := *proof
+	other.C = new(bn256.G2).Add(cm, G2)
+	if challengeUL(verifier.params.H, verifier.params.pubk, 10, 3, &other).Cmp(base) == 0 {
+		t.Errorf("challenge does not depend on C")
+	}
+	other = *proof
+	other.V = append([]*bn256.G2{G2}, proof.V[1:]...)
+	if challengeUL(verifier.params.H, verifier.params.pubk, 10, 3, &other).Cmp(base) == 0 {
+		t.Errorf("challenge does not depend on V")
+	}
+}
+
+func TestCommitFixedBase(t *testing.T) {
+	x, _ := rand.Int(rand.Reader, bn256.Order)
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	for _, h := range []*bn256.G2{mustGeneratorH(GeneratorHashToCurve), mustGeneratorH(GeneratorNUMS), mustGeneratorH(GeneratorLegacy), new(bn256.G2).ScalarBaseMult(x)} {
+		cm, _ := Commit(x, r, h)
+		want := new(bn256.G2).ScalarMult(G2, x)
+		want.Add(want, new(bn256.G2).ScalarMult(h, r))
+		if !bytes.Equal(cm.Marshal(), want.Marshal()) {
+			t.Errorf("Commit differs from g^x.h^r")
+		}
+	}
+}
+
+func mustGeneratorH(version GeneratorVersion) *bn256.G2 {
+	H, err := GeneratorH(version)
+	if err != nil {
+		panic(err)
+	}
+	return H
+}
+
+/*
+BenchmarkCommit compares Commit, which multiplies G2 and H with precomputed
+tables, against the same commitment with ScalarMult. ScalarMult uses the GLS
+decomposition, not the ladder Commit was first measured against; that
+comparison is BenchmarkFixedBaseG2 in bn256.
+*/
+func BenchmarkCommit(b *testing.B) {
+	H := mustGeneratorH(GeneratorNUMS)
+	x, _ := rand.Int(rand.Reader, bn256.Order)
+	r, _ := rand.Int(rand.Reader, bn256.Order)
+	Commit(x, r, H)
+	b.Run("FixedBase", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Commit(x, r, H)
+		}
+	})
+	b.Run("ScalarMult", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			C := new(bn256.G2).ScalarMult(G2, x)
+			C.Add(C, new(bn256.G2).ScalarMult(H, r))
+		}
+	})
+}
+
+/*
+BenchmarkSetupULSign compares the generation of the u signatures of SetupUL
+against the same signatures with ScalarMult, as in BenchmarkCommit.
+*/
+func BenchmarkSetupULSign(b *testing.B) {
+	const u = 16
+	kp, _ := keygen()
+	b.Run("FixedBase", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for m := int64(0); m < u; m++ {
+				sign(new(big.Int).SetInt64(m), kp.privk)
+			}
+		}
+	})
+	b.Run("ScalarMult", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for m := int64(0); m < u; m++ {
+				inv := ModInverse(Mod(Add(new(big.Int).SetInt64(m), kp.privk), bn256.Order), bn256.Order)
+				new(bn256.G2).ScalarMult(G2, inv)
+			}
+		}
+	})
+}
+
+/*
+runCeremony runs a ceremony of len(ts) participants and returns the parameters
+each of them received.
+*/
+func runCeremony(ts []Transport, u, l int64) ([]*PublicParams, []error) {
+	pps := make([]*PublicParams, len(ts))
+	errs := make([]error, len(ts))
+	var wg sync.WaitGroup
+	for i := range ts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pps[i], errs[i] = Ceremony(ts[i], i, len(ts), u, l)
+		}(i)
+	}
+	wg.Wait()
+	return pps, errs
+}
+
+func TestCeremony(t *testing.T) {
+	for name, ts := range map[string][]Transport{
+		"memory": NewMemoryTransports(3),
+		"pipe":   NewPipeTransports(2),
+	} {
+		pps, errs := runCeremony(ts, 10, 3)
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("%s: party %d: %v", name, i, err)
+			}
+		}
+		for i := 1; i < len(pps); i++ {
+			if !bytes.Equal(pps[i].Marshal(), pps[0].Marshal()) {
+				t.Errorf("%s: party %d received different parameters", name, i)
+			}
+		}
+
+		//the parameters work as those of an Issuer
+		prover, err := NewProver(pps[0], 18, 200)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		verifier, err := NewVerifier(pps[len(pps)-1], 18, 200)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r, _ := rand.Int(rand.Reader, bn256.Order)
+		x := new(big.Int).SetInt64(123)
+		cm, _ := Commit(x, r, prover.params.H)
+		proof, err := prover.Prove(x, r)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if result, err := verifier.Verify(proof, cm); err != nil || !result {
+			t.Errorf("%s: proof failed to verify: %v", name, err)
+		}
+	}
+
+	if _, err := Ceremony(NewMemoryTransports(1)[0], 0, 1, 10, 3); err == nil {
+		t.Errorf("expected error for a single participant")
+	}
+}
+
+/*
+corruptTransport replaces the messages sent in round with garbage.
+*/
+type corruptTransport struct {
+	Transport
+	round, sent int
+	mu          sync.Mutex
+}
+
+func (t *corruptTransport) Send(to int, msg []byte) error {
+	t.mu.Lock()
+	t.sent++
+	corrupt := t.sent == t.round
+	t.mu.Unlock()
+	if corrupt {
+		msg = bytes.Repeat([]byte{0xff}, len(msg))
+	}
+	return t.Transport.Send(to, msg)
+}
+
+func TestCeremonyMalformed(t *testing.T) {
+	before := runtime.NumGoroutine()
+	//the second message of party 1, to party 0 with its key share
+	ts := NewMemoryTransports(2)
+	ts[1] = &corruptTransport{Transport: ts[1], round: 2}
+	_, errs := runCeremony(ts, 4, 2)
+	if errs[0] == nil {
+		t.Errorf("expected error for malformed ciphertext")
+	}
+	//party 1 learns of the failure instead of waiting for party 0
+	if errs[1] == nil {
+		t.Errorf("expected party 1 to see the abort")
+	}
+
+	//neither in memory nor over pipes, which block until the message is
+	//read, is a send left behind after the abort
+	ts = NewPipeTransports(2)
+	ts[1] = &corruptTransport{Transport: ts[1], round: 2}
+	if _, errs := runCeremony(ts, 4, 2); errs[0] == nil || errs[1] == nil {
+		t.Errorf("expected both parties to fail over pipes")
+	}
+	for i := 0; runtime.NumGoroutine() > before && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left after the abort", n-before)
+	}
+}
+
+/*
+deviatingTransport applies f to the body of the messages sent in round, where
+round 0 are the commitments, 1 the key shares, 2 the Paillier ciphertexts, 3
+the responses, 4 the shares of y-a and r-b, 5 the shares of w, 6 the hashes of
+the broadcasts and 7 the signature shares.
+*/
+type deviatingTransport struct {
+	Transport
+	round int
+	f     func(body []byte) []byte
+	mu    sync.Mutex
+	sent  map[int]int
+}
+
+func (t *deviatingTransport) Send(to int, msg []byte) error {
+	t.mu.Lock()
+	round := t.sent[to]
+	t.sent[to]++
+	t.mu.Unlock()
+	if round == t.round && len(msg) > 0 && msg[0] == msgData {
+		msg = append([]byte{msgData}, t.f(append([]byte{}, msg[1:]...))...)
+	}
+	return t.Transport.Send(to, msg)
+}
+
+func TestCeremonyDeviating(t *testing.T) {
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1)).Marshal()
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1)).Marshal()
+	cases := []struct {
+		name  string
+		round int
+		f     func(body []byte) []byte
+		// party is true if the error names the deviating party
+		party bool
+	}{
+		{"inconsistent key share", 1, func(body []byte) []byte {
+			copy(body, g1)
+			return body
+		}, true},
+		{"wrong proof of knowledge", 1, func(body []byte) []byte {
+			//the proof follows the key share, the Paillier key and the nonce
+			off := 64 + 4 + int(binary.BigEndian.Uint32(body[64:])) + nonceSize
+			copy(body[off:], g1)
+			return body
+		}, true},
+		{"out of range ciphertext", 2, func(body []byte) []byte {
+			//the shares start with the first ciphertext
+			for i := 8; i < 8+int(binary.BigEndian.Uint32(body[4:])); i++ {
+				body[i] = 0
+			}
+			return body
+		}, true},
+		{"ciphertext of another value", 2, func(body []byte) []byte {
+			//g1^a no longer matches the ciphertext and its range proof
+			off := 8 + int(binary.BigEndian.Uint32(body[4:]))
+			copy(body[off:], g1)
+			return body
+		}, true},
+		{"wrong response", 3, func(body []byte) []byte {
+			//the first response follows the shares
+			off := 4 + int(binary.BigEndian.Uint32(body))
+			off += 4 + int(binary.BigEndian.Uint32(body[off:])) - 1
+			body[off] ^= 1
+			return body
+		}, true},
+		{"wrong y-a", 4, func(body []byte) []byte {
+			body[31] ^= 1
+			return body
+		}, true},
+		{"wrong r-b", 4, func(body []byte) []byte {
+			body[63] ^= 1
+			return body
+		}, true},
+		{"wrong w", 5, func(body []byte) []byte {
+			body[31] ^= 1
+			return body
+		}, true},
+		{"wrong signature share", 7, func(body []byte) []byte {
+			copy(body, g2)
+			return body
+		}, true},
+	}
+	for _, c := range cases {
+		ts := NewMemoryTransports(3)
+		ts[2] = &deviatingTransport{Transport: ts[2], round: c.round, f: c.f, sent: map[int]int{}}
+		_, errs := runCeremony(ts, 2, 1)
+		//the honest parties 0 and 1 detect the deviation
+		for i, err := range errs[:2] {
+			if err == nil {
+				t.Errorf("%s: party %d: expected error", c.name, i)
+			} else if c.party && !strings.Contains(err.Error(), "party 2") {
+				t.Errorf("%s: party %d: %v", c.name, i, err)
+			}
+		}
+	}
+}
+
+func TestPaillier(t *testing.T) {
+	sk, err := newPaillierKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := big.NewInt(1234), big.NewInt(5678)
+	ca, _ := sk.encrypt(a)
+	cb, _ := sk.encrypt(b)
+	if m := sk.decrypt(sk.add(ca, cb)); m.Int64() != 1234+5678 {
+		t.Errorf("Enc(a)·Enc(b) decrypted to %v", m)
+	}
+	if m := sk.decrypt(sk.mul(ca, b)); m.Int64() != 1234*5678 {
+		t.Errorf("Enc(a)^b decrypted to %v", m)
+	}
+	//ciphertexts are the units modulo n²
+	if !sk.valid(ca) {
+		t.Errorf("valid ciphertext rejected")
+	}
+	for _, c := range []*big.Int{big.NewInt(0), sk.n, Multiply(ca, sk.n), sk.n2} {
+		if sk.valid(c) {
+			t.Errorf("invalid ciphertext %v accepted", c)
+		}
+	}
+
+	roots := sk.proveModulus(1)
+	if err := sk.verifyModulus(1, roots); err != nil {
+		t.Fatal(err)
+	}
+	//the proof is bound to the participant
+	if err := sk.verifyModulus(2, roots); err == nil {
+		t.Errorf("expected error for the proof of another participant")
+	}
+	//a modulus with a small factor is rejected
+	bad := newPaillierPublicKey(Multiply(sk.n, big.NewInt(3)))
+	if err := bad.verifyModulus(1, roots); err == nil {
+		t.Errorf("expected error for a modulus divisible by 3")
+	}
+
+	//negative plaintexts decrypt as such
+	cn, _ := sk.encrypt(Sub(sk.n, a))
+	if m := sk.decryptSigned(cn); m.Cmp(new(big.Int).Neg(a)) != 0 {
+		t.Errorf("Enc(-a) decrypted to %v", m)
+	}
+	x, _ := rand.Int(rand.Reader, sk.n)
+	e, _ := rand.Int(rand.Reader, sk.n2)
+	if sk.exp(x, e).Cmp(new(big.Int).Exp(x, e, sk.n)) != 0 {
+		t.Errorf("exp differs from Exp")
+	}
+}
+
+func TestMtAProofs(t *testing.T) {
+	sk, err := newPaillierKey(paillierBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := &sk.paillierPublicKey
+	rp, lambda, err := sk.ringPedersen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, z, err := rp.prove(sk, lambda, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.verify(1, A, z); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.verify(2, A, z); err == nil {
+		t.Errorf("ring-Pedersen proof verified for another participant")
+	}
+	if err := (&ringPedersen{n: rp.n, s: Mod(Multiply(rp.s, rp.s), rp.n), t: rp.t}).verify(1, A, z); err == nil {
+		t.Errorf("ring-Pedersen proof verified for other parameters")
+	}
+
+	tr := func() *transcript { return newTranscript("test") }
+	a, _ := rand.Int(rand.Reader, bn256.Order)
+	X := new(bn256.G1).ScalarBaseMult(a)
+	rho, _ := pk.randomness()
+	K := pk.encryptWith(a, rho)
+	pf, err := proveEnc(tr(), pk, rp, K, X, a, rho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pf.verify(tr(), pk, rp, K, X); err != nil {
+		t.Fatal(err)
+	}
+	if err := pf.verify(newTranscript("other"), pk, rp, K, X); err == nil {
+		t.Errorf("range proof verified under another transcript")
+	}
+	if err := pf.verify(tr(), pk, rp, K, new(bn256.G1).ScalarBaseMult(big.NewInt(1))); err == nil {
+		t.Errorf("range proof verified for another group element")
+	}
+	//a plaintext above the range is rejected, though it has the same residue
+	big1 := Add(a, Multiply(bn256.Order, new(big.Int).Lsh(big.NewInt(1), mtaL+mtaEps)))
+	K1 := pk.encryptWith(big1, rho)
+	if pf, err := proveEnc(tr(), pk, rp, K1, X, big1, rho); err != nil || pf.verify(tr(), pk, rp, K1, X) == nil {
+		t.Errorf("range proof verified for a plaintext out of range: %v", err)
+	}
+
+	b, _ := rand.Int(rand.Reader, bn256.Order)
+	beta, _ := randBits(mtaLPrime)
+	B := new(bn256.G2).ScalarBaseMult(b)
+	Beta := new(bn256.G2).ScalarBaseMult(Mod(beta, bn256.Order))
+	rho2, _ := pk.randomness()
+	D := pk.add(pk.mul(K, b), pk.encryptWith(beta, rho2))
+	apf, err := proveAff(tr(), pk, rp, K, D, B, Beta, b, beta, rho2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := apf.verify(tr(), pk, rp, K, D, B, Beta); err != nil {
+		t.Fatal(err)
+	}
+	if m := sk.decryptSigned(D); m.Cmp(Add(Multiply(a, b), beta)) != 0 {
+		t.Errorf("response decrypted to %v", m)
+	}
+	if err := apf.verify(tr(), pk, rp, K, D, B, B); err == nil {
+		t.Errorf("affine proof verified for another g2^beta")
+	}
+	if err := apf.verify(tr(), pk, rp, K, pk.add(D, K), B, Beta); err == nil {
+		t.Errorf("affine proof verified for another response")
+	}
+	//a mask above the range is rejected
+	beta1 := new(big.Int).Lsh(big.NewInt(1), mtaLPrime+mtaEps+1)
+	D1 := pk.add(pk.mul(K, b), pk.encryptWith(beta1, rho2))
+	Beta1 := new(bn256.G2).ScalarBaseMult(Mod(beta1, bn256.Order))
+	if apf, err := proveAff(tr(), pk, rp, K, D1, B, Beta1, b, beta1, rho2); err != nil || apf.verify(tr(), pk, rp, K, D1, B, Beta1) == nil {
+		t.Errorf("affine proof verified for a mask out of range: %v", err)
+	}
+}
@@ -0,0 +1,836 @@
+package ccs08
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+/*
+This file contains an n-party setup of the public parameters, in which no
+participant learns the Boneh-Boyen private key x unless all of them collude.
+
+Every participant j holds a random share x_j of x = sum(x_j). It first
+broadcasts a hash commitment to g1^x_j and its Paillier modulus, and reveals
+them only when it has received the commitments of all others, together with a
+Schnorr proof of knowledge of x_j, a proof that the modulus n is coprime to
+phi(n), and ring-Pedersen parameters over n with a proof that they are well
+formed. So no participant can choose its share after seeing the others', and
+the sum of g1^x_j is pubk.
+
+The signature on i is g2^(1/y) for y = x+i, computed by the shared inversion
+of Bar-Ilan and Beaver: for a random shared r the participants multiply their
+shares of y and r with a Beaver triple (a, b, a·b), open w = y·r, which is
+uniformly random and reveals nothing about y, and publish g2^(r_j/w), whose
+sum is g2^(r/w) = g2^(1/y). Only sums are opened, y-a, r-b and w, and the
+share of each participant is masked by its shares of a and b.
+
+The shares of a·b are built from the products a_j·b_k of every two
+participants by multiplicative-to-additive conversion with Paillier
+encryption: j sends k Enc(a_j) under its own key, and k returns
+Enc(a_j·b_k + beta) and keeps -beta. The proofs of mta.go show that both
+messages are formed from the published g1^a_j, g2^b_k and g2^beta with values
+small enough that the plaintexts never wrap, so that neither message reveals
+anything about the other participant's share.
+
+Every participant publishes g1^a_j, g2^b_j and g2^r_j, with a proof of
+knowledge of r_j, and each opened share is checked on its own: y_j-a_j
+against g1^x_j/g1^a_j, r_j-b_j against g2^r_j/g2^b_j, w_j with a pairing
+against g1^a_j, g2^b and the g2^beta of the responses to and from j, and the
+signature share against g2^r_j/w. A participant whose share fails is named in
+the error, and the ceremony aborts before any value that depends on the
+failed share is opened. Before the signature shares, the participants compare
+a hash of all broadcasts, so that one that sends different messages to
+different participants cannot make them accept different parameters.
+
+The ceremony is thus secure with abort against active participants, as long
+as one of them is honest. Two failures do not name the deviating participant
+to everyone: a response that fails its proof is seen by its recipient alone,
+whose error names the responder while the others see the abort, and a
+mismatch of the broadcasts shows that some participant equivocated but not
+which. The Paillier moduli are proven coprime to phi(n) and free of factors
+below 2^16, but not to be the product of two primes.
+*/
+
+const (
+	// paillierBits is the size of the Paillier moduli of a ceremony.
+	paillierBits = 2048
+
+	// msgData and msgAbort start the messages of a round and the message
+	// that ends the ceremony after an error.
+	msgData  byte = 0
+	msgAbort byte = 1
+
+	// abortTimeout bounds the wait for the abort messages to be delivered
+	// before the transport is closed.
+	abortTimeout = time.Second
+
+	// ceremonyDST is the domain-separation tag of the commitments and
+	// proofs of a ceremony.
+	ceremonyDST = "blockchain-research/crypto/ccs08/ceremony/v1"
+
+	nonceSize = 32
+)
+
+/*
+ceremonyShares are the group elements that a participant publishes for its
+shares: g1^x_j, and for every index g1^a_j, g2^b_j and g2^r_j, and
+Beta[k] = g2^beta for the mask beta of its response to each participant k.
+*/
+type ceremonyShares struct {
+	X    *bn256.G1
+	A    []*bn256.G1
+	B, R []*bn256.G2
+	Beta [][]*bn256.G2
+}
+
+type ceremonyParty struct {
+	t     Transport
+	id, n int
+	// sk is the Paillier key of this participant, and pks and rps are the
+	// Paillier keys and ring-Pedersen parameters of all of them
+	sk  *paillierKey
+	pks []*paillierPublicKey
+	rps []*ringPedersen
+	// session hashes the commitments, to which every later proof is bound
+	session []byte
+	// shares are the group elements published by every participant
+	shares []*ceremonyShares
+	// view absorbs every broadcast message, so that the participants can
+	// check that they received the same
+	view *transcript
+	// sends counts the sends still in progress
+	sends sync.WaitGroup
+}
+
+/*
+Ceremony runs participant id of an n-party setup of the public parameters for
+[0,u^l) over the Transport t. Every participant must call it with the same n,
+u and l, and each receives the same PublicParams. If the ceremony fails,
+Ceremony tells the other participants and closes t, and the error names the
+participant that deviated if it was detected.
+*/
+func Ceremony(t Transport, id, n int, u, l int64) (*PublicParams, error) {
+	if n < 2 || id < 0 || id >= n {
+		return nil, fmt.Errorf("ccs08: ceremony: invalid participant %d of %d", id, n)
+	}
+	if err := checkBounds(u, l, 0, 0); err != nil {
+		return nil, err
+	}
+	p := &ceremonyParty{t: t, id: id, n: n, view: newTranscript(ceremonyDST)}
+	pp, err := p.run(u, l)
+	if err != nil {
+		p.abort()
+		return nil, err
+	}
+	return pp, nil
+}
+
+func (p *ceremonyParty) run(u, l int64) (*PublicParams, error) {
+	id, n := p.id, p.n
+	H, err := GeneratorH(GeneratorHashToCurve)
+	if err != nil {
+		return nil, err
+	}
+
+	// commit to the key share and the Paillier key
+	x, err := rand.Int(rand.Reader, bn256.Order)
+	if err != nil {
+		return nil, err
+	}
+	if p.sk, err = newPaillierKey(paillierBits); err != nil {
+		return nil, err
+	}
+	rp, lambda, err := p.sk.ringPedersen()
+	if err != nil {
+		return nil, err
+	}
+	X := new(bn256.G1).ScalarBaseMult(x)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	commitment := commitShare(id, X, p.sk.n, nonce)
+	commitments, err := p.broadcast(commitment)
+	if err != nil {
+		return nil, err
+	}
+	session := newTranscript(ceremonyDST)
+	for k, c := range commitments {
+		if len(c) != len(commitment) {
+			return nil, fmt.Errorf("ccs08: party %d: commitment of %d bytes", k, len(c))
+		}
+		session.appendBytes("commitment", c)
+	}
+	p.session = session.h.Sum(nil)
+
+	// reveal them with the proofs
+	R, z, err := proveShare(x, X, id, commitments)
+	if err != nil {
+		return nil, err
+	}
+	A, zs, err := rp.prove(p.sk, lambda, id)
+	if err != nil {
+		return nil, err
+	}
+	e := &encoder{}
+	e.raw(X.Marshal())
+	e.bytes(p.sk.n.Bytes())
+	e.raw(nonce)
+	e.raw(R.Marshal())
+	e.scalar(z)
+	for _, root := range p.sk.proveModulus(id) {
+		e.bytes(root.Bytes())
+	}
+	e.nats(rp.s, rp.t)
+	e.nats(A...)
+	e.nats(zs...)
+	in, err := p.broadcast(e.body)
+	if err != nil {
+		return nil, err
+	}
+	pubk := new(bn256.G1).ScalarBaseMult(x)
+	p.pks = make([]*paillierPublicKey, n)
+	p.rps = make([]*ringPedersen, n)
+	p.shares = make([]*ceremonyShares, n)
+	p.pks[id], p.rps[id], p.shares[id] = &p.sk.paillierPublicKey, rp, &ceremonyShares{X: X}
+	for k, m := range in {
+		if k == id {
+			continue
+		}
+		d := &decoder{m: m}
+		share := d.g1(fmt.Sprintf("party %d: key share", k))
+		N := new(big.Int).SetBytes(d.bytes(fmt.Sprintf("party %d: Paillier key", k)))
+		nonce := d.next(nonceSize, fmt.Sprintf("party %d: nonce", k))
+		R := d.g1(fmt.Sprintf("party %d: proof of knowledge", k))
+		z := d.scalar(fmt.Sprintf("party %d: proof of knowledge", k))
+		roots := make([]*big.Int, modulusProofRoots)
+		for i := range roots {
+			roots[i] = new(big.Int).SetBytes(d.bytes(fmt.Sprintf("party %d: modulus proof[%d]", k, i)))
+		}
+		s := d.nat(fmt.Sprintf("party %d: ring-Pedersen parameters", k))
+		t := d.nat(fmt.Sprintf("party %d: ring-Pedersen parameters", k))
+		A := make([]*big.Int, ringPedersenRounds)
+		zs := make([]*big.Int, ringPedersenRounds)
+		for i := range A {
+			A[i] = d.nat(fmt.Sprintf("party %d: ring-Pedersen proof[%d]", k, i))
+		}
+		for i := range zs {
+			zs[i] = d.nat(fmt.Sprintf("party %d: ring-Pedersen proof[%d]", k, i))
+		}
+		if err := d.finish(); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(commitShare(k, share, N, nonce), commitments[k]) {
+			return nil, fmt.Errorf("ccs08: party %d: key share does not match its commitment", k)
+		}
+		if !verifyShare(share, R, z, k, commitments) {
+			return nil, fmt.Errorf("ccs08: party %d: invalid proof of knowledge of the key share", k)
+		}
+		if N.BitLen() < paillierBits {
+			return nil, fmt.Errorf("ccs08: party %d: Paillier key of %d bits", k, N.BitLen())
+		}
+		pk := newPaillierPublicKey(N)
+		if err := pk.verifyModulus(k, roots); err != nil {
+			return nil, fmt.Errorf("ccs08: party %d: %v", k, err)
+		}
+		rpk := &ringPedersen{n: N, s: s, t: t}
+		if err := rpk.verify(k, A, zs); err != nil {
+			return nil, fmt.Errorf("ccs08: party %d: %v", k, err)
+		}
+		pubk.Add(pubk, share)
+		p.pks[k], p.rps[k], p.shares[k] = pk, rpk, &ceremonyShares{X: share}
+	}
+
+	a, err := randScalars(u)
+	if err != nil {
+		return nil, err
+	}
+	b, err := randScalars(u)
+	if err != nil {
+		return nil, err
+	}
+	r, err := randScalars(u)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.triples(a, b, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// open y-a and r-b, checked against g1^y_j/g1^a_j and g2^r_j/g2^b_j
+	y := make([]*big.Int, u)
+	e = &encoder{}
+	for i := range y {
+		y[i] = new(big.Int).Set(x)
+		if id == 0 {
+			y[i] = Mod(Add(y[i], big.NewInt(int64(i))), bn256.Order)
+		}
+		e.scalar(Mod(Sub(y[i], a[i]), bn256.Order))
+		e.scalar(Mod(Sub(r[i], b[i]), bn256.Order))
+	}
+	in, err = p.broadcast(e.body)
+	if err != nil {
+		return nil, err
+	}
+	ya, rb := make([]*big.Int, u), make([]*big.Int, u)
+	for i := range ya {
+		ya[i] = Mod(Sub(y[i], a[i]), bn256.Order)
+		rb[i] = Mod(Sub(r[i], b[i]), bn256.Order)
+	}
+	err = p.open(in, func(k, i int, s []*big.Int) error {
+		sh := p.shares[k]
+		Y := sh.X
+		if k == 0 {
+			Y = new(bn256.G1).Add(Y, new(bn256.G1).ScalarBaseMult(big.NewInt(int64(i))))
+		}
+		lhs := new(bn256.G1).ScalarBaseMult(s[0])
+		if !bytes.Equal(lhs.Add(lhs, sh.A[i]).Marshal(), Y.Marshal()) {
+			return fmt.Errorf("ccs08: party %d: y-a[%d] does not match its shares", k, i)
+		}
+		lhs2 := new(bn256.G2).ScalarBaseMult(s[1])
+		if !bytes.Equal(lhs2.Add(lhs2, sh.B[i]).Marshal(), sh.R[i].Marshal()) {
+			return fmt.Errorf("ccs08: party %d: r-b[%d] does not match its shares", k, i)
+		}
+		return nil
+	}, ya, rb)
+	if err != nil {
+		return nil, err
+	}
+
+	// open w = y·r, with the share c + (y-a)·b + (r-b)·a [+ (y-a)·(r-b)]
+	w := make([]*big.Int, u)
+	e = &encoder{}
+	for i := range w {
+		w[i] = Add(c[i], Add(Multiply(ya[i], b[i]), Multiply(rb[i], a[i])))
+		if id == 0 {
+			w[i].Add(w[i], Multiply(ya[i], rb[i]))
+		}
+		w[i].Mod(w[i], bn256.Order)
+		e.scalar(w[i])
+	}
+	in, err = p.broadcast(e.body)
+	if err != nil {
+		return nil, err
+	}
+	err = p.open(in, func(k, i int, s []*big.Int) error {
+		if !p.checkW(k, i, s[0], ya[i], rb[i]) {
+			return fmt.Errorf("ccs08: party %d: w[%d] does not match its shares", k, i)
+		}
+		return nil
+	}, w)
+	if err != nil {
+		return nil, err
+	}
+
+	// compare the broadcasts
+	view := p.view.h.Sum(nil)
+	in, err = p.broadcast(view)
+	if err != nil {
+		return nil, err
+	}
+	for k, m := range in {
+		if !bytes.Equal(m, view) {
+			return nil, fmt.Errorf("ccs08: ceremony: party %d received different broadcasts than party %d", k, id)
+		}
+	}
+
+	// publish g2^(r_j/w)
+	pp := &PublicParams{
+		signatures: make([]*bn256.G2, u),
+		H:          H,
+		pubk:       pubk,
+		u:          u,
+		l:          l,
+	}
+	e = &encoder{}
+	for i := range pp.signatures {
+		winv := ModInverse(w[i], bn256.Order)
+		if winv == nil {
+			return nil, fmt.Errorf("ccs08: ceremony: x = -%d, the ceremony must be repeated", i)
+		}
+		pp.signatures[i] = new(bn256.G2).ScalarBaseMult(Mod(Multiply(r[i], winv), bn256.Order))
+		e.raw(pp.signatures[i].Marshal())
+	}
+	in, err = p.broadcast(e.body)
+	if err != nil {
+		return nil, err
+	}
+	for k, m := range in {
+		if k == id {
+			continue
+		}
+		d := &decoder{m: m}
+		for i, sig := range pp.signatures {
+			share := d.g2(fmt.Sprintf("party %d: signature[%d]", k, i))
+			if d.err != nil {
+				return nil, d.err
+			}
+			if !bytes.Equal(new(bn256.G2).ScalarMult(share, w[i]).Marshal(), p.shares[k].R[i].Marshal()) {
+				return nil, fmt.Errorf("ccs08: party %d: signature[%d] does not match its shares", k, i)
+			}
+			sig.Add(sig, share)
+		}
+		if err := d.finish(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := pp.Validate(); err != nil {
+		return nil, err
+	}
+	return pp, nil
+}
+
+/*
+triples returns this participant's shares of a·b, where a and b are the
+participants' shares of u random pairs, and publishes g1^a, g2^b and g2^r for
+its shares r of the blinding values.
+*/
+func (p *ceremonyParty) triples(a, b, r []*big.Int) ([]*big.Int, error) {
+	u := len(a)
+	own := p.shares[p.id]
+	own.A, own.B, own.R = make([]*bn256.G1, u), make([]*bn256.G2, u), make([]*bn256.G2, u)
+	own.Beta = make([][]*bn256.G2, p.n)
+	c := make([]*big.Int, u)
+	for i := range c {
+		c[i] = Mod(Multiply(a[i], b[i]), bn256.Order)
+	}
+
+	// send Enc(a_j) with a range proof to each participant, g1^a_j, and g2^r_j
+	// with a proof of knowledge
+	K, rho := make([]*big.Int, u), make([]*big.Int, u)
+	common := &encoder{}
+	for i := range a {
+		own.A[i] = new(bn256.G1).ScalarBaseMult(a[i])
+		own.B[i] = new(bn256.G2).ScalarBaseMult(b[i])
+		own.R[i] = new(bn256.G2).ScalarBaseMult(r[i])
+		var err error
+		if rho[i], err = p.sk.randomness(); err != nil {
+			return nil, err
+		}
+		K[i] = p.sk.encryptWith(a[i], rho[i])
+		R, z, err := proveKnowledge(p.proofTranscript(p.id, -1, i), r[i], own.R[i])
+		if err != nil {
+			return nil, err
+		}
+		common.bytes(K[i].Bytes())
+		common.raw(own.A[i].Marshal())
+		common.raw(own.R[i].Marshal())
+		common.raw(R.Marshal())
+		common.scalar(z)
+	}
+	out := make([][]byte, p.n)
+	for k := range out {
+		if k == p.id {
+			continue
+		}
+		e := &encoder{}
+		e.bytes(common.body)
+		for i := range a {
+			pf, err := proveEnc(p.proofTranscript(p.id, k, i), &p.sk.paillierPublicKey, p.rps[k], K[i], own.A[i], a[i], rho[i])
+			if err != nil {
+				return nil, err
+			}
+			e.encProof(pf)
+		}
+		out[k] = e.body
+	}
+	in, err := p.exchange(out)
+	if err != nil {
+		return nil, err
+	}
+	Ks := make([][]*big.Int, p.n)
+	commons := make([][]byte, p.n)
+	commons[p.id] = common.body
+	for k, m := range in {
+		if k == p.id {
+			continue
+		}
+		d := &decoder{m: m}
+		commons[k] = d.bytes(fmt.Sprintf("party %d: shares", k))
+		dc := &decoder{m: commons[k]}
+		sh := p.shares[k]
+		sh.A, sh.R, Ks[k] = make([]*bn256.G1, u), make([]*bn256.G2, u), make([]*big.Int, u)
+		for i := range a {
+			Ks[k][i] = dc.ciphertext(p.pks[k], fmt.Sprintf("party %d: Enc(a[%d])", k, i))
+			sh.A[i] = dc.g1(fmt.Sprintf("party %d: g1^a[%d]", k, i))
+			sh.R[i] = dc.g2(fmt.Sprintf("party %d: g2^r[%d]", k, i))
+			R := dc.g2(fmt.Sprintf("party %d: proof of knowledge of r[%d]", k, i))
+			z := dc.scalar(fmt.Sprintf("party %d: proof of knowledge of r[%d]", k, i))
+			if dc.err != nil {
+				return nil, dc.err
+			}
+			if !verifyKnowledge(p.proofTranscript(k, -1, i), sh.R[i], R, z) {
+				return nil, fmt.Errorf("ccs08: party %d: invalid proof of knowledge of r[%d]", k, i)
+			}
+			pf := d.encProof(fmt.Sprintf("party %d: range proof[%d]", k, i))
+			if d.err != nil {
+				return nil, d.err
+			}
+			if err := pf.verify(p.proofTranscript(k, p.id, i), p.pks[k], p.rps[p.id], Ks[k][i], sh.A[i]); err != nil {
+				return nil, fmt.Errorf("ccs08: party %d: Enc(a[%d]): %v", k, i, err)
+			}
+		}
+		if err := dc.finish(); err != nil {
+			return nil, err
+		}
+		if err := d.finish(); err != nil {
+			return nil, err
+		}
+	}
+	p.record(commons)
+
+	// return Enc(a_k·b_j + beta) with a proof to each participant k, keep
+	// -beta, and publish g2^b_j and every g2^beta
+	resp := make([][]*big.Int, p.n)
+	proofs := make([][]*affProof, p.n)
+	for k := range resp {
+		if k == p.id {
+			continue
+		}
+		pk := p.pks[k]
+		resp[k], proofs[k], own.Beta[k] = make([]*big.Int, u), make([]*affProof, u), make([]*bn256.G2, u)
+		for i := range b {
+			beta, err := randBits(mtaLPrime)
+			if err != nil {
+				return nil, err
+			}
+			rho, err := pk.randomness()
+			if err != nil {
+				return nil, err
+			}
+			resp[k][i] = pk.add(pk.mul(Ks[k][i], b[i]), pk.encryptWith(beta, rho))
+			own.Beta[k][i] = new(bn256.G2).ScalarBaseMult(Mod(beta, bn256.Order))
+			proofs[k][i], err = proveAff(p.proofTranscript(p.id, k, i), pk, p.rps[k], Ks[k][i], resp[k][i], own.B[i], own.Beta[k][i], b[i], beta, rho)
+			if err != nil {
+				return nil, err
+			}
+			c[i] = Mod(Sub(c[i], beta), bn256.Order)
+		}
+	}
+	common = &encoder{}
+	for i := range b {
+		common.raw(own.B[i].Marshal())
+	}
+	for _, Beta := range own.Beta {
+		for i := range Beta {
+			common.raw(Beta[i].Marshal())
+		}
+	}
+	for k := range out {
+		if k == p.id {
+			continue
+		}
+		e := &encoder{}
+		e.bytes(common.body)
+		for i := range b {
+			e.bytes(resp[k][i].Bytes())
+			e.affProof(proofs[k][i])
+		}
+		out[k] = e.body
+	}
+	if in, err = p.exchange(out); err != nil {
+		return nil, err
+	}
+	commons[p.id] = common.body
+
+	// decrypt a_j·b_k + beta
+	for k, m := range in {
+		if k == p.id {
+			continue
+		}
+		d := &decoder{m: m}
+		commons[k] = d.bytes(fmt.Sprintf("party %d: shares", k))
+		dc := &decoder{m: commons[k]}
+		sh := p.shares[k]
+		sh.B, sh.Beta = make([]*bn256.G2, u), make([][]*bn256.G2, p.n)
+		for i := range b {
+			sh.B[i] = dc.g2(fmt.Sprintf("party %d: g2^b[%d]", k, i))
+		}
+		for j := range sh.Beta {
+			if j == k {
+				continue
+			}
+			sh.Beta[j] = make([]*bn256.G2, u)
+			for i := range b {
+				sh.Beta[j][i] = dc.g2(fmt.Sprintf("party %d: g2^beta[%d][%d]", k, j, i))
+			}
+		}
+		if err := dc.finish(); err != nil {
+			return nil, err
+		}
+		for i := range a {
+			D := d.ciphertext(&p.sk.paillierPublicKey, fmt.Sprintf("party %d: Enc(a·b[%d])", k, i))
+			pf := d.affProof(fmt.Sprintf("party %d: affine proof[%d]", k, i))
+			if d.err != nil {
+				return nil, d.err
+			}
+			if err := pf.verify(p.proofTranscript(k, p.id, i), &p.sk.paillierPublicKey, p.rps[p.id], K[i], D, sh.B[i], sh.Beta[p.id][i]); err != nil {
+				return nil, fmt.Errorf("ccs08: party %d: Enc(a·b[%d]): %v", k, i, err)
+			}
+			c[i] = Mod(Add(c[i], p.sk.decryptSigned(D)), bn256.Order)
+		}
+		if err := d.finish(); err != nil {
+			return nil, err
+		}
+	}
+	p.record(commons)
+	return c, nil
+}
+
+/*
+checkW reports whether w is the share of participant k of w[i]. With
+c_k = a_k·b + sum_j (beta_kj - beta_jk) its share of a·b, where beta_kj masks
+the response of j to k, the share is c_k + (y-a)·b_k + (r-b)·a_k, plus
+(y-a)·(r-b) for participant 0. The terms in a_k are paired with g1^a_k and
+the others with g1, so that the check is e(g1^a_k, g2^b·g2^(r-b))·e(g1, P) = 1
+for P = g2^(sum_j (beta_kj - beta_jk) + (y-a)·b_k [+ (y-a)·(r-b)] - w).
+*/
+func (p *ceremonyParty) checkW(k, i int, w, ya, rb *big.Int) bool {
+	sh := p.shares[k]
+	B := new(bn256.G2).ScalarBaseMult(rb)
+	for _, other := range p.shares {
+		B.Add(B, other.B[i])
+	}
+	exp := new(big.Int).Neg(w)
+	if k == 0 {
+		exp.Add(exp, Multiply(ya, rb))
+	}
+	P := new(bn256.G2).ScalarBaseMult(Mod(exp, bn256.Order))
+	P.Add(P, new(bn256.G2).ScalarMult(sh.B[i], ya))
+	for j, other := range p.shares {
+		if j == k {
+			continue
+		}
+		P.Add(P, other.Beta[k][i])
+		P.Add(P, new(bn256.G2).Neg(sh.Beta[j][i]))
+	}
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	return bn256.PairingCheck([]*bn256.G1{sh.A[i], g1}, []*bn256.G2{B, P})
+}
+
+/*
+proofTranscript returns the transcript of a proof of participant prover to
+verifier, or to all if verifier is -1, on index i of this ceremony.
+*/
+func (p *ceremonyParty) proofTranscript(prover, verifier, i int) *transcript {
+	t := newTranscript(ceremonyDST)
+	t.appendBytes("session", p.session)
+	t.appendInt("prover", int64(prover))
+	t.appendInt("verifier", int64(verifier))
+	t.appendInt("index", int64(i))
+	return t
+}
+
+/*
+open adds the shares of the other participants in the messages in to the own
+shares in values, which the messages hold as consecutive scalars of each
+index in turn. check is called on the shares of each participant and index
+before they are added, and its error ends the opening.
+*/
+func (p *ceremonyParty) open(in [][]byte, check func(k, i int, shares []*big.Int) error, values ...[]*big.Int) error {
+	for k, m := range in {
+		if k == p.id {
+			continue
+		}
+		d := &decoder{m: m}
+		for i := range values[0] {
+			shares := make([]*big.Int, len(values))
+			for j := range values {
+				shares[j] = d.scalar(fmt.Sprintf("party %d: share %d of %d", k, j, i))
+			}
+			if d.err != nil {
+				return d.err
+			}
+			if err := check(k, i, shares); err != nil {
+				return err
+			}
+			for j, v := range values {
+				v[i] = Mod(Add(v[i], shares[j]), bn256.Order)
+			}
+		}
+		if err := d.finish(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+broadcast sends msg to every other participant and returns the messages of
+all of them, msg included, after recording them in the view.
+*/
+func (p *ceremonyParty) broadcast(msg []byte) ([][]byte, error) {
+	out := make([][]byte, p.n)
+	for k := range out {
+		out[k] = msg
+	}
+	in, err := p.exchange(out)
+	if err != nil {
+		return nil, err
+	}
+	in[p.id] = msg
+	p.record(in)
+	return in, nil
+}
+
+/*
+record absorbs the broadcast messages of a round into the view.
+*/
+func (p *ceremonyParty) record(in [][]byte) {
+	for _, m := range in {
+		p.view.appendBytes("message", m)
+	}
+}
+
+/*
+exchange sends out[k] to every other participant k and returns their messages,
+with in[p.id] nil, or an error if one of them aborted. The sends run alongside
+the receives, so that transports that block until the message is read, such
+as pipes, do not deadlock. After an error the sends are left to abort.
+*/
+func (p *ceremonyParty) exchange(out [][]byte) ([][]byte, error) {
+	errs := make(chan error, p.n)
+	for k := 0; k < p.n; k++ {
+		if k == p.id {
+			continue
+		}
+		p.sends.Add(1)
+		go func(k int) {
+			defer p.sends.Done()
+			if err := p.t.Send(k, append([]byte{msgData}, out[k]...)); err != nil {
+				errs <- fmt.Errorf("ccs08: ceremony: send to %d: %v", k, err)
+			}
+		}(k)
+	}
+	in := make([][]byte, p.n)
+	for k := 0; k < p.n; k++ {
+		if k == p.id {
+			continue
+		}
+		m, err := p.t.Recv(k)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("ccs08: ceremony: receive from %d: %v", k, err)
+		case len(m) == 0 || m[0] != msgData:
+			return nil, fmt.Errorf("ccs08: ceremony: party %d aborted", k)
+		}
+		in[k] = m[1:]
+	}
+	p.sends.Wait()
+	close(errs)
+	if err, ok := <-errs; ok {
+		return nil, err
+	}
+	return in, nil
+}
+
+/*
+abort tells the other participants that the ceremony failed, so that they stop
+waiting for this participant's messages. It closes the transport once the
+messages are delivered, or after abortTimeout if a participant has stopped
+reading, which ends every send still in progress.
+*/
+func (p *ceremonyParty) abort() {
+	for k := 0; k < p.n; k++ {
+		if k == p.id {
+			continue
+		}
+		p.sends.Add(1)
+		go func(k int) {
+			defer p.sends.Done()
+			p.t.Send(k, []byte{msgAbort})
+		}(k)
+	}
+	done := make(chan struct{})
+	go func() {
+		p.sends.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(abortTimeout):
+	}
+	p.t.Close()
+	<-done
+}
+
+/*
+commitShare returns the commitment of participant id to its key share X and
+Paillier modulus n, a hash that the random nonce keeps hiding.
+*/
+func commitShare(id int, X *bn256.G1, n *big.Int, nonce []byte) []byte {
+	t := newTranscript(ceremonyDST)
+	t.appendInt("party", int64(id))
+	t.appendBytes("key share", X.Marshal())
+	t.appendBytes("Paillier key", n.Bytes())
+	t.appendBytes("nonce", nonce)
+	return t.h.Sum(nil)
+}
+
+/*
+proveShare returns a Schnorr proof (R, z) of knowledge of x for X = g1^x,
+whose challenge binds the participant id and the commitments of the ceremony,
+so that it cannot be replayed by another participant or in another ceremony.
+*/
+func proveShare(x *big.Int, X *bn256.G1, id int, commitments [][]byte) (*bn256.G1, *big.Int, error) {
+	k, err := rand.Int(rand.Reader, bn256.Order)
+	if err != nil {
+		return nil, nil, err
+	}
+	R := new(bn256.G1).ScalarBaseMult(k)
+	c := shareChallenge(X, R, id, commitments)
+	return R, Mod(Add(k, Multiply(c, x)), bn256.Order), nil
+}
+
+/*
+verifyShare checks a proof of proveShare: g1^z = R·X^c.
+*/
+func verifyShare(X, R *bn256.G1, z *big.Int, id int, commitments [][]byte) bool {
+	c := shareChallenge(X, R, id, commitments)
+	rhs := new(bn256.G1).ScalarMult(X, c)
+	rhs.Add(rhs, R)
+	return bytes.Equal(new(bn256.G1).ScalarBaseMult(z).Marshal(), rhs.Marshal())
+}
+
+func shareChallenge(X, R *bn256.G1, id int, commitments [][]byte) *big.Int {
+	t := newTranscript(ceremonyDST)
+	t.appendInt("party", int64(id))
+	for _, c := range commitments {
+		t.appendBytes("commitment", c)
+	}
+	t.appendBytes("key share", X.Marshal())
+	t.appendBytes("R", R.Marshal())
+	return t.challenge()
+}
+
+func randScalars(n int64) ([]*big.Int, error) {
+	ret := make([]*big.Int, n)
+	for i := range ret {
+		var err error
+		if ret[i], err = rand.Int(rand.Reader, bn256.Order); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func (d *decoder) ciphertext(pk *paillierPublicKey, field string) *big.Int {
+	b := d.bytes(field)
+	if d.err != nil {
+		return nil
+	}
+	c := new(big.Int).SetBytes(b)
+	if !pk.valid(c) {
+		d.err = fmt.Errorf("ccs08: %s: invalid ciphertext", field)
+		return nil
+	}
+	return c
+}
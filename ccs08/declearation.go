@@ -13,8 +13,9 @@ This must be computed in a trusted setup.
 type ParamsULProver struct {
 	signatures map[string]*bn256.G2
 	H          *bn256.G2
-	// TODO:must protect the private key
-	kp keypair
+	// pubk is the public key of the Issuer, whose private key the prover
+	// never holds.
+	pubk *bn256.G1
 	// u determines the amount of signatures we need in the public params.
 	// Each signature can be compressed to just 1 field element of 256 bits.
 	// Then the parameters have minimum size equal to 256*u bits.
@@ -31,7 +32,10 @@ This must be computed in a trusted setup.
 type ParamsULVerifier struct {
 	H    *bn256.G2
 	pubk *bn256.G1
-	u, l int64
+	// pubkBase is the precomputed table of pubk, which every digit of every
+	// proof multiplies.
+	pubkBase *bn256.FixedBaseG1
+	u, l     int64
 }
 
 /*
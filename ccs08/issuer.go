@@ -0,0 +1,156 @@
+package ccs08
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+/*
+Issuer owns the Boneh-Boyen key pair of a [0,u^l) setup. It signs the digits
+0...u-1 and hands out only the PublicParams, so that provers, who could forge
+signatures on digits outside [0,u) with the private key, never see it.
+*/
+type Issuer struct {
+	kp   keypair
+	H    *bn256.G2
+	u, l int64
+}
+
+/*
+PublicParams contains the parameters an Issuer publishes for provers and
+verifiers: the signatures on 0...u-1, H, the public key, u and l.
+*/
+type PublicParams struct {
+	// signatures[i] is the signature on i
+	signatures []*bn256.G2
+	H          *bn256.G2
+	pubk       *bn256.G1
+	u, l       int64
+}
+
+/*
+NewIssuer generates a key pair for the interval [0,u^l) with the commitment
+generator GeneratorHashToCurve.
+*/
+func NewIssuer(u, l int64) (*Issuer, error) {
+	return newIssuer(u, l, GeneratorHashToCurve)
+}
+
+func newIssuer(u, l int64, version GeneratorVersion) (*Issuer, error) {
+	if err := checkBounds(u, l, 0, 0); err != nil {
+		return nil, err
+	}
+	H, err := GeneratorH(version)
+	if err != nil {
+		return nil, err
+	}
+	kp, err := keygen()
+	if err != nil {
+		return nil, err
+	}
+	return &Issuer{kp: kp, H: H, u: u, l: l}, nil
+}
+
+/*
+Params signs the digits 0...u-1 and returns the public parameters.
+*/
+func (issuer *Issuer) Params() (*PublicParams, error) {
+	pp := &PublicParams{
+		signatures: make([]*bn256.G2, issuer.u),
+		H:          issuer.H,
+		pubk:       issuer.kp.pubk,
+		u:          issuer.u,
+		l:          issuer.l,
+	}
+	for i := range pp.signatures {
+		sig, err := sign(new(big.Int).SetInt64(int64(i)), issuer.kp.privk)
+		if err != nil {
+			return nil, err
+		}
+		pp.signatures[i] = sig
+	}
+	return pp, nil
+}
+
+/*
+Validate checks every signature against the public key with verify, and
+returns an error naming the first digit whose signature is invalid.
+*/
+func (pp *PublicParams) Validate() error {
+	if pp.H == nil || pp.pubk == nil || int64(len(pp.signatures)) != pp.u {
+		return errors.New("ccs08: incomplete public parameters")
+	}
+	for i, sig := range pp.signatures {
+		if sig == nil {
+			return fmt.Errorf("ccs08: signature[%d]: missing", i)
+		}
+		ok, err := verify(sig, new(big.Int).SetInt64(int64(i)), pp.pubk)
+		if err != nil {
+			return fmt.Errorf("ccs08: signature[%d]: %v", i, err)
+		}
+		if !ok {
+			return fmt.Errorf("ccs08: signature[%d]: invalid signature", i)
+		}
+	}
+	return nil
+}
+
+/*
+NewProver validates the public parameters and returns a Prover for the
+interval [a,b). For the [0,u^l) proofs of ProveUL alone a and b may be 0.
+*/
+func NewProver(pp *PublicParams, a, b int64) (*Prover, error) {
+	if err := checkBounds(pp.u, pp.l, a, b); err != nil {
+		return nil, err
+	}
+	if err := pp.Validate(); err != nil {
+		return nil, err
+	}
+	return newProver(pp, a, b), nil
+}
+
+/*
+newProver returns a Prover for public parameters that are already trusted.
+*/
+func newProver(pp *PublicParams, a, b int64) *Prover {
+	params := &ParamsULProver{
+		signatures: make(map[string]*bn256.G2),
+		H:          pp.H,
+		pubk:       pp.pubk,
+		u:          pp.u,
+		l:          pp.l,
+	}
+	for i, sig := range pp.signatures {
+		params.signatures[strconv.Itoa(i)] = sig
+	}
+	return &Prover{params: params, a: a, b: b}
+}
+
+/*
+NewVerifier returns a Verifier for the interval [a,b) with the public
+parameters, of which it uses H, pubk, u and l.
+*/
+func NewVerifier(pp *PublicParams, a, b int64) (*Verifier, error) {
+	if err := checkBounds(pp.u, pp.l, a, b); err != nil {
+		return nil, err
+	}
+	if pp.H == nil || pp.pubk == nil {
+		return nil, errors.New("ccs08: incomplete public parameters")
+	}
+	return newVerifier(pp, a, b), nil
+}
+
+func newVerifier(pp *PublicParams, a, b int64) *Verifier {
+	params := &ParamsULVerifier{
+		H:        pp.H,
+		pubk:     pp.pubk,
+		pubkBase: bn256.NewFixedBaseG1(pp.pubk),
+		u:        pp.u,
+		l:        pp.l,
+	}
+	return &Verifier{params: params, a: a, b: b}
+}
@@ -34,17 +34,11 @@ func keygen() (keypair, error) {
 sign receives as input a message and a private key and outputs a digital signature.
 */
 func sign(m *big.Int, privk *big.Int) (*bn256.G2, error) {
-	var (
-		res       bool
-		signature *bn256.G2
-	)
 	inv := ModInverse(Mod(Add(m, privk), bn256.Order), bn256.Order)
-	signature, res = new(bn256.G2).Unmarshal(new(bn256.G2).ScalarBaseMult(inv).Marshal())
-	if res != false {
-		return signature, nil
-	} else {
+	if inv == nil {
 		return nil, errors.New("Error while computing signature.")
 	}
+	return new(bn256.G2).ScalarBaseMult(inv), nil
 }
 
 /*
@@ -61,16 +55,8 @@ func verify(signature *bn256.G2, m *big.Int, pubk *bn256.G1) (bool, error) {
 	gm, e = new(bn256.G1).Unmarshal(new(bn256.G1).ScalarBaseMult(m).Marshal())
 	// y.g^m
 	gm = gm.Add(gm, pubk)
-	// e(y.g^m, sig)
-	p1 := bn256.Pair(gm, signature)
-	// e(g1,g2)
-	g1 := new(bn256.G1).ScalarBaseMult(new(big.Int).SetInt64(1))
-	g2 := new(bn256.G2).ScalarBaseMult(new(big.Int).SetInt64(1))
-	p2 := bn256.Pair(g1, g2)
-	// p1 == p2?
-	p2 = p2.Neg(p2)
-	p1 = p1.Add(p1, p2)
-	res = p1.IsOne()
+	// e(y.g^m, sig).e(g1,g2)^-1 == 1?
+	res = bn256.PairingCheck([]*bn256.G1{gm, new(bn256.G1).Neg(G1)}, []*bn256.G2{signature, G2})
 	if e != false {
 		return res, nil
 	}
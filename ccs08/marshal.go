@@ -2,168 +2,617 @@ package ccs08
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
 	"math/big"
+	"strconv"
 
 	"github.com/blockchain-research/crypto/bn256"
 )
 
 /*
-Marshal is for marshaling the ProofULVerifier into []byte
+Marshal is for marshaling the ProofUL into []byte: a header, the number of
+digits l as a 4-byte integer, then V, D, C, a, zsig, zv, c and zr.
+proof byte size: 10 + (l+2)|G2| + l|GT| + (2l+2)|BINT|
 */
 func (p *ProofUL) Marshal() []byte {
-	const bLInt int = 32
-	var ret []byte
+	return p.marshal(kindProofUL, (*bn256.G2).Marshal)
+}
+
+/*
+MarshalCompressed is Marshal with the G2 elements V, D and C in compressed
+form, which saves 63 bytes per element.
+proof byte size: 10 + (l+2)|G2c| + l|GT| + (2l+2)|BINT|
+*/
+func (p *ProofUL) MarshalCompressed() []byte {
+	return p.marshal(kindProofULCompressed, (*bn256.G2).MarshalCompressed)
+}
 
-	//processing V
+func (p *ProofUL) marshal(kind byte, marshalG2 func(*bn256.G2) []byte) []byte {
+	e := &encoder{}
+	e.uint32(len(p.V))
 	for _, element := range p.V {
-		bV := element.Marshal()
-		ret = append(ret, bV...)
+		e.raw(marshalG2(element))
+	}
+	e.raw(marshalG2(p.D))
+	e.raw(marshalG2(p.C))
+	for _, element := range p.a {
+		e.raw(element.Marshal())
+	}
+	for _, element := range p.zsig {
+		e.scalar(element)
+	}
+	for _, element := range p.zv {
+		e.scalar(element)
 	}
+	e.scalar(p.c)
+	e.scalar(p.zr)
+	return e.finish(kind)
+}
 
-	//processing D
-	bD := p.D.Marshal()
-	ret = append(ret, bD...)
+/*
+Unmarshal is for converting the output of Marshal or MarshalCompressed back
+into proofUL. It returns an error for malformed input, naming the field that
+failed to decode. Like Verifier.Unmarshal it also accepts the headerless
+encoding used before, whose length gives l, so that stored proofs still decode.
+*/
+func Unmarshal(m []byte, p *ProofUL) error {
+	d, err := newDecoder(m, kindProofUL, kindProofULCompressed)
+	if err != nil {
+		if L, ok := legacyProofULDigits(len(m)); ok {
+			return (&decoder{m: m, kind: kindProofUL}).proofULDigits(p, L)
+		}
+		return err
+	}
+	return d.proofUL(p)
+}
 
-	//processing C
-	bC := p.C.Marshal()
-	ret = append(ret, bC...)
+/*
+UnmarshalCompressed is Unmarshal for the output of MarshalCompressed only.
+*/
+func UnmarshalCompressed(m []byte, p *ProofUL) error {
+	d, err := newDecoder(m, kindProofULCompressed)
+	if err != nil {
+		return err
+	}
+	return d.proofUL(p)
+}
 
-	//processing a
-	for _, element := range p.a {
-		ba := element.Marshal()
-		ret = append(ret, ba...)
+/*
+proofUL reads the body of a ProofUL, with G2 elements compressed if the kind of
+the encoding is kindProofULCompressed.
+*/
+func (d *decoder) proofUL(p *ProofUL) error {
+	g2Size := 128
+	if d.kind == kindProofULCompressed {
+		g2Size = bn256.CompressedG2Size
 	}
+	L := d.uint32("l")
+	if d.err == nil && (L == 0 || uint64(len(d.m)) != uint64(L)*uint64(g2Size+384+2*32)+uint64(2*g2Size+2*32)) {
+		return fmt.Errorf("ccs08: proof length does not match l = %d", L)
+	}
+	return d.proofULDigits(p, L)
+}
 
-	//processing zsig
-	for _, element := range p.zsig {
-		bzsig := make([]byte, bLInt, bLInt)
-		b := element.Bytes()
-		copy(bzsig[bLInt-len(b):], b)
-		ret = append(ret, bzsig...)
+/*
+proofULDigits reads the elements of a ProofUL with l = L, which the caller has
+checked against the remaining length.
+*/
+func (d *decoder) proofULDigits(p *ProofUL, L uint32) error {
+	g2 := d.g2
+	if d.kind == kindProofULCompressed {
+		g2 = d.g2Compressed
 	}
 
-	//processing zv
-	for _, element := range p.zv {
-		bzv := make([]byte, bLInt, bLInt)
-		b := element.Bytes()
-		copy(bzv[bLInt-len(b):], b)
-		ret = append(ret, bzv...)
+	V := make([]*bn256.G2, L)
+	for i := range V {
+		V[i] = g2(fmt.Sprintf("V[%d]", i))
+	}
+	D := g2("D")
+	C := g2("C")
+	a := make([]*bn256.GT, L)
+	for i := range a {
+		a[i] = d.gt(fmt.Sprintf("a[%d]", i))
+	}
+	zsig := make([]*big.Int, L)
+	for i := range zsig {
+		zsig[i] = d.scalar(fmt.Sprintf("zsig[%d]", i))
+	}
+	zv := make([]*big.Int, L)
+	for i := range zv {
+		zv[i] = d.scalar(fmt.Sprintf("zv[%d]", i))
+	}
+	c := d.scalar("c")
+	zr := d.scalar("zr")
+	if err := d.finish(); err != nil {
+		return err
 	}
 
-	//processing c, m, zr
-	bc := make([]byte, bLInt, bLInt)
-	b := p.c.Bytes()
-	copy(bc[bLInt-len(b):], b)
-	ret = append(ret, bc...)
+	p.V, p.D, p.C, p.a = V, D, C, a
+	p.zsig, p.zv, p.c, p.zr = zsig, zv, c, zr
+	return nil
+}
 
-	bzr := make([]byte, bLInt, bLInt)
-	b = p.zr.Bytes()
-	copy(bzr[bLInt-len(b):], b)
-	ret = append(ret, bzr...)
+/*
+Marshal is for marshaling the Proof into []byte: a header followed by the two
+[0,u^l) proofs in the format of ProofUL.Marshal, each prefixed by its length.
+*/
+func (p *Proof) Marshal() []byte {
+	e := &encoder{}
+	e.bytes(p.proof1.Marshal())
+	e.bytes(p.proof2.Marshal())
+	return e.finish(kindProof)
+}
 
-	return ret
+/*
+MarshalCompressed is Marshal with the two proofs in the format of
+ProofUL.MarshalCompressed.
+*/
+func (p *Proof) MarshalCompressed() []byte {
+	e := &encoder{}
+	e.bytes(p.proof1.MarshalCompressed())
+	e.bytes(p.proof2.MarshalCompressed())
+	return e.finish(kindProofCompressed)
 }
 
 /*
-UnMarshal is for converting []byte back into proofUL
-proof byte size: (l+2)|G2| + l|GT| + (2l+2)|BINT|
+Unmarshal is for converting the output of Proof.Marshal or
+Proof.MarshalCompressed back into a Proof.
 */
-func Unmarshal(m []byte, p *ProofUL) {
-	const bLG2 int64 = 128
-	const bLGT int64 = 384
-	const bLInt int64 = 32
-	var i int64
-	L := (int64(len(m)) - 2*bLG2 - 2*bLInt) / (bLG2 + bLGT + 2*bLInt)
-	//getting V
-	for i = 0; i < L; i++ {
-		v, _ := new(bn256.G2).Unmarshal(m[i*bLG2 : (i+1)*bLG2])
-		p.V = append(p.V, v)
+func (p *Proof) Unmarshal(m []byte) error {
+	d, err := newDecoder(m, kindProof, kindProofCompressed)
+	if err != nil {
+		return err
 	}
+	kind := kindProofUL
+	if d.kind == kindProofCompressed {
+		kind = kindProofULCompressed
+	}
+	b1 := d.bytes("proof1")
+	b2 := d.bytes("proof2")
+	if err := d.finish(); err != nil {
+		return err
+	}
+	proof1, proof2 := &ProofUL{}, &ProofUL{}
+	for _, half := range []struct {
+		name  string
+		m     []byte
+		proof *ProofUL
+	}{{"proof1", b1, proof1}, {"proof2", b2, proof2}} {
+		d, err := newDecoder(half.m, kind)
+		if err == nil {
+			err = d.proofUL(half.proof)
+		}
+		if err != nil {
+			return fmt.Errorf("ccs08: %s: %v", half.name, err)
+		}
+	}
+	p.proof1, p.proof2 = proof1, proof2
+	return nil
+}
 
-	//getting D
-	p.D, _ = new(bn256.G2).Unmarshal(m[L*bLG2 : (L+1)*bLG2])
-
-	//getting C
-	p.C, _ = new(bn256.G2).Unmarshal(m[(L+1)*bLG2 : (L+2)*bLG2])
+/*
+Marshal is for marshaling the PublicParams into []byte: a header, u and l, then
+H, pubk and the signatures on 0...u-1 in order.
+*/
+func (pp *PublicParams) Marshal() []byte {
+	e := &encoder{}
+	e.int64(pp.u)
+	e.int64(pp.l)
+	e.params(pp)
+	return e.finish(kindParams)
+}
 
-	//getting a
-	index := (L + 2) * bLG2
-	for i = 0; i < L; i++ {
-		a, _ := new(bn256.GT).Unmarshal(m[index+i*bLGT : index+(i+1)*bLGT])
-		p.a = append(p.a, a)
+/*
+Unmarshal is for converting the output of PublicParams.Marshal back into
+PublicParams. It does not check the signatures, which is done by Validate.
+*/
+func (pp *PublicParams) Unmarshal(m []byte) error {
+	d, err := newDecoder(m, kindParams)
+	if err != nil {
+		return err
+	}
+	u, l := d.int64("u"), d.int64("l")
+	if d.err == nil {
+		d.err = checkBounds(u, l, 0, 0)
 	}
+	pp2 := d.params(u, l)
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*pp = *pp2
+	return nil
+}
+
+/*
+Marshal is for marshaling the Prover into []byte: a header, u, l, a and b,
+then H, pubk and the signatures on 0...u-1 in order.
+*/
+func (prover *Prover) Marshal() []byte {
+	e := &encoder{}
+	e.bounds(prover.params.u, prover.params.l, prover.a, prover.b)
+	e.params(&PublicParams{
+		signatures: prover.signatures(),
+		H:          prover.params.H,
+		pubk:       prover.params.pubk,
+	})
+	return e.finish(kindProver)
+}
 
-	//get zsig
-	index = (L+2)*bLG2 + L*bLGT
-	for i = 0; i < L; i++ {
-		zsig := new(big.Int).SetBytes(m[index+i*bLInt : index+(i+1)*bLInt])
-		p.zsig = append(p.zsig, zsig)
+/*
+Unmarshal is for converting the output of Prover.Marshal back into a Prover.
+As in NewProver, every signature is checked against pubk.
+*/
+func (prover *Prover) Unmarshal(m []byte) error {
+	d, err := newDecoder(m, kindProver)
+	if err != nil {
+		return err
+	}
+	u, l, a, b := d.bounds()
+	pp := d.params(u, l)
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if err := pp.Validate(); err != nil {
+		return err
 	}
+	*prover = *newProver(pp, a, b)
+	return nil
+}
 
-	//get zv
-	index = (L+2)*bLG2 + L*bLGT + L*bLInt
-	for i = 0; i < L; i++ {
-		zv := new(big.Int).SetBytes(m[index+i*bLInt : index+(i+1)*bLInt])
-		p.zv = append(p.zv, zv)
+/*
+signatures returns the signatures of the prover as a slice indexed by digit.
+*/
+func (prover *Prover) signatures() []*bn256.G2 {
+	sigs := make([]*bn256.G2, prover.params.u)
+	for i := range sigs {
+		sigs[i] = prover.params.signatures[strconv.Itoa(i)]
 	}
+	return sigs
+}
 
-	//get c, m, zr
-	index = (L+2)*bLG2 + L*bLGT + (L*2)*bLInt
-	p.c = new(big.Int).SetBytes(m[index : index+bLInt])
+/*
+Marshal is for marshaling the Verifier into []byte: a header, u, l, a and b,
+then H and pubk.
+*/
+func (v *Verifier) Marshal() []byte {
+	e := &encoder{}
+	e.bounds(v.params.u, v.params.l, v.a, v.b)
+	e.raw(v.params.H.Marshal())
+	e.raw(v.params.pubk.Marshal())
+	return e.finish(kindVerifier)
+}
 
-	index = (L+2)*bLG2 + L*bLGT + (L*2+1)*bLInt
-	p.zr = new(big.Int).SetBytes(m[index : index+bLInt])
-	return
+/*
+Unmarshal is for converting the output of Verifier.Marshal back into a
+Verifier. It also accepts the headerless encoding of H, pubk, u and l used
+before, for which a and b are 0.
+*/
+func (v *Verifier) Unmarshal(m []byte) error {
+	var (
+		u, l, a, b int64
+		d          *decoder
+		err        error
+	)
+	if len(m) == legacyVerifierSize {
+		d = &decoder{m: m}
+	} else {
+		d, err = newDecoder(m, kindVerifier)
+		if err != nil {
+			return err
+		}
+		u, l, a, b = d.bounds()
+	}
+	params := &ParamsULVerifier{}
+	params.H = d.g2("H")
+	params.pubk = d.g1("pubk")
+	if len(m) == legacyVerifierSize {
+		u = d.varint("u")
+		l = d.varint("l")
+		if d.err == nil {
+			d.err = checkBounds(u, l, a, b)
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	params.u, params.l = u, l
+	params.pubkBase = bn256.NewFixedBaseG1(params.pubk)
+	v.params, v.a, v.b = params, a, b
+	return nil
 }
 
+// encodingVersion is the first byte of the encodings of ProofUL, Proof,
+// Prover, Verifier and PublicParams, followed by the kind of the encoding and the length of the rest
+// as a 4-byte big-endian integer.
+const encodingVersion = 1
+
+const (
+	kindProof    byte = 1
+	kindProver   byte = 2
+	kindVerifier byte = 3
+	kindParams   byte = 4
+
+	kindProofUL           byte = 5
+	kindProofULCompressed byte = 6
+	kindProofCompressed   byte = 7
+)
+
+// headerSize is the size of the version, kind and length.
+const headerSize = 6
+
+// legacyVerifierSize is the size of the headerless Verifier encoding: H, pubk,
+// and u and l as varints padded to binary.MaxVarintLen64 bytes.
+const legacyVerifierSize = 128 + 64 + 2*binary.MaxVarintLen64
+
 /*
-Marshal is for marshaling the ParamsULVerifier into []byte
+legacyProofULDigits returns l for a headerless ProofUL encoding of size n,
+(l+2)·128 + l·384 + (2l+2)·32 bytes, and false if no l gives n. A versioned
+encoding is never taken for it: its header would have to fail to decode.
 */
-func (v *Verifier) Marshal() []byte {
-	const bLInt64 int = binary.MaxVarintLen64
-	var ret []byte
-	//processing H
-	bH := v.params.H.Marshal()
-	ret = append(ret, bH...)
+func legacyProofULDigits(n int) (uint32, bool) {
+	const digit, fixed = 128 + 384 + 2*32, 2*128 + 2*32
+	if n < digit+fixed || (n-fixed)%digit != 0 || (n-fixed)/digit > math.MaxUint32 {
+		return 0, false
+	}
+	return uint32((n - fixed) / digit), true
+}
+
+type encoder struct {
+	body []byte
+}
+
+func (e *encoder) raw(b []byte) {
+	e.body = append(e.body, b...)
+}
+
+func (e *encoder) bytes(b []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+	e.raw(n[:])
+	e.raw(b)
+}
+
+func (e *encoder) uint32(x int) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(x))
+	e.raw(b[:])
+}
+
+func (e *encoder) int64(x int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(x))
+	e.raw(b[:])
+}
+
+func (e *encoder) scalar(x *big.Int) {
+	var b [32]byte
+	e.raw(x.FillBytes(b[:]))
+}
 
-	//processing pubk
-	bpubk := v.params.pubk.Marshal()
-	ret = append(ret, bpubk...)
+func (e *encoder) bounds(u, l, a, b int64) {
+	e.int64(u)
+	e.int64(l)
+	e.int64(a)
+	e.int64(b)
+}
 
-	//processing u
-	bu := make([]byte, bLInt64, bLInt64)
-	binary.PutVarint(bu, v.params.u)
-	ret = append(ret, bu...)
+func (e *encoder) params(pp *PublicParams) {
+	e.raw(pp.H.Marshal())
+	e.raw(pp.pubk.Marshal())
+	for _, sig := range pp.signatures {
+		e.raw(sig.Marshal())
+	}
+}
 
-	//processing l
-	bl := make([]byte, bLInt64, bLInt64)
-	binary.PutVarint(bl, v.params.l)
-	ret = append(ret, bl...)
+func (e *encoder) finish(kind byte) []byte {
+	ret := make([]byte, headerSize, headerSize+len(e.body))
+	ret[0] = encodingVersion
+	ret[1] = kind
+	binary.BigEndian.PutUint32(ret[2:], uint32(len(e.body)))
+	return append(ret, e.body...)
+}
 
-	return ret
+/*
+decoder reads the fields of an encoding in order. The first error is kept in
+err, names the field, and turns every later read into a no-op.
+*/
+type decoder struct {
+	m    []byte
+	kind byte
+	err  error
 }
 
 /*
-UnMarshal is for converting []byte back into ParamsULVerifier
+newDecoder checks the header of m, which must be of one of the given kinds,
+and returns a decoder for the rest.
 */
-func (v *Verifier) Unmarshal(m []byte) {
-	const bLInt64 int64 = binary.MaxVarintLen64
-	const bLG2 int64 = 128
-	const bLG1 int64 = 64
+func newDecoder(m []byte, kinds ...byte) (*decoder, error) {
+	if len(m) < headerSize {
+		return nil, errors.New("ccs08: encoding shorter than its header")
+	}
+	if m[0] != encodingVersion {
+		return nil, fmt.Errorf("ccs08: unsupported encoding version %d", m[0])
+	}
+	known := false
+	for _, kind := range kinds {
+		known = known || m[1] == kind
+	}
+	if !known {
+		return nil, fmt.Errorf("ccs08: encoding of kind %d, expected one of %v", m[1], kinds)
+	}
+	if n := binary.BigEndian.Uint32(m[2:]); uint64(n) != uint64(len(m)-headerSize) {
+		return nil, fmt.Errorf("ccs08: header length %d does not match the %d bytes that follow", n, len(m)-headerSize)
+	}
+	return &decoder{m: m[headerSize:], kind: m[1]}, nil
+}
+
+func (d *decoder) next(n int, field string) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.m) < n {
+		d.err = fmt.Errorf("ccs08: %s: unexpected end of input", field)
+		return nil
+	}
+	b := d.m[:n]
+	d.m = d.m[n:]
+	return b
+}
 
-	if v.params == nil {
-		v.params = &ParamsULVerifier{}
+func (d *decoder) bytes(field string) []byte {
+	n := d.next(4, field)
+	if n == nil {
+		return nil
 	}
-	//getting H
-	v.params.H, _ = new(bn256.G2).Unmarshal(m[0:bLG2])
+	return d.next(int(binary.BigEndian.Uint32(n)), field)
+}
+
+func (d *decoder) uint32(field string) uint32 {
+	b := d.next(4, field)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
 
-	//getting pubk
-	v.params.pubk, _ = new(bn256.G1).Unmarshal(m[bLG2 : bLG1+bLG2])
+func (d *decoder) int64(field string) int64 {
+	b := d.next(8, field)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
 
-	//getting u
-	v.params.u, _ = binary.Varint(m[bLG1+bLG2 : bLG1+bLG2+bLInt64])
+func (d *decoder) varint(field string) int64 {
+	b := d.next(binary.MaxVarintLen64, field)
+	if b == nil {
+		return 0
+	}
+	x, n := binary.Varint(b)
+	if n <= 0 {
+		d.err = fmt.Errorf("ccs08: %s: invalid varint", field)
+	}
+	return x
+}
 
-	//getting l
-	v.params.l, _ = binary.Varint(m[bLG1+bLG2+bLInt64 : bLG1+bLG2+2*bLInt64])
-	return
+func (d *decoder) scalar(field string) *big.Int {
+	b := d.next(32, field)
+	if b == nil {
+		return nil
+	}
+	x := new(big.Int).SetBytes(b)
+	if x.Cmp(bn256.Order) >= 0 {
+		d.err = fmt.Errorf("ccs08: %s: scalar out of range", field)
+		return nil
+	}
+	return x
+}
+
+func (d *decoder) g1(field string) *bn256.G1 {
+	b := d.next(64, field)
+	if b == nil {
+		return nil
+	}
+	g, ok := new(bn256.G1).Unmarshal(b)
+	if !ok {
+		d.err = fmt.Errorf("ccs08: %s: invalid G1 element", field)
+	}
+	return g
+}
+
+func (d *decoder) g2(field string) *bn256.G2 {
+	b := d.next(128, field)
+	if b == nil {
+		return nil
+	}
+	g, err := new(bn256.G2).Unmarshal(b)
+	if err != nil {
+		d.err = fmt.Errorf("ccs08: %s: %v", field, err)
+	}
+	return g
+}
+
+func (d *decoder) g2Compressed(field string) *bn256.G2 {
+	b := d.next(bn256.CompressedG2Size, field)
+	if b == nil {
+		return nil
+	}
+	g, err := new(bn256.G2).UnmarshalCompressed(b)
+	if err != nil {
+		d.err = fmt.Errorf("ccs08: %s: %v", field, err)
+	}
+	return g
+}
+
+func (d *decoder) gt(field string) *bn256.GT {
+	b := d.next(384, field)
+	if b == nil {
+		return nil
+	}
+	g, err := new(bn256.GT).Unmarshal(b)
+	if err != nil {
+		d.err = fmt.Errorf("ccs08: %s: %v", field, err)
+	}
+	return g
+}
+
+/*
+bounds reads u, l, a and b and checks that they describe a usable setup.
+*/
+func (d *decoder) bounds() (u, l, a, b int64) {
+	u, l = d.int64("u"), d.int64("l")
+	a, b = d.int64("a"), d.int64("b")
+	if d.err == nil {
+		d.err = checkBounds(u, l, a, b)
+	}
+	return u, l, a, b
+}
+
+/*
+params reads H, pubk and u signatures, after checking that exactly that much
+input is left.
+*/
+func (d *decoder) params(u, l int64) *PublicParams {
+	if d.err != nil {
+		return nil
+	}
+	if rest := len(d.m) - (128 + 64); rest < 0 || rest%128 != 0 || int64(rest/128) != u {
+		d.err = fmt.Errorf("ccs08: signatures: length does not match u = %d", u)
+		return nil
+	}
+	pp := &PublicParams{u: u, l: l}
+	pp.H = d.g2("H")
+	pp.pubk = d.g1("pubk")
+	pp.signatures = make([]*bn256.G2, 0, u)
+	for i := int64(0); i < u && d.err == nil; i++ {
+		pp.signatures = append(pp.signatures, d.g2(fmt.Sprintf("signature[%d]", i)))
+	}
+	return pp
+}
+
+func checkBounds(u, l, a, b int64) error {
+	switch {
+	case u < 2:
+		return fmt.Errorf("ccs08: u: %d is less than 2", u)
+	case l < 1:
+		return fmt.Errorf("ccs08: l: %d is less than 1", l)
+	case a > b:
+		return fmt.Errorf("ccs08: a: %d is greater than b = %d", a, b)
+	}
+	return nil
+}
+
+/*
+finish returns the first error, or an error if input is left over.
+*/
+func (d *decoder) finish() error {
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.m) != 0 {
+		return fmt.Errorf("ccs08: %d trailing bytes", len(d.m))
+	}
+	return nil
 }
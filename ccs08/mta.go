@@ -0,0 +1,441 @@
+package ccs08
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+/*
+This file contains the zero-knowledge proofs that keep the
+multiplicative-to-additive conversion of a Ceremony honest, after Canetti,
+Gennaro, Goldfeder, Makriyannis and Peled, "UC Non-Interactive, Proactive,
+Threshold ECDSA with Identifiable Aborts" (CGGMP).
+
+A participant j that sends K = Enc(a) proves to every other participant k
+that K encrypts the discrete logarithm of g1^a, and a participant k that
+responds with D = K^b·Enc(beta) proves to j that it knows b and beta with
+g2^b and g2^beta as published. Both proofs show that the values are at most
+2^(mtaL+mtaEps) and 2^(mtaLPrime+mtaEps) with ring-Pedersen commitments under
+the parameters of the verifier, which the prover cannot open to anything else.
+So a·b+beta never wraps modulo a Paillier modulus of paillierBits bits, no
+response depends on more of a or b than their residues modulo bn256.Order,
+and whether a later check fails reveals nothing about them.
+*/
+
+const (
+	// mtaL, mtaLPrime and mtaEps are the bits of a and b, the bits of beta
+	// and the slack of the range proofs, ℓ, ℓ' and ε of CGGMP.
+	mtaL      = 256
+	mtaLPrime = 5 * mtaL
+	mtaEps    = 2 * mtaL
+
+	// ringPedersenRounds is the number of binary challenges of the proof
+	// that s is a power of t.
+	ringPedersenRounds = 128
+)
+
+/*
+ringPedersen are the parameters of the commitments s^x·t^m mod n of a
+participant, over its Paillier modulus n, with t a random square and
+s = t^lambda.
+*/
+type ringPedersen struct {
+	n, s, t *big.Int
+}
+
+/*
+ringPedersen returns ring-Pedersen parameters over the modulus of sk and
+their trapdoor lambda.
+*/
+func (sk *paillierKey) ringPedersen() (*ringPedersen, *big.Int, error) {
+	tau, err := sk.randomness()
+	if err != nil {
+		return nil, nil, err
+	}
+	lambda, err := rand.Int(rand.Reader, sk.phi)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := new(big.Int).Exp(tau, big.NewInt(2), sk.n)
+	s := sk.exp(t, lambda)
+	return &ringPedersen{n: sk.n, s: s, t: t}, lambda, nil
+}
+
+/*
+commit returns s^x·t^m mod n for x, m >= 0.
+*/
+func (rp *ringPedersen) commit(x, m *big.Int) *big.Int {
+	c := new(big.Int).Exp(rp.s, x, rp.n)
+	c.Mul(c, new(big.Int).Exp(rp.t, m, rp.n))
+	return c.Mod(c, rp.n)
+}
+
+/*
+valid reports whether x is a unit modulo n.
+*/
+func (rp *ringPedersen) valid(x *big.Int) bool {
+	if x == nil || x.Sign() <= 0 || x.Cmp(rp.n) >= 0 {
+		return false
+	}
+	return new(big.Int).GCD(nil, nil, x, rp.n).Cmp(big.NewInt(1)) == 0
+}
+
+/*
+randomnessBits bounds the bits of the responses that open the commitments of
+a range proof with randomness below 2^(mtaL+mtaEps)·n.
+*/
+func (rp *ringPedersen) randomnessBits() int {
+	return mtaL + mtaEps + rp.n.BitLen() + 1
+}
+
+/*
+prove proves that participant id knows lambda with s = t^lambda, Π^prm of
+CGGMP: for each of ringPedersenRounds challenge bits e it returns A = t^alpha
+for a random alpha and z = alpha + e·lambda mod phi(n).
+*/
+func (rp *ringPedersen) prove(sk *paillierKey, lambda *big.Int, id int) ([]*big.Int, []*big.Int, error) {
+	alpha := make([]*big.Int, ringPedersenRounds)
+	A := make([]*big.Int, ringPedersenRounds)
+	for i := range A {
+		var err error
+		if alpha[i], err = rand.Int(rand.Reader, sk.phi); err != nil {
+			return nil, nil, err
+		}
+		A[i] = sk.exp(rp.t, alpha[i])
+	}
+	e := rp.challenge(id, A)
+	z := make([]*big.Int, ringPedersenRounds)
+	for i := range z {
+		z[i] = alpha[i]
+		if e.Bit(i) == 1 {
+			z[i] = Mod(Add(alpha[i], lambda), sk.phi)
+		}
+	}
+	return A, z, nil
+}
+
+/*
+verify checks a proof of prove by participant id: t^z = A·s^e mod n.
+*/
+func (rp *ringPedersen) verify(id int, A, z []*big.Int) error {
+	if !rp.valid(rp.s) || !rp.valid(rp.t) {
+		return errors.New("ccs08: invalid ring-Pedersen parameters")
+	}
+	if len(A) != ringPedersenRounds || len(z) != ringPedersenRounds {
+		return fmt.Errorf("ccs08: ring-Pedersen proof of %d rounds", len(A))
+	}
+	e := rp.challenge(id, A)
+	for i := range A {
+		if !rp.valid(A[i]) || z[i].Cmp(rp.n) >= 0 {
+			return fmt.Errorf("ccs08: ring-Pedersen proof[%d]: out of range", i)
+		}
+		rhs := new(big.Int).Set(A[i])
+		if e.Bit(i) == 1 {
+			rhs.Mul(rhs, rp.s).Mod(rhs, rp.n)
+		}
+		if new(big.Int).Exp(rp.t, z[i], rp.n).Cmp(rhs) != 0 {
+			return fmt.Errorf("ccs08: ring-Pedersen proof[%d]: invalid response", i)
+		}
+	}
+	return nil
+}
+
+/*
+challenge returns the challenge bits of the ring-Pedersen proof of
+participant id, a hash of the parameters, id and the commitments A.
+*/
+func (rp *ringPedersen) challenge(id int, A []*big.Int) *big.Int {
+	t := newTranscript(ceremonyDST)
+	t.appendInt("party", int64(id))
+	t.appendNats("ring-Pedersen", rp.n, rp.s, rp.t)
+	t.appendNats("A", A...)
+	return new(big.Int).SetBytes(t.h.Sum(nil))
+}
+
+/*
+encProof is a proof that a Paillier ciphertext K encrypts the discrete
+logarithm a of X = g1^a with 0 <= a <= 2^(mtaL+mtaEps), Π^log* of CGGMP.
+*/
+type encProof struct {
+	S, A, D    *big.Int
+	Y          *bn256.G1
+	z1, z2, z3 *big.Int
+}
+
+/*
+proveEnc proves that K = Enc(a; rho) under pk encrypts the discrete logarithm
+of X = g1^a, for a verifier with parameters rp. The transcript t binds the
+prover, the verifier and the ceremony.
+*/
+func proveEnc(t *transcript, pk *paillierPublicKey, rp *ringPedersen, K *big.Int, X *bn256.G1, a, rho *big.Int) (*encProof, error) {
+	alpha, err := randBits(mtaL + mtaEps)
+	if err != nil {
+		return nil, err
+	}
+	mu, err := randBelow(mtaL, rp.n)
+	if err != nil {
+		return nil, err
+	}
+	gamma, err := randBelow(mtaL+mtaEps, rp.n)
+	if err != nil {
+		return nil, err
+	}
+	r, err := pk.randomness()
+	if err != nil {
+		return nil, err
+	}
+	pf := &encProof{
+		S: rp.commit(a, mu),
+		A: pk.encryptWith(alpha, r),
+		D: rp.commit(alpha, gamma),
+		Y: new(bn256.G1).ScalarBaseMult(Mod(alpha, bn256.Order)),
+	}
+	e := pf.challenge(t, pk, rp, K, X)
+	pf.z1 = Add(alpha, Multiply(e, a))
+	pf.z2 = Mod(Multiply(r, new(big.Int).Exp(rho, e, pk.n)), pk.n)
+	pf.z3 = Add(gamma, Multiply(e, mu))
+	return pf, nil
+}
+
+/*
+verify checks a proof of proveEnc: Enc(z1; z2) = A·K^e, g1^z1 = Y·X^e and
+s^z1·t^z3 = D·S^e, with z1 in range.
+*/
+func (pf *encProof) verify(t *transcript, pk *paillierPublicKey, rp *ringPedersen, K *big.Int, X *bn256.G1) error {
+	if !rp.valid(pf.S) || !rp.valid(pf.D) || !pk.valid(pf.A) {
+		return errors.New("ccs08: range proof: commitment out of range")
+	}
+	if pf.z1.BitLen() > mtaL+mtaEps || pf.z2.Sign() <= 0 || pf.z2.Cmp(pk.n) >= 0 ||
+		pf.z3.BitLen() > rp.randomnessBits() {
+		return errors.New("ccs08: range proof: response out of range")
+	}
+	e := pf.challenge(t, pk, rp, K, X)
+	if pk.encryptWith(pf.z1, pf.z2).Cmp(pk.add(pf.A, pk.mul(K, e))) != 0 {
+		return errors.New("ccs08: range proof: ciphertext does not match")
+	}
+	Xe := new(bn256.G1).ScalarMult(X, e)
+	if !bytes.Equal(new(bn256.G1).ScalarBaseMult(Mod(pf.z1, bn256.Order)).Marshal(), Xe.Add(Xe, pf.Y).Marshal()) {
+		return errors.New("ccs08: range proof: group element does not match")
+	}
+	if rp.commit(pf.z1, pf.z3).Cmp(mulExp(pf.D, pf.S, e, rp.n)) != 0 {
+		return errors.New("ccs08: range proof: commitment does not match")
+	}
+	return nil
+}
+
+func (pf *encProof) challenge(t *transcript, pk *paillierPublicKey, rp *ringPedersen, K *big.Int, X *bn256.G1) *big.Int {
+	t.appendNats("Paillier key", pk.n)
+	t.appendNats("ring-Pedersen", rp.n, rp.s, rp.t)
+	t.appendNats("K", K)
+	t.appendBytes("X", X.Marshal())
+	t.appendNats("S, A, D", pf.S, pf.A, pf.D)
+	t.appendBytes("Y", pf.Y.Marshal())
+	return t.challenge()
+}
+
+/*
+affProof is a proof that a Paillier ciphertext D = K^b·Enc(beta) for the
+discrete logarithms b of B = g2^b and beta of Beta = g2^beta, with
+0 <= b <= 2^(mtaL+mtaEps) and 0 <= beta <= 2^(mtaLPrime+mtaEps), Π^aff-g of
+CGGMP with g2^beta in place of an encryption of beta.
+*/
+type affProof struct {
+	A, E, S, F, T     *big.Int
+	Bx, By            *bn256.G2
+	z1, z2, z3, z4, w *big.Int
+}
+
+/*
+proveAff proves that D = K^b·Enc(beta; rho) under pk, for B = g2^b and
+Beta = g2^beta, to the owner of pk with parameters rp.
+*/
+func proveAff(t *transcript, pk *paillierPublicKey, rp *ringPedersen, K, D *big.Int, B, Beta *bn256.G2, b, beta, rho *big.Int) (*affProof, error) {
+	alpha, err := randBits(mtaL + mtaEps)
+	if err != nil {
+		return nil, err
+	}
+	betaMask, err := randBits(mtaLPrime + mtaEps)
+	if err != nil {
+		return nil, err
+	}
+	r, err := pk.randomness()
+	if err != nil {
+		return nil, err
+	}
+	gamma, err := randBelow(mtaL+mtaEps, rp.n)
+	if err != nil {
+		return nil, err
+	}
+	delta, err := randBelow(mtaL+mtaEps, rp.n)
+	if err != nil {
+		return nil, err
+	}
+	m, err := randBelow(mtaL, rp.n)
+	if err != nil {
+		return nil, err
+	}
+	mu, err := randBelow(mtaL, rp.n)
+	if err != nil {
+		return nil, err
+	}
+	pf := &affProof{
+		A:  pk.add(pk.mul(K, alpha), pk.encryptWith(betaMask, r)),
+		E:  rp.commit(alpha, gamma),
+		S:  rp.commit(b, m),
+		F:  rp.commit(betaMask, delta),
+		T:  rp.commit(beta, mu),
+		Bx: new(bn256.G2).ScalarBaseMult(Mod(alpha, bn256.Order)),
+		By: new(bn256.G2).ScalarBaseMult(Mod(betaMask, bn256.Order)),
+	}
+	e := pf.challenge(t, pk, rp, K, D, B, Beta)
+	pf.z1 = Add(alpha, Multiply(e, b))
+	pf.z2 = Add(betaMask, Multiply(e, beta))
+	pf.z3 = Add(gamma, Multiply(e, m))
+	pf.z4 = Add(delta, Multiply(e, mu))
+	pf.w = Mod(Multiply(r, new(big.Int).Exp(rho, e, pk.n)), pk.n)
+	return pf, nil
+}
+
+/*
+verify checks a proof of proveAff: K^z1·Enc(z2; w) = A·D^e, g2^z1 = Bx·B^e,
+g2^z2 = By·Beta^e, s^z1·t^z3 = E·S^e and s^z2·t^z4 = F·T^e, with z1 and z2
+in range.
+*/
+func (pf *affProof) verify(t *transcript, pk *paillierPublicKey, rp *ringPedersen, K, D *big.Int, B, Beta *bn256.G2) error {
+	if !pk.valid(pf.A) || !rp.valid(pf.E) || !rp.valid(pf.S) || !rp.valid(pf.F) || !rp.valid(pf.T) {
+		return errors.New("ccs08: affine proof: commitment out of range")
+	}
+	if pf.z1.BitLen() > mtaL+mtaEps || pf.z2.BitLen() > mtaLPrime+mtaEps ||
+		pf.z3.BitLen() > rp.randomnessBits() || pf.z4.BitLen() > rp.randomnessBits() ||
+		pf.w.Sign() <= 0 || pf.w.Cmp(pk.n) >= 0 {
+		return errors.New("ccs08: affine proof: response out of range")
+	}
+	e := pf.challenge(t, pk, rp, K, D, B, Beta)
+	if pk.add(pk.mul(K, pf.z1), pk.encryptWith(pf.z2, pf.w)).Cmp(pk.add(pf.A, pk.mul(D, e))) != 0 {
+		return errors.New("ccs08: affine proof: ciphertext does not match")
+	}
+	if !openG2(pf.z1, pf.Bx, B, e) || !openG2(pf.z2, pf.By, Beta, e) {
+		return errors.New("ccs08: affine proof: group element does not match")
+	}
+	if rp.commit(pf.z1, pf.z3).Cmp(mulExp(pf.E, pf.S, e, rp.n)) != 0 ||
+		rp.commit(pf.z2, pf.z4).Cmp(mulExp(pf.F, pf.T, e, rp.n)) != 0 {
+		return errors.New("ccs08: affine proof: commitment does not match")
+	}
+	return nil
+}
+
+func (pf *affProof) challenge(t *transcript, pk *paillierPublicKey, rp *ringPedersen, K, D *big.Int, B, Beta *bn256.G2) *big.Int {
+	t.appendNats("Paillier key", pk.n)
+	t.appendNats("ring-Pedersen", rp.n, rp.s, rp.t)
+	t.appendNats("K, D", K, D)
+	t.appendBytes("B", B.Marshal())
+	t.appendBytes("Beta", Beta.Marshal())
+	t.appendNats("A, E, S, F, T", pf.A, pf.E, pf.S, pf.F, pf.T)
+	t.appendBytes("Bx", pf.Bx.Marshal())
+	t.appendBytes("By", pf.By.Marshal())
+	return t.challenge()
+}
+
+/*
+proveKnowledge returns a Schnorr proof (R, z) of knowledge of r for g2^r,
+whose challenge extends the transcript t.
+*/
+func proveKnowledge(t *transcript, r *big.Int, P *bn256.G2) (*bn256.G2, *big.Int, error) {
+	k, err := rand.Int(rand.Reader, bn256.Order)
+	if err != nil {
+		return nil, nil, err
+	}
+	R := new(bn256.G2).ScalarBaseMult(k)
+	c := knowledgeChallenge(t, P, R)
+	return R, Mod(Add(k, Multiply(c, r)), bn256.Order), nil
+}
+
+/*
+verifyKnowledge checks a proof of proveKnowledge: g2^z = R·P^c.
+*/
+func verifyKnowledge(t *transcript, P, R *bn256.G2, z *big.Int) bool {
+	c := knowledgeChallenge(t, P, R)
+	rhs := new(bn256.G2).ScalarMult(P, c)
+	rhs.Add(rhs, R)
+	return bytes.Equal(new(bn256.G2).ScalarBaseMult(z).Marshal(), rhs.Marshal())
+}
+
+func knowledgeChallenge(t *transcript, P, R *bn256.G2) *big.Int {
+	t.appendBytes("P", P.Marshal())
+	t.appendBytes("R", R.Marshal())
+	return t.challenge()
+}
+
+// appendNats absorbs the non-negative integers xs in turn, with label.
+func (t *transcript) appendNats(label string, xs ...*big.Int) {
+	for _, x := range xs {
+		t.appendBytes(label, x.Bytes())
+	}
+}
+
+func (e *encoder) nats(xs ...*big.Int) {
+	for _, x := range xs {
+		e.bytes(x.Bytes())
+	}
+}
+
+func (d *decoder) nat(field string) *big.Int {
+	b := d.bytes(field)
+	if d.err != nil {
+		return nil
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+func (e *encoder) encProof(pf *encProof) {
+	e.nats(pf.S, pf.A, pf.D)
+	e.raw(pf.Y.Marshal())
+	e.nats(pf.z1, pf.z2, pf.z3)
+}
+
+func (d *decoder) encProof(field string) *encProof {
+	pf := &encProof{S: d.nat(field), A: d.nat(field), D: d.nat(field)}
+	pf.Y = d.g1(field)
+	pf.z1, pf.z2, pf.z3 = d.nat(field), d.nat(field), d.nat(field)
+	return pf
+}
+
+func (e *encoder) affProof(pf *affProof) {
+	e.nats(pf.A, pf.E, pf.S, pf.F, pf.T)
+	e.raw(pf.Bx.Marshal())
+	e.raw(pf.By.Marshal())
+	e.nats(pf.z1, pf.z2, pf.z3, pf.z4, pf.w)
+}
+
+func (d *decoder) affProof(field string) *affProof {
+	pf := &affProof{A: d.nat(field), E: d.nat(field), S: d.nat(field), F: d.nat(field), T: d.nat(field)}
+	pf.Bx, pf.By = d.g2(field), d.g2(field)
+	pf.z1, pf.z2, pf.z3, pf.z4, pf.w = d.nat(field), d.nat(field), d.nat(field), d.nat(field), d.nat(field)
+	return pf
+}
+
+// openG2 reports whether g2^z = mask·P^e.
+func openG2(z *big.Int, mask, P *bn256.G2, e *big.Int) bool {
+	Pe := new(bn256.G2).ScalarMult(P, e)
+	return bytes.Equal(new(bn256.G2).ScalarBaseMult(Mod(z, bn256.Order)).Marshal(), Pe.Add(Pe, mask).Marshal())
+}
+
+// mulExp returns a·b^e mod n.
+func mulExp(a, b, e, n *big.Int) *big.Int {
+	return Mod(Multiply(a, new(big.Int).Exp(b, e, n)), n)
+}
+
+// randBits returns a random integer in [0, 2^bits).
+func randBits(bits int) (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+}
+
+// randBelow returns a random integer in [0, 2^bits·n).
+func randBelow(bits int, n *big.Int) (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(n, uint(bits)))
+}
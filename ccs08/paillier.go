@@ -0,0 +1,236 @@
+package ccs08
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// modulusProofRoots is the number of n-th roots in a modulus proof. A
+	// modulus with gcd(n, phi(n)) != 1 and no prime factor below
+	// smallPrimeBound has at most one n-th residue in 2^16, so that it passes
+	// with probability at most 2^-128.
+	modulusProofRoots = 8
+	smallPrimeBound   = 1 << 16
+)
+
+// smallPrimes are the primes below smallPrimeBound.
+var smallPrimes = func() []int64 {
+	var primes []int64
+	composite := make([]bool, smallPrimeBound)
+	for i := 2; i < smallPrimeBound; i++ {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, int64(i))
+		for j := i * i; j < smallPrimeBound; j += i {
+			composite[j] = true
+		}
+	}
+	return primes
+}()
+
+/*
+paillierPublicKey is a Paillier public key with generator n+1, under which
+Enc(m1)·Enc(m2) = Enc(m1+m2) and Enc(m)^k = Enc(k·m) modulo n.
+*/
+type paillierPublicKey struct {
+	n, n2 *big.Int
+}
+
+/*
+paillierKey is a Paillier private key, with n = p·q, phi = (p-1)(q-1),
+mu = phi^-1 mod n and qinv = q^-1 mod p.
+*/
+type paillierKey struct {
+	paillierPublicKey
+	phi, mu    *big.Int
+	p, q, qinv *big.Int
+}
+
+func newPaillierPublicKey(n *big.Int) *paillierPublicKey {
+	return &paillierPublicKey{n: n, n2: new(big.Int).Mul(n, n)}
+}
+
+/*
+newPaillierKey generates a key whose modulus n has the given number of bits.
+*/
+func newPaillierKey(bits int) (*paillierKey, error) {
+	for {
+		p, err := rand.Prime(rand.Reader, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := rand.Prime(rand.Reader, bits-bits/2)
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		one := big.NewInt(1)
+		phi := Multiply(Sub(p, one), Sub(q, one))
+		pk := newPaillierPublicKey(Multiply(p, q))
+		mu := ModInverse(phi, pk.n)
+		if mu == nil {
+			continue
+		}
+		return &paillierKey{*pk, phi, mu, p, q, ModInverse(q, p)}, nil
+	}
+}
+
+/*
+encrypt returns (1+n)^m·r^n mod n² for a random r.
+*/
+func (pk *paillierPublicKey) encrypt(m *big.Int) (*big.Int, error) {
+	r, err := pk.randomness()
+	if err != nil {
+		return nil, err
+	}
+	return pk.encryptWith(m, r), nil
+}
+
+/*
+encryptWith returns (1+n)^m·r^n mod n² for the given r.
+*/
+func (pk *paillierPublicKey) encryptWith(m, r *big.Int) *big.Int {
+	// (1+n)^m = 1+m·n mod n²
+	c := new(big.Int).Mul(m, pk.n)
+	c.Add(c, big.NewInt(1))
+	c.Mul(c, new(big.Int).Exp(r, pk.n, pk.n2))
+	return c.Mod(c, pk.n2)
+}
+
+/*
+randomness returns a random r in [1, n) for encryptWith.
+*/
+func (pk *paillierPublicKey) randomness() (*big.Int, error) {
+	r, err := rand.Int(rand.Reader, pk.n)
+	if err != nil {
+		return nil, err
+	}
+	if r.Sign() == 0 {
+		return nil, errors.New("ccs08: zero Paillier randomness")
+	}
+	return r, nil
+}
+
+/*
+add returns an encryption of the sum of the plaintexts of c1 and c2.
+*/
+func (pk *paillierPublicKey) add(c1, c2 *big.Int) *big.Int {
+	return Mod(Multiply(c1, c2), pk.n2)
+}
+
+/*
+mul returns an encryption of k times the plaintext of c.
+*/
+func (pk *paillierPublicKey) mul(c, k *big.Int) *big.Int {
+	return new(big.Int).Exp(c, k, pk.n2)
+}
+
+/*
+valid reports whether c is a ciphertext, an element 0 < c < n² coprime to n.
+*/
+func (pk *paillierPublicKey) valid(c *big.Int) bool {
+	if c.Sign() <= 0 || c.Cmp(pk.n2) >= 0 {
+		return false
+	}
+	return new(big.Int).GCD(nil, nil, c, pk.n).Cmp(big.NewInt(1)) == 0
+}
+
+/*
+decrypt returns the plaintext of c, L(c^phi mod n²)·mu mod n with
+L(x) = (x-1)/n.
+*/
+func (sk *paillierKey) decrypt(c *big.Int) *big.Int {
+	x := new(big.Int).Exp(c, sk.phi, sk.n2)
+	x.Sub(x, big.NewInt(1))
+	x.Div(x, sk.n)
+	x.Mul(x, sk.mu)
+	return x.Mod(x, sk.n)
+}
+
+/*
+decryptSigned returns the plaintext of c in (-n/2, n/2], for plaintexts that
+may be negative.
+*/
+func (sk *paillierKey) decryptSigned(c *big.Int) *big.Int {
+	m := sk.decrypt(c)
+	if new(big.Int).Lsh(m, 1).Cmp(sk.n) > 0 {
+		m.Sub(m, sk.n)
+	}
+	return m
+}
+
+/*
+exp returns x^e mod n for a unit x and e >= 0, computed modulo p and q.
+*/
+func (sk *paillierKey) exp(x, e *big.Int) *big.Int {
+	one := big.NewInt(1)
+	xp := new(big.Int).Exp(x, Mod(e, Sub(sk.p, one)), sk.p)
+	xq := new(big.Int).Exp(x, Mod(e, Sub(sk.q, one)), sk.q)
+	// x^e = xq + q·((xp-xq)·q^-1 mod p)
+	h := Mod(Multiply(Sub(xp, xq), sk.qinv), sk.p)
+	return Add(xq, Multiply(h, sk.q))
+}
+
+/*
+proveModulus proves that gcd(n, phi(n)) = 1, under which every element of
+Z*_n² is the encryption of exactly one plaintext. It returns the n-th roots
+modulo n of modulusProofRoots challenges derived from n and the participant
+id, after Goldberg, Reyzin, Sagga and Baldimtsi.
+*/
+func (sk *paillierKey) proveModulus(id int) []*big.Int {
+	d := ModInverse(sk.n, sk.phi)
+	roots := make([]*big.Int, modulusProofRoots)
+	for i := range roots {
+		roots[i] = new(big.Int).Exp(modulusChallenge(sk.n, id, i), d, sk.n)
+	}
+	return roots
+}
+
+/*
+verifyModulus checks a proof of proveModulus by participant id.
+*/
+func (pk *paillierPublicKey) verifyModulus(id int, roots []*big.Int) error {
+	if pk.n.Bit(0) == 0 {
+		return errors.New("ccs08: even Paillier modulus")
+	}
+	m, p := new(big.Int), new(big.Int)
+	for _, prime := range smallPrimes {
+		if m.Mod(pk.n, p.SetInt64(prime)).Sign() == 0 {
+			return fmt.Errorf("ccs08: Paillier modulus divisible by %d", prime)
+		}
+	}
+	if len(roots) != modulusProofRoots {
+		return fmt.Errorf("ccs08: modulus proof of %d roots", len(roots))
+	}
+	for i, root := range roots {
+		if root.Sign() <= 0 || root.Cmp(pk.n) >= 0 ||
+			new(big.Int).Exp(root, pk.n, pk.n).Cmp(modulusChallenge(pk.n, id, i)) != 0 {
+			return fmt.Errorf("ccs08: modulus proof[%d]: invalid root", i)
+		}
+	}
+	return nil
+}
+
+/*
+modulusChallenge returns the i-th challenge of the modulus proof of
+participant id, a hash of n, id and i expanded to 128 bits more than n and
+reduced modulo n.
+*/
+func modulusChallenge(n *big.Int, id, i int) *big.Int {
+	var b []byte
+	for block := 0; len(b)*8 < n.BitLen()+128; block++ {
+		t := newTranscript(ceremonyDST)
+		t.appendBytes("n", n.Bytes())
+		t.appendInt("party", int64(id))
+		t.appendInt("root", int64(i))
+		t.appendInt("block", int64(block))
+		b = t.h.Sum(b)
+	}
+	return Mod(new(big.Int).SetBytes(b), n)
+}
@@ -0,0 +1,77 @@
+package ccs08
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/bn256"
+)
+
+// TranscriptDST is the domain-separation tag that starts the Fiat-Shamir
+// transcript of every [0,u^l) proof.
+const TranscriptDST = "blockchain-research/crypto/ccs08/transcript/v1"
+
+/*
+transcript is a Fiat-Shamir transcript. Every element is absorbed with its
+label, both prefixed by their 8-byte big-endian length, so that two different
+sequences of elements never hash the same. Group elements are absorbed in
+their canonical Marshal form.
+*/
+type transcript struct {
+	h hash.Hash
+}
+
+func newTranscript(dst string) *transcript {
+	t := &transcript{sha512.New()}
+	t.appendBytes("dst", []byte(dst))
+	return t
+}
+
+func (t *transcript) appendBytes(label string, b []byte) {
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(label)))
+	t.h.Write(n[:])
+	t.h.Write([]byte(label))
+	binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+	t.h.Write(n[:])
+	t.h.Write(b)
+}
+
+func (t *transcript) appendInt(label string, x int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(x))
+	t.appendBytes(label, b[:])
+}
+
+/*
+challenge returns the 512-bit digest of the transcript reduced modulo
+bn256.Order, which is uniform up to a bias of 2^-258.
+*/
+func (t *transcript) challenge() *big.Int {
+	c := new(big.Int).SetBytes(t.h.Sum(nil))
+	return c.Mod(c, bn256.Order)
+}
+
+/*
+challengeUL returns the challenge of a [0,u^l) proof, which binds the public
+parameters H, pubk, u and l and the statement C together with the prover's
+messages V, a and D.
+*/
+func challengeUL(H *bn256.G2, pubk *bn256.G1, u, l int64, proof *ProofUL) *big.Int {
+	t := newTranscript(TranscriptDST)
+	t.appendInt("u", u)
+	t.appendInt("l", l)
+	t.appendBytes("H", H.Marshal())
+	t.appendBytes("pubk", pubk.Marshal())
+	t.appendBytes("C", proof.C.Marshal())
+	for i := range proof.V {
+		t.appendBytes("V", proof.V[i].Marshal())
+	}
+	for i := range proof.a {
+		t.appendBytes("a", proof.a[i].Marshal())
+	}
+	t.appendBytes("D", proof.D.Marshal())
+	return t.challenge()
+}
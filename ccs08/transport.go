@@ -0,0 +1,198 @@
+package ccs08
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+/*
+Transport carries the messages of one participant of a Ceremony to and from the
+others, which are numbered 0...n-1. Messages between two participants must
+arrive in the order they were sent. Close ends the Transport: Send and Recv
+calls blocked in it return an error, and so do the calls of the other
+participants that wait for this one.
+*/
+type Transport interface {
+	Send(to int, msg []byte) error
+	Recv(from int) ([]byte, error)
+	Close() error
+}
+
+// maxMessageSize bounds the messages read by a stream Transport.
+const maxMessageSize = 1 << 26
+
+var errClosed = errors.New("ccs08: transport closed")
+
+type memoryTransport struct {
+	id int
+	// links[i][j] carries the messages from i to j
+	links [][]chan []byte
+	// closed[i] is closed when participant i closes its Transport
+	closed []chan struct{}
+	once   sync.Once
+}
+
+/*
+NewMemoryTransports returns the Transports of n participants in one process,
+connected by channels.
+*/
+func NewMemoryTransports(n int) []Transport {
+	links := make([][]chan []byte, n)
+	for i := range links {
+		links[i] = make([]chan []byte, n)
+		for j := range links[i] {
+			if i != j {
+				links[i][j] = make(chan []byte, 1)
+			}
+		}
+	}
+	closed := make([]chan struct{}, n)
+	for i := range closed {
+		closed[i] = make(chan struct{})
+	}
+	ts := make([]Transport, n)
+	for i := range ts {
+		ts[i] = &memoryTransport{id: i, links: links, closed: closed}
+	}
+	return ts
+}
+
+func (t *memoryTransport) link(from, to int) (chan []byte, error) {
+	if from < 0 || from >= len(t.links) || to < 0 || to >= len(t.links) || from == to {
+		return nil, fmt.Errorf("ccs08: no link from %d to %d", from, to)
+	}
+	return t.links[from][to], nil
+}
+
+func (t *memoryTransport) Send(to int, msg []byte) error {
+	ch, err := t.link(t.id, to)
+	if err != nil {
+		return err
+	}
+	select {
+	case ch <- append([]byte{}, msg...):
+		return nil
+	case <-t.closed[t.id]:
+		return errClosed
+	case <-t.closed[to]:
+		return fmt.Errorf("ccs08: participant %d closed", to)
+	}
+}
+
+func (t *memoryTransport) Recv(from int) ([]byte, error) {
+	ch, err := t.link(from, t.id)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case m := <-ch:
+		return m, nil
+	case <-t.closed[t.id]:
+		return nil, errClosed
+	case <-t.closed[from]:
+		// the messages sent before Close are still delivered
+		select {
+		case m := <-ch:
+			return m, nil
+		default:
+			return nil, fmt.Errorf("ccs08: participant %d closed", from)
+		}
+	}
+}
+
+func (t *memoryTransport) Close() error {
+	t.once.Do(func() { close(t.closed[t.id]) })
+	return nil
+}
+
+type streamTransport struct {
+	conns []io.ReadWriter
+}
+
+/*
+NewStreamTransport returns the Transport of a participant with a connection
+conns[i] to every other participant i, such as a pipe or a TCP connection.
+Messages are framed with their 4-byte big-endian length. Close closes the
+connections that are io.Closers.
+*/
+func NewStreamTransport(conns []io.ReadWriter) Transport {
+	return &streamTransport{conns}
+}
+
+/*
+NewPipeTransports returns the Transports of n participants in one process,
+connected by net.Pipe.
+*/
+func NewPipeTransports(n int) []Transport {
+	conns := make([][]io.ReadWriter, n)
+	for i := range conns {
+		conns[i] = make([]io.ReadWriter, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			conns[i][j], conns[j][i] = net.Pipe()
+		}
+	}
+	ts := make([]Transport, n)
+	for i := range ts {
+		ts[i] = NewStreamTransport(conns[i])
+	}
+	return ts
+}
+
+func (t *streamTransport) conn(i int) (io.ReadWriter, error) {
+	if i < 0 || i >= len(t.conns) || t.conns[i] == nil {
+		return nil, fmt.Errorf("ccs08: no connection to %d", i)
+	}
+	return t.conns[i], nil
+}
+
+func (t *streamTransport) Send(to int, msg []byte) error {
+	c, err := t.conn(to)
+	if err != nil {
+		return err
+	}
+	if len(msg) > maxMessageSize {
+		return errors.New("ccs08: message too long")
+	}
+	frame := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	_, err = c.Write(append(frame, msg...))
+	return err
+}
+
+func (t *streamTransport) Recv(from int) ([]byte, error) {
+	c, err := t.conn(from)
+	if err != nil {
+		return nil, err
+	}
+	var n [4]byte
+	if _, err := io.ReadFull(c, n[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(n[:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("ccs08: message of %d bytes from %d is too long", size, from)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(c, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (t *streamTransport) Close() error {
+	var err error
+	for _, c := range t.conns {
+		if closer, ok := c.(io.Closer); ok {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
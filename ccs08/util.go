@@ -2,7 +2,9 @@ package ccs08
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/blockchain-research/crypto/bn256"
 )
@@ -14,6 +16,78 @@ var (
 	E  = bn256.Pair(G1, G2)
 )
 
+/*
+GeneratorVersion identifies how the commitment generator H is derived.
+Commitments are only meaningful under the generator they were created with.
+*/
+type GeneratorVersion uint8
+
+const (
+	// GeneratorLegacy is H = h*G2 for the hard-coded scalar h below. Since h is
+	// public, anyone can open a commitment to any value; it is kept only to
+	// verify commitments created before GeneratorNUMS.
+	GeneratorLegacy GeneratorVersion = 1
+	// GeneratorNUMS is H = bn256.HashG2(nil, GeneratorDST), with the
+	// try-and-increment map. It is kept to verify commitments created before
+	// GeneratorHashToCurve.
+	GeneratorNUMS GeneratorVersion = 2
+	// GeneratorHashToCurve is H = bn256.HashToG2(nil, GeneratorHashToCurveDST),
+	// with the hash_to_curve construction of RFC 9380. It is the generator of
+	// new parameters.
+	GeneratorHashToCurve GeneratorVersion = 3
+)
+
+// GeneratorDST is the domain-separation tag used to derive H for GeneratorNUMS.
+const GeneratorDST = "blockchain-research/crypto/ccs08/pedersen-H/v2"
+
+// GeneratorHashToCurveDST is the domain-separation tag used to derive H for
+// GeneratorHashToCurve. It ends with the RFC 9380 suite identifier of
+// bn256.HashToG2.
+const GeneratorHashToCurveDST = "BLOCKCHAIN-RESEARCH-CCS08-PEDERSEN-H-V3-BN254G2_XMD:SHA-256_SVDW_RO_"
+
+// legacyH is log_G2(H) for GeneratorLegacy.
+const legacyH = "18560948149108576432482904553159745978835170526553990798435819795989606410925"
+
+/*
+GeneratorH returns the commitment generator H for the given version.
+*/
+func GeneratorH(version GeneratorVersion) (*bn256.G2, error) {
+	switch version {
+	case GeneratorLegacy:
+		return new(bn256.G2).ScalarBaseMult(GetBigInt(legacyH)), nil
+	case GeneratorNUMS:
+		return bn256.HashG2(nil, []byte(GeneratorDST)), nil
+	case GeneratorHashToCurve:
+		return bn256.HashToG2(nil, []byte(GeneratorHashToCurveDST)), nil
+	}
+	return nil, fmt.Errorf("unknown generator version %d", version)
+}
+
+var (
+	generatorTablesOnce sync.Once
+	// generatorTables maps the encoding of the generator H of every
+	// GeneratorVersion to its precomputed table.
+	generatorTables map[string]*bn256.FixedBaseG2
+)
+
+/*
+scalarMultH returns h^k, using a precomputed table if h is the generator H of a
+GeneratorVersion, since commitments and proofs multiply H over and over.
+*/
+func scalarMultH(h *bn256.G2, k *big.Int) *bn256.G2 {
+	generatorTablesOnce.Do(func() {
+		generatorTables = make(map[string]*bn256.FixedBaseG2)
+		for _, version := range []GeneratorVersion{GeneratorLegacy, GeneratorNUMS, GeneratorHashToCurve} {
+			H, _ := GeneratorH(version)
+			generatorTables[string(H.Marshal())] = bn256.NewFixedBaseG2(H)
+		}
+	})
+	if table, ok := generatorTables[string(h.Marshal())]; ok {
+		return table.ScalarMult(k)
+	}
+	return new(bn256.G2).ScalarMult(h, k)
+}
+
 /*
 Decompose receives as input a bigint x and outputs an array of integers such that
 x = sum(xi.u^i), i.e. it returns the decomposition of x into base u.
@@ -42,7 +116,7 @@ func Commit(x, r *big.Int, h *bn256.G2) (*bn256.G2, error) {
 		C *bn256.G2
 	)
 	C = new(bn256.G2).ScalarBaseMult(x)
-	C.Add(C, new(bn256.G2).ScalarMult(h, r))
+	C.Add(C, scalarMultH(h, r))
 	return C, nil
 }
 
@@ -61,6 +135,9 @@ func CommitG1(x, r *big.Int, h *bn256.G1) (*bn256.G1, error) {
 
 /*
 HashSet is responsible for the computing a Zp element given elements from GT and G2.
+
+Deprecated: it hashes the debug String form of the elements. Proofs derive
+their challenge from a canonical transcript instead.
 */
 func HashSet(a *bn256.GT, D *bn256.G2) (*big.Int, error) {
 	digest := sha256.New()
@@ -73,6 +150,9 @@ func HashSet(a *bn256.GT, D *bn256.G2) (*big.Int, error) {
 
 /*
 Hash is responsible for the computing a Zp element given elements from GT and G2.
+
+Deprecated: it hashes the debug String form of the elements and omits the
+statement. Proofs derive their challenge from a canonical transcript instead.
 */
 func Hash(a []*bn256.GT, D *bn256.G2) (*big.Int, error) {
 	digest := sha256.New()
@@ -0,0 +1,204 @@
+/*
+Package ct implements confidential transactions on top of brs commitments.
+
+Amounts are hidden in Pedersen commitments C = rG + vH (brs.Commit). A
+transaction balances if the inputs minus the outputs minus the explicit fee
+commit to zero, i.e. the excess E = sum(C_in) - sum(C_out) - fee*H equals xG
+for x = sum(r_in) - sum(r_out). The transaction carries a Schnorr proof of
+knowledge of x, which is only possible if the values balance, and a brs range
+proof per output so that no output can hide a negative amount.
+*/
+package ct
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/brs"
+	"github.com/btcsuite/btcd/btcec"
+)
+
+const excessDST = "blockchain-research/crypto/ct/excess/v1"
+
+/*
+Opening is the value and blinding factor of a commitment.
+*/
+type Opening struct {
+	Value *big.Int
+	Blind *big.Int
+}
+
+/*
+Output is a committed transaction output with its range proof.
+*/
+type Output struct {
+	Commitment []*big.Int
+	RangeProof *brs.ProofUL
+}
+
+/*
+Transaction spends the input commitments into the outputs and an explicit fee.
+*/
+type Transaction struct {
+	Inputs      [][]*big.Int
+	Outputs     []*Output
+	Fee         *big.Int
+	Excess      []*big.Int
+	ExcessProof *ExcessProof
+}
+
+/*
+Params contains the range proof parameters: every output amount is proven to
+be in [0,u^l).
+*/
+type Params struct {
+	rp *brs.ParamsUL
+}
+
+/*
+Setup sets up the parameters for outputs in range [0,u^l).
+*/
+func Setup(u, l int64) *Params {
+	return &Params{rp: brs.SetupUL(u, l)}
+}
+
+/*
+Commit returns the commitment to the opening under the parameters' generator H.
+*/
+func (p *Params) Commit(o *Opening) []*big.Int {
+	hx, hy := p.rp.H()
+	x, y := brs.Commit(o.Value, o.Blind, hx, hy)
+	return []*big.Int{x, y}
+}
+
+/*
+Build creates a transaction spending inputs into outputs and fee. The input
+commitments are recomputed from their openings; the values must balance.
+*/
+func (p *Params) Build(inputs, outputs []*Opening, fee *big.Int) (*Transaction, error) {
+	if fee == nil {
+		return nil, errors.New("ct: fee is missing")
+	}
+	if fee.Sign() < 0 {
+		return nil, errors.New("ct: fee must not be negative")
+	}
+	curve := btcec.S256()
+	balance := new(big.Int).Neg(fee)
+	x := new(big.Int)
+
+	tx := &Transaction{Fee: new(big.Int).Set(fee)}
+	for _, in := range inputs {
+		tx.Inputs = append(tx.Inputs, p.Commit(in))
+		balance.Add(balance, in.Value)
+		x.Add(x, in.Blind)
+	}
+	for i, out := range outputs {
+		proof, err := p.rp.ProveULWithBlinding(out.Value, out.Blind)
+		if err != nil {
+			return nil, fmt.Errorf("ct: output %d: %v", i, err)
+		}
+		tx.Outputs = append(tx.Outputs, &Output{
+			Commitment: p.Commit(out),
+			RangeProof: proof,
+		})
+		balance.Sub(balance, out.Value)
+		x.Sub(x, out.Blind)
+	}
+	if balance.Sign() != 0 {
+		return nil, errors.New("ct: inputs do not equal outputs plus fee")
+	}
+	x.Mod(x, curve.N)
+
+	var err error
+	tx.Excess, err = p.Excess(tx)
+	if err != nil {
+		return nil, err
+	}
+	tx.ExcessProof, err = proveExcess(x, tx.Excess, tx.digest())
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+/*
+Excess computes sum(C_in) - sum(C_out) - fee*H for the transaction.
+*/
+func (p *Params) Excess(tx *Transaction) ([]*big.Int, error) {
+	curve := btcec.S256()
+	if tx.Fee == nil || tx.Fee.Sign() < 0 || tx.Fee.Cmp(curve.N) >= 0 {
+		return nil, errors.New("ct: fee is out of range")
+	}
+	ex, ey := new(big.Int), new(big.Int)
+	for i, in := range tx.Inputs {
+		if !isPoint(in) {
+			return nil, fmt.Errorf("ct: input %d is not a valid commitment", i)
+		}
+		ex, ey = curve.Add(ex, ey, in[0], in[1])
+	}
+	for i, out := range tx.Outputs {
+		if out == nil || !isPoint(out.Commitment) {
+			return nil, fmt.Errorf("ct: output %d is not a valid commitment", i)
+		}
+		negy := new(big.Int).Sub(curve.P, out.Commitment[1])
+		ex, ey = curve.Add(ex, ey, out.Commitment[0], negy)
+	}
+	if tx.Fee.Sign() > 0 {
+		hx, hy := p.rp.H()
+		fx, fy := curve.ScalarMult(hx, hy, tx.Fee.Bytes())
+		ex, ey = curve.Add(ex, ey, fx, new(big.Int).Sub(curve.P, fy))
+	}
+	return []*big.Int{ex, ey}, nil
+}
+
+/*
+Verify checks that every output carries a valid range proof and that the
+excess is a commitment to zero whose blinding factor the creator knows.
+*/
+func (p *Params) Verify(tx *Transaction) error {
+	if tx == nil || len(tx.Outputs) == 0 {
+		return errors.New("ct: transaction has no outputs")
+	}
+	for i, out := range tx.Outputs {
+		if out == nil || !isPoint(out.Commitment) ||
+			!p.rp.VerifyUL(out.RangeProof, out.Commitment[0], out.Commitment[1]) {
+			return fmt.Errorf("ct: invalid range proof for output %d", i)
+		}
+	}
+	E, err := p.Excess(tx)
+	if err != nil {
+		return err
+	}
+	if len(tx.Excess) != 2 || tx.Excess[0] == nil || tx.Excess[1] == nil ||
+		E[0].Cmp(tx.Excess[0]) != 0 || E[1].Cmp(tx.Excess[1]) != 0 {
+		return errors.New("ct: excess does not match the commitments and fee")
+	}
+	if !verifyExcess(tx.ExcessProof, E, tx.digest()) {
+		return errors.New("ct: invalid excess proof")
+	}
+	return nil
+}
+
+/*
+digest hashes the commitments and fee that the excess proof is bound to.
+*/
+func (tx *Transaction) digest() []byte {
+	digest := sha256.New()
+	for _, in := range tx.Inputs {
+		digest.Write(pointBytes(in))
+	}
+	for _, out := range tx.Outputs {
+		digest.Write(pointBytes(out.Commitment))
+	}
+	fee := make([]byte, 32)
+	b := tx.Fee.Bytes()
+	copy(fee[32-len(b):], b)
+	digest.Write(fee)
+	return digest.Sum(nil)
+}
+
+func isPoint(P []*big.Int) bool {
+	return len(P) == 2 && P[0] != nil && P[1] != nil && btcec.S256().IsOnCurve(P[0], P[1])
+}
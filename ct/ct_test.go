@@ -0,0 +1,72 @@
+package ct
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+func opening(v int64) *Opening {
+	r, _ := rand.Int(rand.Reader, btcec.S256().N)
+	return &Opening{Value: big.NewInt(v), Blind: r}
+}
+
+func TestTransaction(t *testing.T) {
+	params := Setup(10, 4)
+
+	//100 + 50 = 120 + 25 + fee 5
+	inputs := []*Opening{opening(100), opening(50)}
+	outputs := []*Opening{opening(120), opening(25)}
+	tx, err := params.Build(inputs, outputs, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := params.Verify(tx); err != nil {
+		t.Errorf("Transaction verification failed: %v", err)
+	}
+
+	//changing the fee breaks the balance
+	tx.Fee = big.NewInt(6)
+	if err := params.Verify(tx); err == nil {
+		t.Errorf("Transaction with wrong fee verified")
+	}
+	tx.Fee = big.NewInt(5)
+
+	//replacing an output commitment breaks the range proof
+	tx.Outputs[0].Commitment = params.Commit(opening(120))
+	if err := params.Verify(tx); err == nil {
+		t.Errorf("Transaction with replaced output verified")
+	}
+
+	//unbalanced transactions cannot be built
+	if _, err := params.Build(inputs, outputs, big.NewInt(4)); err == nil {
+		t.Errorf("expected error for unbalanced transaction")
+	}
+	if _, err := params.Build(inputs, outputs, nil); err == nil {
+		t.Errorf("expected error for missing fee")
+	}
+	//outputs must be in range
+	if _, err := params.Build([]*Opening{opening(20000)}, []*Opening{opening(20000)}, big.NewInt(0)); err == nil {
+		t.Errorf("expected error for output out of range")
+	}
+}
+
+func TestExcessProof(t *testing.T) {
+	curve := btcec.S256()
+	x, _ := rand.Int(rand.Reader, curve.N)
+	Ex, Ey := curve.ScalarBaseMult(x.Bytes())
+	E := []*big.Int{Ex, Ey}
+
+	proof, err := proveExcess(x, E, []byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyExcess(proof, E, []byte("msg")) {
+		t.Errorf("Excess proof verification failed")
+	}
+	if verifyExcess(proof, E, []byte("other")) {
+		t.Errorf("Excess proof verified for another message")
+	}
+}
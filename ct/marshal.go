@@ -0,0 +1,304 @@
+package ct
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/blockchain-research/crypto/brs"
+	"github.com/btcsuite/btcd/btcec"
+)
+
+/*
+Wire format
+
+All integers are big-endian. Scalars are encoded as fixed 32-byte values in
+[0, N) and curve points as 33-byte compressed secp256k1 points.
+
+ExcessProof:
+
+	version  (1 byte)
+	R        (33 bytes)
+	s        (32 bytes)
+
+Transaction:
+
+	version  (1 byte)
+	nIn      (uint32)          number of inputs
+	nOut     (uint32)          number of outputs
+	in[i]    (33 bytes each)   for 0<=i<nIn
+	fee      (32 bytes)
+	E        (33 bytes)        excess
+	proof    (ExcessProof)
+	out[i]                     for 0<=i<nOut:
+	  C      (33 bytes)        commitment
+	  len    (uint32)          length of the range proof
+	  proof  (brs.ProofUL)
+*/
+const (
+	scalarLen = 32
+	pointLen  = 33
+	uint32Len = 4
+
+	excessProofVersion byte = 1
+	transactionVersion byte = 1
+
+	excessProofLen = 1 + pointLen + scalarLen
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*ExcessProof)(nil)
+	_ encoding.BinaryUnmarshaler = (*ExcessProof)(nil)
+	_ encoding.BinaryMarshaler   = (*Transaction)(nil)
+	_ encoding.BinaryUnmarshaler = (*Transaction)(nil)
+)
+
+/*
+MarshalBinary encodes the excess proof into its canonical binary form.
+*/
+func (proof *ExcessProof) MarshalBinary() ([]byte, error) {
+	R, err := encodePoint(proof.R)
+	if err != nil {
+		return nil, err
+	}
+	s, err := encodeScalar(proof.s)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{excessProofVersion}, R...), s...), nil
+}
+
+/*
+UnmarshalBinary decodes an excess proof produced by MarshalBinary. The
+response must be in [0, N) and R a valid compressed point.
+*/
+func (proof *ExcessProof) UnmarshalBinary(data []byte) error {
+	if len(data) != excessProofLen {
+		return errors.New("ct: excess proof length is invalid")
+	}
+	r := &reader{data: data}
+	if v := r.byte(); v != excessProofVersion {
+		return fmt.Errorf("ct: unsupported excess proof version %d", v)
+	}
+	R := r.point()
+	s := r.scalar()
+	if r.err != nil {
+		return r.err
+	}
+	proof.R = R
+	proof.s = s
+	return nil
+}
+
+/*
+MarshalBinary encodes the transaction into its canonical binary form.
+*/
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.ExcessProof == nil {
+		return nil, errors.New("ct: transaction has no excess proof")
+	}
+	ret := []byte{transactionVersion}
+	ret = appendUint32(ret, len(tx.Inputs))
+	ret = appendUint32(ret, len(tx.Outputs))
+	for i, in := range tx.Inputs {
+		b, err := encodePoint(in)
+		if err != nil {
+			return nil, fmt.Errorf("ct: input %d: %v", i, err)
+		}
+		ret = append(ret, b...)
+	}
+	fee, err := encodeScalar(tx.Fee)
+	if err != nil {
+		return nil, fmt.Errorf("ct: fee: %v", err)
+	}
+	ret = append(ret, fee...)
+	E, err := encodePoint(tx.Excess)
+	if err != nil {
+		return nil, fmt.Errorf("ct: excess: %v", err)
+	}
+	ret = append(ret, E...)
+	proof, err := tx.ExcessProof.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	ret = append(ret, proof...)
+	for i, out := range tx.Outputs {
+		if out == nil || out.RangeProof == nil {
+			return nil, fmt.Errorf("ct: output %d is incomplete", i)
+		}
+		C, err := encodePoint(out.Commitment)
+		if err != nil {
+			return nil, fmt.Errorf("ct: output %d: %v", i, err)
+		}
+		rp, err := out.RangeProof.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("ct: output %d: %v", i, err)
+		}
+		ret = append(ret, C...)
+		ret = appendUint32(ret, len(rp))
+		ret = append(ret, rp...)
+	}
+	return ret, nil
+}
+
+/*
+UnmarshalBinary decodes a transaction produced by MarshalBinary. Every point
+must be a valid compressed point, the fee must be in [0, N) and the data must
+end with the last range proof. It does not verify the transaction.
+*/
+func (tx *Transaction) UnmarshalBinary(data []byte) error {
+	r := &reader{data: data}
+	if v := r.byte(); v != transactionVersion {
+		if r.err != nil {
+			return r.err
+		}
+		return fmt.Errorf("ct: unsupported transaction version %d", v)
+	}
+	nIn := uint64(r.uint32())
+	nOut := uint64(r.uint32())
+	if r.err != nil {
+		return r.err
+	}
+	//every output takes at least a commitment and a length
+	if nIn*pointLen+scalarLen+pointLen+excessProofLen+nOut*(pointLen+uint32Len) > uint64(r.remaining()) {
+		return errors.New("ct: transaction length does not match its inputs and outputs")
+	}
+
+	inputs := make([][]*big.Int, nIn)
+	for i := range inputs {
+		inputs[i] = r.point()
+	}
+	fee := r.scalar()
+	excess := r.point()
+	proof := &ExcessProof{}
+	if b := r.next(excessProofLen); r.err == nil {
+		r.err = proof.UnmarshalBinary(b)
+	}
+	outputs := make([]*Output, nOut)
+	for i := range outputs {
+		C := r.point()
+		n := r.uint32()
+		b := r.next(int(n))
+		if r.err != nil {
+			return r.err
+		}
+		rp := &brs.ProofUL{}
+		if err := rp.UnmarshalBinary(b); err != nil {
+			return fmt.Errorf("ct: output %d: %v", i, err)
+		}
+		outputs[i] = &Output{Commitment: C, RangeProof: rp}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if r.remaining() != 0 {
+		return errors.New("ct: trailing data after transaction")
+	}
+
+	tx.Inputs = inputs
+	tx.Outputs = outputs
+	tx.Fee = fee
+	tx.Excess = excess
+	tx.ExcessProof = proof
+	return nil
+}
+
+func appendUint32(b []byte, v int) []byte {
+	var buf [uint32Len]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(v))
+	return append(b, buf[:]...)
+}
+
+func encodeScalar(k *big.Int) ([]byte, error) {
+	if k == nil || k.Sign() < 0 || k.Cmp(btcec.S256().N) >= 0 {
+		return nil, errors.New("ct: scalar out of range")
+	}
+	ret := make([]byte, scalarLen)
+	b := k.Bytes()
+	copy(ret[scalarLen-len(b):], b)
+	return ret, nil
+}
+
+func encodePoint(P []*big.Int) ([]byte, error) {
+	if !isPoint(P) {
+		return nil, errors.New("ct: invalid curve point")
+	}
+	return (&btcec.PublicKey{Curve: btcec.S256(), X: P[0], Y: P[1]}).SerializeCompressed(), nil
+}
+
+/*
+reader consumes data front to back and records the first decoding error.
+*/
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) remaining() int {
+	return len(r.data)
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = errors.New("ct: unexpected end of data")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.next(uint32Len)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) scalar() *big.Int {
+	b := r.next(scalarLen)
+	if b == nil {
+		return nil
+	}
+	k := new(big.Int).SetBytes(b)
+	if k.Cmp(btcec.S256().N) >= 0 {
+		r.err = errors.New("ct: scalar out of range")
+		return nil
+	}
+	return k
+}
+
+func (r *reader) point() []*big.Int {
+	b := r.next(pointLen)
+	if b == nil {
+		return nil
+	}
+	if b[0] != 0x02 && b[0] != 0x03 {
+		r.err = errors.New("ct: invalid point encoding")
+		return nil
+	}
+	if new(big.Int).SetBytes(b[1:]).Cmp(btcec.S256().P) >= 0 {
+		r.err = errors.New("ct: point coordinate out of range")
+		return nil
+	}
+	pub, err := btcec.ParsePubKey(b, btcec.S256())
+	if err != nil {
+		r.err = fmt.Errorf("ct: invalid point: %v", err)
+		return nil
+	}
+	return []*big.Int{pub.X, pub.Y}
+}
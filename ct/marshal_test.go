@@ -0,0 +1,130 @@
+package ct
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestExcessProofMarshal(t *testing.T) {
+	params := Setup(10, 2)
+	tx, err := params.Build([]*Opening{opening(30)}, []*Opening{opening(30)}, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := tx.ExcessProof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1+33+32 {
+		t.Errorf("unexpected excess proof length %d", len(data))
+	}
+	proof := &ExcessProof{}
+	if err := proof.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !verifyExcess(proof, tx.Excess, tx.digest()) {
+		t.Errorf("Excess proof verification failed after unmarshal")
+	}
+
+	//truncated and trailing data must be rejected
+	for i := 0; i < len(data); i++ {
+		if err := new(ExcessProof).UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("expected error for truncated excess proof of length %d", i)
+		}
+	}
+	if err := new(ExcessProof).UnmarshalBinary(append(data, 0)); err == nil {
+		t.Errorf("expected error for trailing data")
+	}
+
+	//response >= N must be rejected
+	bad := append([]byte{}, data...)
+	for i := len(bad) - 32; i < len(bad); i++ {
+		bad[i] = 0xff
+	}
+	if err := new(ExcessProof).UnmarshalBinary(bad); err == nil {
+		t.Errorf("expected error for out of range response")
+	}
+
+	//R must be a valid compressed point
+	bad = append([]byte{}, data...)
+	bad[1] = 0x04
+	if err := new(ExcessProof).UnmarshalBinary(bad); err == nil {
+		t.Errorf("expected error for invalid point encoding")
+	}
+
+	if _, err := new(ExcessProof).MarshalBinary(); err == nil {
+		t.Errorf("expected error for incomplete excess proof")
+	}
+}
+
+func TestTransactionMarshal(t *testing.T) {
+	params := Setup(10, 4)
+	inputs := []*Opening{opening(100), opening(50)}
+	outputs := []*Opening{opening(120), opening(25)}
+	tx, err := params.Build(inputs, outputs, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx2 := &Transaction{}
+	if err := tx2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := params.Verify(tx2); err != nil {
+		t.Errorf("Transaction verification failed after unmarshal: %v", err)
+	}
+
+	//truncated and trailing data must be rejected without panicking
+	for i := 0; i < len(data); i += 5 {
+		if err := new(Transaction).UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("expected error for truncated transaction of length %d", i)
+		}
+	}
+	if err := new(Transaction).UnmarshalBinary(append(data, 0)); err == nil {
+		t.Errorf("expected error for trailing data")
+	}
+
+	//counts larger than the data must be rejected before allocating
+	bad := append([]byte{}, data...)
+	bad[1], bad[2], bad[3], bad[4] = 0xff, 0xff, 0xff, 0xff
+	if err := new(Transaction).UnmarshalBinary(bad); err == nil {
+		t.Errorf("expected error for oversized input count")
+	}
+
+	//fee >= N must be rejected
+	bad = append([]byte{}, data...)
+	off := 1 + 4 + 4 + 2*33
+	for i := off; i < off+32; i++ {
+		bad[i] = 0xff
+	}
+	if err := new(Transaction).UnmarshalBinary(bad); err == nil {
+		t.Errorf("expected error for out of range fee")
+	}
+
+	//an unknown version must be rejected
+	bad = append([]byte{}, data...)
+	bad[0] = 2
+	if err := new(Transaction).UnmarshalBinary(bad); err == nil {
+		t.Errorf("expected error for unknown version")
+	}
+
+	//a modified transaction still decodes but does not verify
+	bad = append([]byte{}, data...)
+	bad[off+31] ^= 1
+	tx3 := &Transaction{}
+	if err := tx3.UnmarshalBinary(bad); err != nil {
+		t.Fatal(err)
+	}
+	if err := params.Verify(tx3); err == nil {
+		t.Errorf("Transaction with modified fee verified")
+	}
+
+	if _, err := new(Transaction).MarshalBinary(); err == nil {
+		t.Errorf("expected error for incomplete transaction")
+	}
+}
@@ -0,0 +1,79 @@
+package ct
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+/*
+ExcessProof is a Schnorr proof of knowledge of x with E = xG for the excess
+point E of a transaction.
+*/
+type ExcessProof struct {
+	R []*big.Int //commitment kG
+	s *big.Int   //response k + ex
+}
+
+/*
+proveExcess proves knowledge of the discrete logarithm x of E = xG. The
+challenge is bound to msg, the digest of the transaction.
+*/
+func proveExcess(x *big.Int, E []*big.Int, msg []byte) (*ExcessProof, error) {
+	curve := btcec.S256()
+	k, err := rand.Int(rand.Reader, curve.N)
+	if err != nil {
+		return nil, err
+	}
+	Rx, Ry := curve.ScalarBaseMult(k.Bytes())
+	R := []*big.Int{Rx, Ry}
+	e := excessChallenge(R, E, msg)
+
+	s := new(big.Int).Mul(e, x)
+	s.Add(s, k)
+	s.Mod(s, curve.N)
+	return &ExcessProof{R: R, s: s}, nil
+}
+
+/*
+verifyExcess checks sG = R + eE.
+*/
+func verifyExcess(proof *ExcessProof, E []*big.Int, msg []byte) bool {
+	curve := btcec.S256()
+	if proof == nil || proof.s == nil || len(proof.R) != 2 || proof.R[0] == nil || proof.R[1] == nil ||
+		!curve.IsOnCurve(proof.R[0], proof.R[1]) {
+		return false
+	}
+	e := excessChallenge(proof.R, E, msg)
+
+	sGx, sGy := curve.ScalarBaseMult(proof.s.Bytes())
+	eEx, eEy := curve.ScalarMult(E[0], E[1], e.Bytes())
+	x, y := curve.Add(proof.R[0], proof.R[1], eEx, eEy)
+	return sGx.Cmp(x) == 0 && sGy.Cmp(y) == 0
+}
+
+/*
+excessChallenge computes e = H(domain || R || E || msg) mod N.
+*/
+func excessChallenge(R, E []*big.Int, msg []byte) *big.Int {
+	digest := sha256.New()
+	digest.Write([]byte(excessDST))
+	digest.Write(pointBytes(R))
+	digest.Write(pointBytes(E))
+	digest.Write(msg)
+	e := new(big.Int).SetBytes(digest.Sum(nil))
+	return e.Mod(e, btcec.S256().N)
+}
+
+/*
+pointBytes returns the compressed encoding of P, or 33 zero bytes for the
+point at infinity.
+*/
+func pointBytes(P []*big.Int) []byte {
+	if P[0].Sign() == 0 && P[1].Sign() == 0 {
+		return make([]byte, 33)
+	}
+	return (&btcec.PublicKey{Curve: btcec.S256(), X: P[0], Y: P[1]}).SerializeCompressed()
+}